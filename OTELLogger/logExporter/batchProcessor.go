@@ -0,0 +1,486 @@
+package logExporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"otellogger/otel"
+	"otellogger/utils"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// optional driver interface for exporters that can send several transactions
+// in one request, used by the BatchProcessor
+type BatchTransactionExporter interface {
+	ExportTransactions(transactionLogs []*otel.TransactionLog, config map[string]string) error
+}
+
+// what a BatchProcessor does when its queue is full
+type QueueFullPolicy int
+
+const (
+	// wait until there is room in the queue (default)
+	QueueBlock QueueFullPolicy = iota
+	// drop the transaction being exported
+	QueueDropNewest
+	// drop the oldest queued transaction to make room
+	QueueDropOldest
+)
+
+// returned when exporting to a BatchProcessor that was shut down
+var ErrProcessorShutdown = errors.New("batch processor is shut down")
+
+// export transactions in the background so logging never waits on a slow exporter
+//
+// exports are put on a bounded queue and return right away, workers take them
+// off in batches of up to BatchSize and export a batch once it is full or
+// BatchTimeout after its first transaction. batches go to exporters that
+// implement BatchTransactionExporter in one call per run of transactions with
+// the same config, other exporters get the transactions one by one. since the
+// export happens later its errors go to OnError instead of the caller. the
+// zero values of the settings mean their defaults
+type BatchProcessor struct {
+	Exporter LogExporter
+
+	// transactions waiting to be exported (default 2048)
+	QueueSize int
+	// most transactions exported together (default 512)
+	BatchSize int
+	// longest a transaction waits for its batch to fill up (default 5s)
+	BatchTimeout time.Duration
+	// batches exported at the same time (default 1)
+	Workers         int
+	QueueFullPolicy QueueFullPolicy
+	// longest an export waits for room in the queue with QueueBlock, the
+	// transaction is dropped after that (default 0, wait until there is room)
+	BlockTimeout time.Duration
+	// called with the error and the transactions of every failed export
+	OnError func(err error, transactionLogs []*otel.TransactionLog)
+
+	startOnce sync.Once
+	started   atomic.Bool // set once the queue exists
+	queue     chan batchItem
+	workers   sync.WaitGroup
+
+	mu       sync.Mutex
+	shutdown bool
+	done     chan struct{}  // closed by Shutdown to wake up blocked exports
+	senders  sync.WaitGroup // exports sending to the queue, it is closed once they're done
+
+	flushMu     sync.Mutex
+	flushing    int
+	flushSignal chan struct{}  // closed when a flush starts
+	generation  uint64         // bumped by every flush, new transactions belong to the current one
+	pending     map[uint64]int // transactions queued or being exported per generation
+	flushWaits  []flushWait    // flushes waiting for their generation to be exported
+
+	queued   atomic.Uint64
+	exported atomic.Uint64
+	failed   atomic.Uint64
+	dropped  atomic.Uint64
+}
+
+// counters of a batch processor
+type BatchStats struct {
+	// transactions taken by ExportLogs/ExportTransaction, including dropped ones
+	Queued uint64
+	// transactions exported and failed to export
+	Exported uint64
+	Failed   uint64
+	// transactions dropped because the queue was full
+	Dropped uint64
+	// transactions currently in the queue
+	QueueLength int
+}
+
+// a queued transaction and the config it was exported with
+type batchItem struct {
+	transactionLog *otel.TransactionLog
+	config         map[string]string
+	// rebuilt from ExportLogs, exported with ExportLogs again
+	logsOnly bool
+	// flushes started after it was queued don't wait for it
+	generation uint64
+}
+
+// a flush waiting for everything queued before it
+type flushWait struct {
+	generation uint64
+	done       chan struct{}
+}
+
+const (
+	defaultBatchQueueSize = 2048
+	defaultBatchSize      = 512
+	defaultBatchTimeout   = 5 * time.Second
+	defaultBatchWorkers   = 1
+)
+
+// create a batch processor with the default queue and batch sizes
+func NewBatchProcessor(exporter LogExporter) *BatchProcessor {
+	return &BatchProcessor{Exporter: exporter}
+}
+
+// queue the logs for export
+func (p *BatchProcessor) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	return p.ExportLogsContext(context.Background(), traceID, logs, config)
+}
+
+// queue the transaction for export
+func (p *BatchProcessor) ExportTransaction(transactionLog *otel.TransactionLog, config map[string]string) error {
+	return p.ExportTransactionContext(context.Background(), transactionLog, config)
+}
+
+// same as ExportLogs, but stops waiting for room in the queue once the context is done
+func (p *BatchProcessor) ExportLogsContext(ctx context.Context, traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	return p.enqueue(ctx, batchItem{transactionLog: transactionFromLogs(traceID, logs), config: config, logsOnly: true})
+}
+
+// same as ExportTransaction, but stops waiting for room in the queue once the context is done
+func (p *BatchProcessor) ExportTransactionContext(ctx context.Context, transactionLog *otel.TransactionLog, config map[string]string) error {
+	return p.enqueue(ctx, batchItem{transactionLog: transactionLog, config: config})
+}
+
+// get the counters, doesn't start the workers
+func (p *BatchProcessor) Stats() BatchStats {
+	stats := BatchStats{
+		Queued:   p.queued.Load(),
+		Exported: p.exported.Load(),
+		Failed:   p.failed.Load(),
+		Dropped:  p.dropped.Load(),
+	}
+	if p.started.Load() {
+		stats.QueueLength = len(p.queue)
+	}
+
+	return stats
+}
+
+// export everything queued without waiting for the batches to fill up and
+// wait until it is done (or the context is), then flush the wrapped exporter.
+// transactions queued after the flush started aren't waited for
+func (p *BatchProcessor) Flush(ctx context.Context) error {
+	p.start()
+
+	p.flushMu.Lock()
+	generation := p.generation
+	p.generation++
+
+	if p.exportedUpTo(generation) {
+		p.flushMu.Unlock()
+		return FlushExporter(ctx, p.Exporter)
+	}
+
+	exported := make(chan struct{})
+	p.flushWaits = append(p.flushWaits, flushWait{generation: generation, done: exported})
+
+	p.flushing++
+	close(p.flushSignal)
+	p.flushSignal = make(chan struct{})
+	p.flushMu.Unlock()
+
+	defer func() {
+		p.flushMu.Lock()
+		p.flushing--
+		p.flushMu.Unlock()
+	}()
+
+	select {
+	case <-exported:
+		return FlushExporter(ctx, p.Exporter)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stop taking exports, export everything queued, stop the workers and shut
+// down the wrapped exporter. when the context is done first the workers keep
+// draining the queue in the background and the context error is returned
+func (p *BatchProcessor) Shutdown(ctx context.Context) error {
+	p.start()
+
+	p.mu.Lock()
+	if !p.shutdown {
+		p.shutdown = true
+		close(p.done)
+
+		// exports blocked on a full queue give up once done is closed, the
+		// queue can only be closed after the last of them is gone
+		go func() {
+			p.senders.Wait()
+			close(p.queue)
+		}()
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return ShutdownExporter(ctx, p.Exporter)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// start the workers on first use so the zero value works like the other exporters
+func (p *BatchProcessor) start() {
+	p.startOnce.Do(func() {
+		p.queue = make(chan batchItem, positiveOr(p.QueueSize, defaultBatchQueueSize))
+		p.flushSignal = make(chan struct{})
+		p.done = make(chan struct{})
+		p.pending = make(map[uint64]int)
+
+		for i := 0; i < positiveOr(p.Workers, defaultBatchWorkers); i++ {
+			p.workers.Add(1)
+			go p.work()
+		}
+
+		p.started.Store(true)
+	})
+}
+
+func (p *BatchProcessor) enqueue(ctx context.Context, item batchItem) error {
+	p.start()
+
+	// registered as a sender so the queue isn't closed while we send to it,
+	// the lock isn't held while waiting for room in the queue
+	p.mu.Lock()
+	if p.shutdown {
+		p.mu.Unlock()
+		return ErrProcessorShutdown
+	}
+	p.senders.Add(1)
+	p.mu.Unlock()
+
+	defer p.senders.Done()
+
+	item.generation = p.addPending()
+	p.queued.Add(1)
+
+	switch p.QueueFullPolicy {
+	case QueueDropNewest:
+		select {
+		case p.queue <- item:
+		default:
+			p.drop(item)
+		}
+	case QueueDropOldest:
+		for {
+			select {
+			case p.queue <- item:
+				return nil
+			default:
+			}
+
+			// a worker may have taken it in the meantime, then just try again
+			select {
+			case oldest := <-p.queue:
+				p.drop(oldest)
+			default:
+			}
+		}
+	default:
+		var timeout <-chan time.Time
+		if p.BlockTimeout > 0 {
+			timer := time.NewTimer(p.BlockTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+
+		select {
+		case p.queue <- item:
+		case <-p.done:
+			p.drop(item)
+			return ErrProcessorShutdown
+		case <-timeout:
+			p.drop(item)
+			return &utils.RetryableError{Err: fmt.Errorf("batch queue still full after %s", p.BlockTimeout)}
+		case <-ctx.Done():
+			p.drop(item)
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func (p *BatchProcessor) drop(item batchItem) {
+	p.dropped.Add(1)
+	p.removePending(item)
+}
+
+// count a new transaction in the current generation so flushes know to wait for it
+func (p *BatchProcessor) addPending() uint64 {
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+
+	p.pending[p.generation]++
+
+	return p.generation
+}
+
+// remove exported or dropped transactions and wake up the flushes that were waiting for them
+func (p *BatchProcessor) removePending(items ...batchItem) {
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+
+	for _, item := range items {
+		p.pending[item.generation]--
+		if p.pending[item.generation] == 0 {
+			delete(p.pending, item.generation)
+		}
+	}
+
+	waits := p.flushWaits[:0]
+	for _, wait := range p.flushWaits {
+		if p.exportedUpTo(wait.generation) {
+			close(wait.done)
+		} else {
+			waits = append(waits, wait)
+		}
+	}
+	p.flushWaits = waits
+}
+
+// check if nothing of the generation or older ones is pending, flushMu must be held
+func (p *BatchProcessor) exportedUpTo(generation uint64) bool {
+	for pending := range p.pending {
+		if pending <= generation {
+			return false
+		}
+	}
+	return true
+}
+
+// get the channel closed by the next flush, and if a flush is running
+func (p *BatchProcessor) flushState() (chan struct{}, bool) {
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+
+	return p.flushSignal, p.flushing > 0
+}
+
+// collect transactions from the queue into batches until it is closed
+func (p *BatchProcessor) work() {
+	defer p.workers.Done()
+
+	batchSize := positiveOr(p.BatchSize, defaultBatchSize)
+	batchTimeout := p.BatchTimeout
+	if batchTimeout <= 0 {
+		batchTimeout = defaultBatchTimeout
+	}
+
+	var batch []batchItem
+	timer := time.NewTimer(batchTimeout)
+	timer.Stop()
+
+	exportBatch := func() {
+		timer.Stop()
+		if len(batch) > 0 {
+			p.exportBatch(batch)
+			batch = nil
+		}
+	}
+
+	for {
+		var item batchItem
+		var ok bool
+
+		select {
+		case item, ok = <-p.queue:
+		default:
+			flushSignal, flushing := p.flushState()
+
+			// nothing else queued, no point in waiting for more while flushing
+			if flushing && len(batch) > 0 {
+				exportBatch()
+				continue
+			}
+
+			select {
+			case item, ok = <-p.queue:
+			case <-timer.C:
+				exportBatch()
+				continue
+			case <-flushSignal:
+				continue
+			}
+		}
+
+		if !ok {
+			exportBatch()
+			return
+		}
+
+		if len(batch) == 0 {
+			timer.Reset(batchTimeout)
+		}
+
+		batch = append(batch, item)
+		if len(batch) >= batchSize {
+			exportBatch()
+		}
+	}
+}
+
+func (p *BatchProcessor) exportBatch(batch []batchItem) {
+	defer p.removePending(batch...)
+
+	if batchExp, ok := p.Exporter.(BatchTransactionExporter); ok {
+		// the transactions of an export share its config, so a batch is
+		// split where the config changes
+		for start := 0; start < len(batch); {
+			end := start + 1
+			for end < len(batch) && maps.Equal(batch[end].config, batch[start].config) {
+				end++
+			}
+
+			transactionLogs := make([]*otel.TransactionLog, 0, end-start)
+			for _, item := range batch[start:end] {
+				transactionLogs = append(transactionLogs, item.transactionLog)
+			}
+
+			p.exportResult(batchExp.ExportTransactions(transactionLogs, batch[start].config), transactionLogs)
+			start = end
+		}
+		return
+	}
+
+	for _, item := range batch {
+		var err error
+		if item.logsOnly {
+			err = p.Exporter.ExportLogs(item.transactionLog.TraceID, item.transactionLog.Spans, item.config)
+		} else {
+			err = exportTransaction(p.Exporter, item.transactionLog, item.config)
+		}
+
+		p.exportResult(err, []*otel.TransactionLog{item.transactionLog})
+	}
+}
+
+func (p *BatchProcessor) exportResult(err error, transactionLogs []*otel.TransactionLog) {
+	if err == nil {
+		p.exported.Add(uint64(len(transactionLogs)))
+		return
+	}
+
+	p.failed.Add(uint64(len(transactionLogs)))
+	if p.OnError != nil {
+		p.OnError(err, transactionLogs)
+	}
+}
+
+func positiveOr(value, fallback int) int {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,371 @@
+package logExporter_test
+
+import (
+	"context"
+	"errors"
+	"otellogger/logExporter"
+	"otellogger/otel"
+	"otellogger/utils"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// exporter taking whole batches
+type testBatchExporter struct {
+	mu      sync.Mutex
+	batches [][]string // trace IDs of each batch
+}
+
+func (exp *testBatchExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	return exp.ExportTransactions([]*otel.TransactionLog{{TraceID: traceID, Spans: logs}}, config)
+}
+
+func (exp *testBatchExporter) ExportTransactions(transactionLogs []*otel.TransactionLog, config map[string]string) error {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	var traceIDs []string
+	for _, transactionLog := range transactionLogs {
+		traceIDs = append(traceIDs, transactionLog.TraceID)
+	}
+	exp.batches = append(exp.batches, traceIDs)
+
+	return nil
+}
+
+func (exp *testBatchExporter) sizes() []int {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	var sizes []int
+	for _, batch := range exp.batches {
+		sizes = append(sizes, len(batch))
+	}
+	return sizes
+}
+
+func TestExportLogsBatch(t *testing.T) {
+	t.Run("Export logs in batches successful", TestExportLogsBatch_Success)
+	t.Run("Export logs in batches with different configs successful", TestExportLogsBatch_Configs)
+	t.Run("Export logs after batch timeout successful", TestExportLogsBatch_Timeout)
+	t.Run("Flush batch processor successful", TestExportLogsBatch_Flush)
+	t.Run("Flush batch processor while logging successful", TestExportLogsBatch_FlushWhileLogging)
+	t.Run("Export logs with full queue - drop newest", TestExportLogsBatch_DropNewest)
+	t.Run("Export logs with full queue - drop oldest", TestExportLogsBatch_DropOldest)
+	t.Run("Export logs with full queue - block", TestExportLogsBatch_Block)
+	t.Run("Error exporting logs with full queue - block timeout", TestExportLogsBatch_BlockTimeout)
+	t.Run("Error exporting logs with full queue - shut down while blocked", TestExportLogsBatch_BlockShutdown)
+	t.Run("Error exporting logs in batches - exporter returns error", TestExportLogsBatch_Error)
+	t.Run("Error exporting logs in batches - shut down", TestExportLogsBatch_Shutdown)
+}
+
+func TestExportLogsBatch_Success(t *testing.T) {
+	downstream := &testBatchExporter{}
+	p := &logExporter.BatchProcessor{Exporter: downstream, BatchSize: 3, BatchTimeout: time.Minute}
+
+	for i := 0; i < 7; i++ {
+		err := p.ExportLogs(strconv.Itoa(i), createTestLog(), nil)
+		assert.Equal(t, nil, err)
+	}
+
+	// two full batches, shutting down exports the rest
+	err := p.Shutdown(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []int{3, 3, 1}, downstream.sizes())
+	assert.Equal(t, []string{"0", "1", "2"}, downstream.batches[0])
+
+	stats := p.Stats()
+	assert.Equal(t, uint64(7), stats.Queued)
+	assert.Equal(t, uint64(7), stats.Exported)
+	assert.Equal(t, uint64(0), stats.Failed)
+}
+
+func TestExportLogsBatch_Configs(t *testing.T) {
+	downstream := &testBatchExporter{}
+	p := &logExporter.BatchProcessor{Exporter: downstream, BatchSize: 4, BatchTimeout: time.Minute}
+
+	// nothing started yet
+	assert.Equal(t, logExporter.BatchStats{}, p.Stats())
+
+	configA := map[string]string{"index": "a"}
+	configB := map[string]string{"index": "b"}
+	for i, config := range []map[string]string{configA, {"index": "a"}, configB, configA} {
+		err := p.ExportLogs(strconv.Itoa(i), createTestLog(), config)
+		assert.Equal(t, nil, err)
+	}
+
+	// every run of the same config is exported with that config
+	err := p.Shutdown(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, [][]string{{"0", "1"}, {"2"}, {"3"}}, downstream.batches)
+	assert.Equal(t, uint64(4), p.Stats().Exported)
+}
+
+func TestExportLogsBatch_Timeout(t *testing.T) {
+	downstream := &testTransactionExporter{}
+	p := &logExporter.BatchProcessor{Exporter: downstream, BatchTimeout: 50 * time.Millisecond}
+	defer p.Shutdown(context.Background())
+
+	err := p.ExportTransaction(createTestTransaction(), nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, downstream.count())
+
+	// exporters without batches get the transactions one by one, as transactions
+	assert.Eventually(t, func() bool { return downstream.count() == 1 }, time.Second, 10*time.Millisecond)
+	assert.Len(t, downstream.transactions, 1)
+}
+
+func TestExportLogsBatch_Flush(t *testing.T) {
+	downstream := &testExporter{delay: 10 * time.Millisecond}
+	p := &logExporter.BatchProcessor{Exporter: downstream, BatchTimeout: time.Minute, Workers: 2}
+	defer p.Shutdown(context.Background())
+
+	// nothing to flush yet
+	err := p.Flush(context.Background())
+	assert.Equal(t, nil, err)
+
+	for i := 0; i < 5; i++ {
+		p.ExportLogs(strconv.Itoa(i), createTestLog(), nil)
+	}
+
+	err = p.Flush(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 5, downstream.count())
+
+	// a flush running out of time
+	downstream = &testExporter{delay: 200 * time.Millisecond}
+	p = &logExporter.BatchProcessor{Exporter: downstream}
+	defer p.Shutdown(context.Background())
+
+	p.ExportLogs("1234567890", createTestLog(), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err = p.Flush(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestExportLogsBatch_FlushWhileLogging(t *testing.T) {
+	downstream := &testExporter{delay: time.Millisecond}
+	p := &logExporter.BatchProcessor{Exporter: downstream, BatchTimeout: time.Minute}
+	defer p.Shutdown(context.Background())
+
+	for i := 0; i < 5; i++ {
+		p.ExportLogs(strconv.Itoa(i), createTestLog(), nil)
+	}
+
+	// keep logging so the queue is never empty
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				p.ExportLogs("1234567890", createTestLog(), nil)
+			}
+		}
+	}()
+
+	// the flush only waits for what was queued before it
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := p.Flush(ctx)
+	close(stop)
+	wg.Wait()
+
+	assert.Equal(t, nil, err)
+	assert.GreaterOrEqual(t, downstream.count(), 5)
+}
+
+func TestExportLogsBatch_DropNewest(t *testing.T) {
+	downstream := &testExporter{delay: 100 * time.Millisecond}
+	p := &logExporter.BatchProcessor{
+		Exporter:        downstream,
+		QueueSize:       2,
+		BatchSize:       1,
+		QueueFullPolicy: logExporter.QueueDropNewest,
+	}
+
+	// the worker takes the first one and is busy with it
+	p.ExportLogs("busy", createTestLog(), nil)
+	time.Sleep(20 * time.Millisecond)
+
+	// a full queue doesn't make the caller wait
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		err := p.ExportLogs(strconv.Itoa(i), createTestLog(), nil)
+		assert.Equal(t, nil, err)
+	}
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+
+	err := p.Shutdown(context.Background())
+	assert.Equal(t, nil, err)
+
+	// only the first ones fit in the queue
+	assert.Equal(t, []string{"busy", "0", "1"}, downstream.exports)
+
+	stats := p.Stats()
+	assert.Equal(t, uint64(6), stats.Queued)
+	assert.Equal(t, uint64(3), stats.Exported)
+	assert.Equal(t, uint64(3), stats.Dropped)
+}
+
+func TestExportLogsBatch_DropOldest(t *testing.T) {
+	downstream := &testExporter{delay: 100 * time.Millisecond}
+	p := &logExporter.BatchProcessor{
+		Exporter:        downstream,
+		QueueSize:       2,
+		BatchSize:       1,
+		QueueFullPolicy: logExporter.QueueDropOldest,
+	}
+
+	p.ExportLogs("busy", createTestLog(), nil)
+	time.Sleep(20 * time.Millisecond)
+
+	for i := 0; i < 5; i++ {
+		err := p.ExportLogs(strconv.Itoa(i), createTestLog(), nil)
+		assert.Equal(t, nil, err)
+	}
+
+	err := p.Shutdown(context.Background())
+	assert.Equal(t, nil, err)
+
+	// only the newest stay in the queue
+	assert.Equal(t, []string{"busy", "3", "4"}, downstream.exports)
+	assert.Equal(t, uint64(3), p.Stats().Dropped)
+}
+
+func TestExportLogsBatch_Block(t *testing.T) {
+	downstream := &testExporter{delay: 30 * time.Millisecond}
+	p := &logExporter.BatchProcessor{Exporter: downstream, QueueSize: 1, BatchSize: 1}
+
+	// waits for room in the queue instead of dropping
+	for i := 0; i < 5; i++ {
+		err := p.ExportLogs(strconv.Itoa(i), createTestLog(), nil)
+		assert.Equal(t, nil, err)
+	}
+
+	err := p.Shutdown(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"0", "1", "2", "3", "4"}, downstream.exports)
+	assert.Equal(t, uint64(0), p.Stats().Dropped)
+}
+
+func TestExportLogsBatch_BlockTimeout(t *testing.T) {
+	downstream := &testExporter{delay: 200 * time.Millisecond}
+	p := &logExporter.BatchProcessor{Exporter: downstream, QueueSize: 1, BatchSize: 1, BlockTimeout: 20 * time.Millisecond}
+	defer p.Shutdown(context.Background())
+
+	// one being exported and one in the queue
+	p.ExportLogs("busy", createTestLog(), nil)
+	time.Sleep(20 * time.Millisecond)
+	p.ExportLogs("queued", createTestLog(), nil)
+
+	err := p.ExportLogs("1234567890", createTestLog(), nil)
+	assert.ErrorContains(t, err, "batch queue still full after 20ms")
+	assert.Equal(t, true, utils.IsRetryable(err))
+
+	// or until the context of the caller is done
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err = p.ExportTransactionContext(ctx, createTestTransaction(), nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, uint64(2), p.Stats().Dropped)
+}
+
+func TestExportLogsBatch_BlockShutdown(t *testing.T) {
+	downstream := &testExporter{delay: 200 * time.Millisecond}
+	p := &logExporter.BatchProcessor{Exporter: downstream, QueueSize: 1, BatchSize: 1}
+
+	p.ExportLogs("busy", createTestLog(), nil)
+	time.Sleep(20 * time.Millisecond)
+	p.ExportLogs("queued", createTestLog(), nil)
+
+	blocked := make(chan error)
+	go func() {
+		blocked <- p.ExportLogs("1234567890", createTestLog(), nil)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// shutting down doesn't wait for the blocked export and keeps to its deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := p.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	select {
+	case err = <-blocked:
+		assert.ErrorIs(t, err, logExporter.ErrProcessorShutdown)
+	case <-time.After(time.Second):
+		t.Fatal("export still blocked after shutdown")
+	}
+
+	// the queued ones are still exported
+	err = p.Shutdown(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"busy", "queued"}, downstream.exports)
+}
+
+func TestExportLogsBatch_Error(t *testing.T) {
+	errDown := errors.New("down")
+	downstream := &testExporter{err: errDown}
+
+	var mu sync.Mutex
+	var failed []string
+
+	p := &logExporter.BatchProcessor{
+		Exporter: downstream,
+		OnError: func(err error, transactionLogs []*otel.TransactionLog) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			assert.Equal(t, errDown, err)
+			for _, transactionLog := range transactionLogs {
+				failed = append(failed, transactionLog.TraceID)
+			}
+		},
+	}
+
+	// the caller doesn't see the error, the hook does
+	err := p.ExportLogs("1234567890", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+
+	err = p.Shutdown(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"1234567890"}, failed)
+	assert.Equal(t, uint64(1), p.Stats().Failed)
+}
+
+func TestExportLogsBatch_Shutdown(t *testing.T) {
+	p := logExporter.NewBatchProcessor(&testExporter{delay: 200 * time.Millisecond})
+	p.ExportLogs("1234567890", createTestLog(), nil)
+	time.Sleep(20 * time.Millisecond)
+
+	// the export is still running
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := p.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	err = p.ExportLogs("1234567890", createTestLog(), nil)
+	assert.ErrorIs(t, err, logExporter.ErrProcessorShutdown)
+
+	// shutting down again waits for the rest
+	err = p.Shutdown(context.Background())
+	assert.Equal(t, nil, err)
+}
@@ -0,0 +1,276 @@
+package logExporter
+
+import (
+	"context"
+	"errors"
+	"otellogger/otel"
+	"otellogger/utils"
+	"sync"
+	"time"
+)
+
+// state of a circuit breaker
+type CircuitState int
+
+const (
+	// exports go to the exporter
+	CircuitClosed CircuitState = iota
+	// the exporter is failing, exports fail fast (or go to the fallback)
+	CircuitOpen
+	// the cooldown is over, a few trial exports check if the exporter recovered
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// returned (wrapped as retryable) while the circuit is open and there is no fallback
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// stop calling an exporter that keeps failing so logging doesn't stall on it
+//
+// only retryable errors (and exports taking longer than Timeout) count as
+// failures, other errors are returned as they are. the circuit opens after
+// ConsecutiveFailures failures in a row or when FailureRate of the last Window
+// exports failed. after Cooldown, HalfOpenCalls trial exports are let through,
+// closing the circuit if all of them succeed and opening it again otherwise.
+// the zero values of the settings mean their defaults
+type CircuitBreakerExporter struct {
+	Exporter LogExporter
+	// optional exporter used while the circuit is open
+	Fallback LogExporter
+
+	// open after this many failures in a row (default 5)
+	ConsecutiveFailures int
+	// open when this fraction of the last Window exports failed (0 to only use ConsecutiveFailures)
+	FailureRate float64
+	// number of exports the failure rate is computed over (default 20)
+	Window int
+	// how long the circuit stays open (default 30s)
+	Cooldown time.Duration
+	// trial exports in the half-open state (default 1)
+	HalfOpenCalls int
+	// exports taking longer count as failed, 0 to wait as long as it takes
+	Timeout time.Duration
+	// called after every state change
+	OnStateChange func(from, to CircuitState)
+
+	mu                sync.Mutex
+	state             CircuitState
+	consecutive       int
+	results           []bool // last exports, true if failed
+	openedAt          time.Time
+	halfOpenInFlight  int
+	halfOpenSuccesses int
+}
+
+const (
+	defaultCircuitConsecutiveFailures = 5
+	defaultCircuitWindow              = 20
+	defaultCircuitCooldown            = 30 * time.Second
+	defaultCircuitHalfOpenCalls       = 1
+)
+
+// create a circuit breaker with the default thresholds
+func NewCircuitBreakerExporter(exporter LogExporter) *CircuitBreakerExporter {
+	return &CircuitBreakerExporter{Exporter: exporter}
+}
+
+// export the logs unless the circuit is open
+func (exp *CircuitBreakerExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	return exp.export(func(ctx context.Context, child LogExporter) error {
+		return exportLogsContext(ctx, child, traceID, logs, config)
+	})
+}
+
+// export the transaction unless the circuit is open
+func (exp *CircuitBreakerExporter) ExportTransaction(transactionLog *otel.TransactionLog, config map[string]string) error {
+	return exp.export(func(ctx context.Context, child LogExporter) error {
+		return exportTransactionContext(ctx, child, transactionLog, config)
+	})
+}
+
+// flush the wrapped exporter and the fallback
+func (exp *CircuitBreakerExporter) Flush(ctx context.Context) error {
+	return flushAll(ctx, exp.Exporter, exp.Fallback)
+}
+
+// shut down the wrapped exporter and the fallback
+func (exp *CircuitBreakerExporter) Shutdown(ctx context.Context) error {
+	return shutdownAll(ctx, exp.Exporter, exp.Fallback)
+}
+
+// get the current state
+func (exp *CircuitBreakerExporter) State() CircuitState {
+	exp.mu.Lock()
+	from, to, state := exp.refreshState()
+	exp.mu.Unlock()
+
+	exp.notify(from, to)
+
+	return state
+}
+
+func (exp *CircuitBreakerExporter) export(export func(ctx context.Context, child LogExporter) error) error {
+	allowed, halfOpen := exp.allow()
+	if !allowed {
+		if exp.Fallback != nil {
+			return export(context.Background(), exp.Fallback)
+		}
+		return &utils.RetryableError{Err: ErrCircuitOpen}
+	}
+
+	err := exportWithTimeout(exp.Exporter, exp.Timeout, export)
+	exp.record(utils.IsRetryable(err), halfOpen)
+
+	return err
+}
+
+// check if an export may go to the exporter
+func (exp *CircuitBreakerExporter) allow() (bool, bool) {
+	exp.mu.Lock()
+	from, to, state := exp.refreshState()
+
+	allowed, halfOpen := false, false
+	switch state {
+	case CircuitClosed:
+		allowed = true
+	case CircuitHalfOpen:
+		if exp.halfOpenInFlight < exp.halfOpenCalls() {
+			exp.halfOpenInFlight++
+			allowed, halfOpen = true, true
+		}
+	}
+	exp.mu.Unlock()
+
+	exp.notify(from, to)
+
+	return allowed, halfOpen
+}
+
+// record the result of an export and change the state if needed
+func (exp *CircuitBreakerExporter) record(failed, halfOpen bool) {
+	exp.mu.Lock()
+	from, to := exp.state, exp.state
+
+	if halfOpen {
+		exp.halfOpenInFlight--
+
+		// a result from before the circuit opened again doesn't count
+		if exp.state == CircuitHalfOpen {
+			if failed {
+				to = exp.open()
+			} else {
+				exp.halfOpenSuccesses++
+				if exp.halfOpenSuccesses >= exp.halfOpenCalls() {
+					to = exp.close()
+				}
+			}
+		}
+	} else if exp.state == CircuitClosed {
+		exp.results = append(exp.results, failed)
+		if len(exp.results) > exp.window() {
+			exp.results = exp.results[1:]
+		}
+
+		if failed {
+			exp.consecutive++
+		} else {
+			exp.consecutive = 0
+		}
+
+		if exp.consecutive >= exp.consecutiveFailures() || exp.failureRateExceeded() {
+			to = exp.open()
+		}
+	}
+	exp.mu.Unlock()
+
+	exp.notify(from, to)
+}
+
+// move from open to half-open once the cooldown is over
+func (exp *CircuitBreakerExporter) refreshState() (CircuitState, CircuitState, CircuitState) {
+	from := exp.state
+	if exp.state == CircuitOpen && time.Since(exp.openedAt) >= exp.cooldown() {
+		exp.state = CircuitHalfOpen
+		exp.halfOpenSuccesses = 0
+	}
+
+	return from, exp.state, exp.state
+}
+
+func (exp *CircuitBreakerExporter) open() CircuitState {
+	exp.state = CircuitOpen
+	exp.openedAt = time.Now()
+
+	return exp.state
+}
+
+func (exp *CircuitBreakerExporter) close() CircuitState {
+	exp.state = CircuitClosed
+	exp.consecutive = 0
+	exp.results = nil
+
+	return exp.state
+}
+
+// only checked once the window is full so a single early failure doesn't open the circuit
+func (exp *CircuitBreakerExporter) failureRateExceeded() bool {
+	if exp.FailureRate <= 0 || len(exp.results) < exp.window() {
+		return false
+	}
+
+	failures := 0
+	for _, failed := range exp.results {
+		if failed {
+			failures++
+		}
+	}
+
+	return float64(failures)/float64(len(exp.results)) >= exp.FailureRate
+}
+
+// call the hook outside of the lock so it can use the exporter
+func (exp *CircuitBreakerExporter) notify(from, to CircuitState) {
+	if from != to && exp.OnStateChange != nil {
+		exp.OnStateChange(from, to)
+	}
+}
+
+func (exp *CircuitBreakerExporter) consecutiveFailures() int {
+	if exp.ConsecutiveFailures <= 0 {
+		return defaultCircuitConsecutiveFailures
+	}
+	return exp.ConsecutiveFailures
+}
+
+func (exp *CircuitBreakerExporter) window() int {
+	if exp.Window <= 0 {
+		return defaultCircuitWindow
+	}
+	return exp.Window
+}
+
+func (exp *CircuitBreakerExporter) cooldown() time.Duration {
+	if exp.Cooldown <= 0 {
+		return defaultCircuitCooldown
+	}
+	return exp.Cooldown
+}
+
+func (exp *CircuitBreakerExporter) halfOpenCalls() int {
+	if exp.HalfOpenCalls <= 0 {
+		return defaultCircuitHalfOpenCalls
+	}
+	return exp.HalfOpenCalls
+}
@@ -0,0 +1,166 @@
+package logExporter_test
+
+import (
+	"errors"
+	"otellogger/logExporter"
+	"otellogger/utils"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// records the state changes of a circuit breaker
+type testCircuitHook struct {
+	mu          sync.Mutex
+	transitions []string
+}
+
+func (hook *testCircuitHook) onStateChange(from, to logExporter.CircuitState) {
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+
+	hook.transitions = append(hook.transitions, from.String()+" -> "+to.String())
+}
+
+func TestExportLogsCircuitBreaker(t *testing.T) {
+	t.Run("Export logs through closed circuit successful", TestExportLogsCircuitBreaker_Closed)
+	t.Run("Export logs through circuit after recovery successful", TestExportLogsCircuitBreaker_Recovery)
+	t.Run("Export logs to fallback while open successful", TestExportLogsCircuitBreaker_Fallback)
+	t.Run("Error exporting logs through circuit - failure rate", TestExportLogsCircuitBreaker_FailureRate)
+	t.Run("Error exporting logs through circuit - timeout", TestExportLogsCircuitBreaker_Timeout)
+}
+
+func TestExportLogsCircuitBreaker_Closed(t *testing.T) {
+	downstream := &testExporter{}
+	exp := logExporter.NewCircuitBreakerExporter(downstream)
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, logExporter.CircuitClosed, exp.State())
+
+	// errors that aren't about the downstream being unavailable don't open the circuit
+	downstream.setErr(errors.New("invalid log"))
+	for i := 0; i < 10; i++ {
+		err = exp.ExportLogs("1234567890", createTestLog(), nil)
+		assert.EqualError(t, err, "invalid log")
+	}
+	assert.Equal(t, logExporter.CircuitClosed, exp.State())
+	assert.Equal(t, 11, downstream.count())
+}
+
+func TestExportLogsCircuitBreaker_Recovery(t *testing.T) {
+	hook := &testCircuitHook{}
+	downstream := &testExporter{err: retryable("down")}
+
+	exp := &logExporter.CircuitBreakerExporter{
+		Exporter:            downstream,
+		ConsecutiveFailures: 3,
+		Cooldown:            100 * time.Millisecond,
+		OnStateChange:       hook.onStateChange,
+	}
+
+	for i := 0; i < 3; i++ {
+		err := exp.ExportLogs("1234567890", createTestLog(), nil)
+		assert.EqualError(t, err, "down")
+	}
+	assert.Equal(t, logExporter.CircuitOpen, exp.State())
+
+	// fails fast without calling the exporter
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.ErrorIs(t, err, logExporter.ErrCircuitOpen)
+	assert.Equal(t, true, utils.IsRetryable(err))
+	assert.Equal(t, 3, downstream.count())
+
+	// a failed trial opens the circuit again
+	time.Sleep(120 * time.Millisecond)
+	assert.Equal(t, logExporter.CircuitHalfOpen, exp.State())
+
+	err = exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.EqualError(t, err, "down")
+	assert.Equal(t, logExporter.CircuitOpen, exp.State())
+
+	// a successful trial closes it
+	downstream.setErr(nil)
+	time.Sleep(120 * time.Millisecond)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, logExporter.CircuitClosed, exp.State())
+	assert.Equal(t, 5, downstream.count())
+
+	assert.Equal(t, []string{
+		"closed -> open",
+		"open -> half-open",
+		"half-open -> open",
+		"open -> half-open",
+		"half-open -> closed",
+	}, hook.transitions)
+}
+
+func TestExportLogsCircuitBreaker_Fallback(t *testing.T) {
+	downstream := &testExporter{err: retryable("down")}
+	fallback := &testTransactionExporter{}
+
+	exp := &logExporter.CircuitBreakerExporter{
+		Exporter:            downstream,
+		Fallback:            fallback,
+		ConsecutiveFailures: 1,
+		Cooldown:            time.Minute,
+	}
+
+	err := exp.ExportTransaction(createTestTransaction(), nil)
+	assert.EqualError(t, err, "down")
+
+	err = exp.ExportTransaction(createTestTransaction(), nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, downstream.count())
+	assert.Len(t, fallback.transactions, 1)
+}
+
+func TestExportLogsCircuitBreaker_FailureRate(t *testing.T) {
+	downstream := &testExporter{}
+
+	exp := &logExporter.CircuitBreakerExporter{
+		Exporter:            downstream,
+		ConsecutiveFailures: 100,
+		FailureRate:         0.5,
+		Window:              4,
+	}
+
+	// alternating failures never reach the consecutive threshold, but half of them fail
+	for i := 0; i < 3; i++ {
+		if i%2 == 0 {
+			downstream.setErr(retryable("flapping"))
+		} else {
+			downstream.setErr(nil)
+		}
+		exp.ExportLogs("1234567890", createTestLog(), nil)
+		assert.Equal(t, logExporter.CircuitClosed, exp.State())
+	}
+
+	downstream.setErr(nil)
+	exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.Equal(t, logExporter.CircuitOpen, exp.State())
+}
+
+func TestExportLogsCircuitBreaker_Timeout(t *testing.T) {
+	downstream := &testExporter{delay: 200 * time.Millisecond}
+
+	exp := &logExporter.CircuitBreakerExporter{
+		Exporter:            downstream,
+		ConsecutiveFailures: 1,
+		Timeout:             20 * time.Millisecond,
+		Cooldown:            time.Minute,
+	}
+
+	start := time.Now()
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.EqualError(t, err, "export timed out after 20ms")
+	assert.Equal(t, logExporter.CircuitOpen, exp.State())
+
+	err = exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.ErrorIs(t, err, logExporter.ErrCircuitOpen)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+}
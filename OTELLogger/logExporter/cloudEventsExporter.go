@@ -0,0 +1,230 @@
+package logExporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"otellogger/otel"
+	"otellogger/utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+// export logs (or whole transactions) as CloudEvents 1.0
+//
+// config keys (plus the shared HTTPConfig keys):
+//   - cloudEventsMode: "structured" (default) or "binary" http content mode,
+//     or "file" to append the events as ndjson to filepath + filename + ".ndjson"
+//   - cloudEventsURL: where to send the events in the http modes
+//   - cloudEventsGranularity: "log" (default) for an event per log or "transaction"
+//   - cloudEventsSource: source attribute (default the service name)
+//   - cloudEventsTypePrefix: prefix of the type attribute (default "otellogger"),
+//     types look like otellogger.log.error or otellogger.transaction
+type CloudEventsExporter struct {
+	HTTPConfig
+
+	mu sync.Mutex // serializes appends to the ndjson file
+}
+
+const defaultCloudEventsTypePrefix = "otellogger"
+
+type cloudEvent struct {
+	SpecVersion     string `json:"specversion"`
+	ID              string `json:"id"`
+	Source          string `json:"source"`
+	Type            string `json:"type"`
+	Subject         string `json:"subject,omitempty"`
+	Time            string `json:"time,omitempty"`
+	DataContentType string `json:"datacontenttype"`
+	TraceParent     string `json:"traceparent"`
+	Data            any    `json:"data"`
+}
+
+// export logs from a transaction, rebuilding the transaction from its logs
+func (exp *CloudEventsExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	// check if there are no logs to export
+	if len(logs) == 0 {
+		return nil
+	}
+
+	return exp.ExportTransaction(transactionFromLogs(traceID, logs), config)
+}
+
+// export a transaction as one event or as an event per log
+func (exp *CloudEventsExporter) ExportTransaction(transactionLog *otel.TransactionLog, config map[string]string) error {
+	if config == nil {
+		return utils.ErrNoConfig
+	}
+
+	var events []*cloudEvent
+	switch config["cloudEventsGranularity"] {
+	case "", "log":
+		for _, log := range transactionLog.Spans {
+			events = append(events, logCloudEvent(log, config))
+		}
+	case "transaction":
+		events = append(events, transactionCloudEvent(transactionLog, config))
+	default:
+		return configError("cloudEventsGranularity", "unsupported cloudEventsGranularity %q", config["cloudEventsGranularity"])
+	}
+
+	switch config["cloudEventsMode"] {
+	case "", "structured", "binary":
+		url, err := configValue(config, "cloudEventsURL")
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			err := exp.sendEvent(url, event, config)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case "file":
+		return exp.appendEvents(events, config)
+	default:
+		return configError("cloudEventsMode", "unsupported cloudEventsMode %q", config["cloudEventsMode"])
+	}
+}
+
+// send an event in the structured or binary content mode
+func (exp *CloudEventsExporter) sendEvent(url string, event *cloudEvent, config map[string]string) error {
+	var body []byte
+	var err error
+
+	binary := config["cloudEventsMode"] == "binary"
+	if binary {
+		// only the data goes in the body, the attributes are headers
+		body, err = json.Marshal(event.Data)
+	} else {
+		body, err = json.Marshal(event)
+	}
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if binary {
+		req.Header.Set("Content-Type", event.DataContentType)
+		req.Header.Set("ce-specversion", event.SpecVersion)
+		req.Header.Set("ce-id", event.ID)
+		req.Header.Set("ce-source", event.Source)
+		req.Header.Set("ce-type", event.Type)
+		if event.Subject != "" {
+			req.Header.Set("ce-subject", event.Subject)
+		}
+		if event.Time != "" {
+			req.Header.Set("ce-time", event.Time)
+		}
+		// the tracing extension maps to the W3C header over http
+		req.Header.Set("traceparent", event.TraceParent)
+	} else {
+		req.Header.Set("Content-Type", "application/cloudevents+json")
+	}
+
+	_, err = exp.send(req, config)
+	return err
+}
+
+// append the events to the ndjson file, one event per line
+func (exp *CloudEventsExporter) appendEvents(events []*cloudEvent, config map[string]string) error {
+	// get the filepath from config
+	filepath, ok := config["filepath"]
+	if !ok {
+		return configError("filepath", "no filepath in config")
+	}
+
+	// get the way the filename will look like
+	filename, ok := config["filename"]
+	if !ok {
+		return configError("filename", "no filename in config")
+	}
+
+	// encode everything first so a failing event doesn't leave a partial write
+	var lines bytes.Buffer
+	encoder := json.NewEncoder(&lines)
+	for _, event := range events {
+		err := encoder.Encode(event)
+		if err != nil {
+			return err
+		}
+	}
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	file, err := os.OpenFile(filepath+filename+".ndjson", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(lines.Bytes())
+	return err
+}
+
+func logCloudEvent(log *otel.OTelLog, config map[string]string) *cloudEvent {
+	event := newCloudEvent(log.ServiceName, config)
+	event.ID = log.TraceID + "-" + log.SpanID
+	event.Type = cloudEventsType(config, "log."+strings.ToLower(log.Severity))
+	event.Subject = log.LoggerName
+	event.TraceParent = traceParent(log.TraceID, log.SpanID)
+	event.Data = log
+
+	if timestamp, err := parseTimestamp(log.Timestamp); err == nil {
+		event.Time = timestamp.Format(time.RFC3339Nano)
+	}
+
+	return event
+}
+
+func transactionCloudEvent(transactionLog *otel.TransactionLog, config map[string]string) *cloudEvent {
+	event := newCloudEvent(transactionLog.ServiceName, config)
+	event.ID = transactionLog.TraceID
+	event.Type = cloudEventsType(config, "transaction")
+	event.Subject = transactionName(transactionLog)
+	event.TraceParent = traceParent(transactionLog.TraceID, transactionLog.TraceID)
+	event.Data = transactionLog
+
+	start, _ := transactionTimespan(transactionLog)
+	if !start.IsZero() {
+		event.Time = start.Format(time.RFC3339Nano)
+	}
+
+	return event
+}
+
+func newCloudEvent(serviceName string, config map[string]string) *cloudEvent {
+	source := config["cloudEventsSource"]
+	if source == "" {
+		source = serviceName
+	}
+
+	return &cloudEvent{
+		SpecVersion:     "1.0",
+		Source:          source,
+		DataContentType: "application/json",
+	}
+}
+
+func cloudEventsType(config map[string]string, suffix string) string {
+	prefix := config["cloudEventsTypePrefix"]
+	if prefix == "" {
+		prefix = defaultCloudEventsTypePrefix
+	}
+
+	return prefix + "." + suffix
+}
+
+// W3C trace context header value, always sampled
+func traceParent(traceID, spanID string) string {
+	return "00-" + traceIDHex(traceID) + "-" + spanIDHex(spanID) + "-01"
+}
@@ -0,0 +1,185 @@
+package logExporter_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"otellogger/logExporter"
+	"otellogger/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportLogsCloudEvents(t *testing.T) {
+	t.Run("Export logs as structured cloud events successful", TestExportLogsCloudEvents_Structured)
+	t.Run("Export logs as binary cloud events successful", TestExportLogsCloudEvents_Binary)
+	t.Run("Export transaction as a cloud event successful", TestExportLogsCloudEvents_Transaction)
+	t.Run("Export logs as cloud events to a file successful", TestExportLogsCloudEvents_File)
+	t.Run("Error exporting logs as cloud events - invalid config", TestExportLogsCloudEvents_InvalidConfig)
+}
+
+func TestExportLogsCloudEvents_Structured(t *testing.T) {
+	var requests []testWebhookRequest
+	server := startTestWebhook(t, &requests)
+
+	exp := &logExporter.CloudEventsExporter{}
+
+	err := exp.ExportLogs("1234567890", nil, nil)
+	assert.Equal(t, nil, err)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"cloudEventsURL": server.URL})
+	assert.Equal(t, nil, err)
+
+	if !assert.Len(t, requests, 2) {
+		return
+	}
+	assert.Equal(t, "application/cloudevents+json", requests[1].header.Get("Content-Type"))
+
+	var event map[string]any
+	assert.Equal(t, nil, json.Unmarshal([]byte(requests[1].body), &event))
+
+	timestamp, _ := time.ParseInLocation(utils.TimestampFormat, "10.03.2025 17:01:00", time.Local)
+	assert.Equal(t, "1.0", event["specversion"])
+	assert.Equal(t, "1234567890-00000000001", event["id"])
+	assert.Equal(t, utils.ServiceName, event["source"])
+	assert.Equal(t, "otellogger.log.info", event["type"])
+	assert.Equal(t, utils.LoggerName, event["subject"])
+	assert.Equal(t, timestamp.Format(time.RFC3339Nano), event["time"])
+	assert.Equal(t, "application/json", event["datacontenttype"])
+	assert.Equal(t, "00-000000000000000000000000499602d2-0000000000000001-01", event["traceparent"])
+	assert.Equal(t, "test message 2", event["data"].(map[string]any)["Message"])
+}
+
+func TestExportLogsCloudEvents_Binary(t *testing.T) {
+	var requests []testWebhookRequest
+	server := startTestWebhook(t, &requests)
+
+	logs := createTestLog()
+	logs[0].Severity = "ERROR"
+
+	exp := &logExporter.CloudEventsExporter{}
+
+	err := exp.ExportLogs("1234567890", logs, map[string]string{
+		"cloudEventsURL":        server.URL,
+		"cloudEventsMode":       "binary",
+		"cloudEventsSource":     "/shop/checkout",
+		"cloudEventsTypePrefix": "com.example",
+	})
+	assert.Equal(t, nil, err)
+
+	if !assert.Len(t, requests, 2) {
+		return
+	}
+
+	// the attributes are headers and the body is only the log
+	header := requests[0].header
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+	assert.Equal(t, "1.0", header.Get("ce-specversion"))
+	assert.Equal(t, "1234567890-00000000000", header.Get("ce-id"))
+	assert.Equal(t, "/shop/checkout", header.Get("ce-source"))
+	assert.Equal(t, "com.example.log.error", header.Get("ce-type"))
+	assert.Equal(t, utils.LoggerName, header.Get("ce-subject"))
+	assert.NotEqual(t, "", header.Get("ce-time"))
+	assert.Equal(t, "00-000000000000000000000000499602d2-0000000000000000-01", header.Get("traceparent"))
+
+	var log map[string]any
+	assert.Equal(t, nil, json.Unmarshal([]byte(requests[0].body), &log))
+	assert.Equal(t, "test message 1", log["Message"])
+	assert.Equal(t, "ERROR", log["Severity"])
+}
+
+func TestExportLogsCloudEvents_Transaction(t *testing.T) {
+	var requests []testWebhookRequest
+	server := startTestWebhook(t, &requests)
+
+	transaction := createTestTransaction()
+	exp := &logExporter.CloudEventsExporter{}
+
+	err := exp.ExportTransaction(transaction, map[string]string{
+		"cloudEventsURL":         server.URL,
+		"cloudEventsGranularity": "transaction",
+	})
+	assert.Equal(t, nil, err)
+
+	if !assert.Len(t, requests, 1) {
+		return
+	}
+
+	var event map[string]any
+	assert.Equal(t, nil, json.Unmarshal([]byte(requests[0].body), &event))
+	assert.Equal(t, "1234567890", event["id"])
+	assert.Equal(t, "otellogger.transaction", event["type"])
+	assert.Equal(t, "checkout", event["subject"])
+	assert.Equal(t, transaction.StartTime.Format(time.RFC3339Nano), event["time"])
+	assert.Equal(t, "00-000000000000000000000000499602d2-00000000499602d2-01", event["traceparent"])
+
+	data := event["data"].(map[string]any)
+	assert.Equal(t, "1234567890", data["TraceID"])
+	assert.Len(t, data["Spans"], 2)
+}
+
+func TestExportLogsCloudEvents_File(t *testing.T) {
+	dir := t.TempDir() + "/"
+	config := map[string]string{
+		"cloudEventsMode": "file",
+		"filepath":        dir,
+		"filename":        "events",
+	}
+
+	exp := &logExporter.CloudEventsExporter{}
+
+	// every export appends to the same file
+	err := exp.ExportLogs("1234567890", createTestLog(), config)
+	assert.Equal(t, nil, err)
+	err = exp.ExportLogs("1234567890", createTestLog()[:1], config)
+	assert.Equal(t, nil, err)
+
+	file, err := os.Open(dir + "events.ndjson")
+	if err != nil {
+		t.Fatalf("Error opening file: %v", err)
+	}
+	defer file.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event map[string]any
+		assert.Equal(t, nil, json.Unmarshal(scanner.Bytes(), &event))
+		ids = append(ids, event["id"].(string))
+	}
+	assert.Equal(t, []string{"1234567890-00000000000", "1234567890-00000000001", "1234567890-00000000000"}, ids)
+}
+
+func TestExportLogsCloudEvents_InvalidConfig(t *testing.T) {
+	exp := &logExporter.CloudEventsExporter{}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.EqualError(t, err, "no config provided")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{})
+	assert.EqualError(t, err, "no cloudEventsURL in config")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"cloudEventsMode": "file", "filepath": "./"})
+	assert.EqualError(t, err, "no filename in config")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"cloudEventsMode": "batched"})
+	assert.EqualError(t, err, `unsupported cloudEventsMode "batched"`)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"cloudEventsGranularity": "span"})
+	assert.EqualError(t, err, `unsupported cloudEventsGranularity "span"`)
+
+	// error statuses are returned as HTTPStatusError
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"cloudEventsURL": server.URL})
+	var statusErr *logExporter.HTTPStatusError
+	assert.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, true, utils.IsRetryable(err))
+}
@@ -0,0 +1,97 @@
+package logExporter
+
+import (
+	"errors"
+	"fmt"
+	"otellogger/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// helpers for reading exporter settings from the logger config
+
+// error about a config value, can be checked with errors.Is(err, utils.ErrInvalidConfig)
+func configError(key, format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	return &utils.ConfigError{Key: key, Msg: err.Error(), Err: errors.Unwrap(err)}
+}
+
+// get a required value from config
+func configValue(config map[string]string, key string) (string, error) {
+	if config == nil {
+		return "", utils.ErrNoConfig
+	}
+
+	value, ok := config[key]
+	if !ok || value == "" {
+		return "", configError(key, "no %s in config", key)
+	}
+
+	return value, nil
+}
+
+// get a duration (e.g. "10s") from config, falling back to the default value if missing
+func configDuration(config map[string]string, key string, defaultValue time.Duration) (time.Duration, error) {
+	value, ok := config[key]
+	if !ok || value == "" {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, configError(key, "invalid %s in config: %w", key, err)
+	}
+
+	return duration, nil
+}
+
+// get a boolean flag from config, false if missing
+func configBool(config map[string]string, key string) (bool, error) {
+	value, ok := config[key]
+	if !ok || value == "" {
+		return false, nil
+	}
+
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, configError(key, "invalid %s in config: %w", key, err)
+	}
+
+	return flag, nil
+}
+
+// get an integer from config, falling back to the default value if missing
+func configInt(config map[string]string, key string, defaultValue int) (int, error) {
+	value, ok := config[key]
+	if !ok || value == "" {
+		return defaultValue, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, configError(key, "invalid %s in config: %w", key, err)
+	}
+
+	return number, nil
+}
+
+// parse a list of key=value pairs separated by commas (e.g. "api-key=secret,tenant=a")
+func parsePairs(value string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	if value == "" {
+		return pairs, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid key=value pair %q", pair)
+		}
+
+		pairs[key] = strings.TrimSpace(val)
+	}
+
+	return pairs, nil
+}
@@ -0,0 +1,479 @@
+package logExporter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"otellogger/otel"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// a transaction that failed to export for good
+type DeadLetter struct {
+	Time time.Time `json:"time"`
+	// exporter that failed and why
+	Exporter string `json:"exporter"`
+	Reason   string `json:"reason"`
+	// how many times the export was tried
+	Attempts    int                  `json:"attempts"`
+	Transaction *otel.TransactionLog `json:"transaction"`
+}
+
+// directory of NDJSON files (one per day) keeping the transactions that
+// failed to export so they can be redriven later. the config isn't kept since
+// it may hold credentials, Redrive takes it again
+type DeadLetterSink struct {
+	Dir string
+
+	mu        sync.Mutex // held while changing the files, never while exporting
+	redriveMu sync.Mutex // one redrive at a time
+}
+
+// outcome of a redrive
+type RedriveResult struct {
+	// dead letters exported and removed from the sink
+	Redriven int
+	// dead letters that failed again and were kept
+	Failed int
+	// malformed lines moved to a .invalid file next to their dead-letter file
+	Invalid int
+}
+
+const (
+	deadLetterExt = ".ndjson"
+	// a file taken by a running redrive, new dead letters of the day go to a new file
+	deadLetterRedriveExt = ".redrive"
+	// lines of a file that aren't dead letters, moved aside by a redrive
+	deadLetterInvalidExt = ".invalid"
+)
+
+// create a dead-letter sink writing to the given directory
+func NewDeadLetterSink(dir string) *DeadLetterSink {
+	return &DeadLetterSink{Dir: dir}
+}
+
+// dead-letter a transaction after its export failed with the given error.
+// the exporter is recorded by the type of the innermost exporter it wraps
+// (see ExporterName) and the attempts are taken from a RetryError, 1 otherwise
+func (s *DeadLetterSink) Add(err error, exporter LogExporter, transactionLog *otel.TransactionLog) error {
+	return s.add(err, ExporterName(exporter), transactionLog)
+}
+
+func (s *DeadLetterSink) add(err error, exporter string, transactionLog *otel.TransactionLog) error {
+	return s.Write(&DeadLetter{
+		Time:        time.Now(),
+		Exporter:    exporter,
+		Reason:      err.Error(),
+		Attempts:    exportAttempts(err),
+		Transaction: transactionLog,
+	})
+}
+
+// append a dead letter to today's file
+func (s *DeadLetterSink) Write(letter *DeadLetter) error {
+	if s.Dir == "" {
+		return errors.New("no directory for the dead-letter sink")
+	}
+
+	line, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err = os.MkdirAll(s.Dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.Dir, letter.Time.Format("2006-01-02")+deadLetterExt)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// get all dead letters, oldest first, including the ones being redriven.
+// malformed lines are skipped
+func (s *DeadLetterSink) Read() ([]*DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	paths, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+
+	var letters []*DeadLetter
+	for _, path := range paths {
+		fileLetters, _, err := readDeadLetters(path)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, fileLetters...)
+	}
+
+	return letters, nil
+}
+
+// export the dead letters again with the given exporter and config, removing
+// the ones that make it. the ones failing again are kept with the new reason
+// and the attempts of the redrive added.
+//
+// each file is taken aside before its dead letters are exported, so exporters
+// dead-lettering in the meantime aren't held up and write to a new file. the
+// exporter is flushed before the redriven ones are removed, if that fails they
+// are kept too. malformed lines are moved to a file of their own
+func (s *DeadLetterSink) Redrive(exp LogExporter, config map[string]string) (RedriveResult, error) {
+	var result RedriveResult
+
+	s.redriveMu.Lock()
+	defer s.redriveMu.Unlock()
+
+	s.mu.Lock()
+	paths, err := s.files()
+	s.mu.Unlock()
+	if err != nil {
+		return result, err
+	}
+
+	for i, path := range paths {
+		// a snapshot left by a redrive that didn't finish and the file of
+		// its day are taken together
+		path = strings.TrimSuffix(path, deadLetterRedriveExt)
+		if i > 0 && strings.TrimSuffix(paths[i-1], deadLetterRedriveExt) == path {
+			continue
+		}
+
+		err = s.redriveFile(path, exp, config, &result)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// redrive the dead letters of a file, see Redrive
+func (s *DeadLetterSink) redriveFile(path string, exp LogExporter, config map[string]string, result *RedriveResult) error {
+	snapshot, err := s.takeSnapshot(path)
+	if err != nil {
+		return err
+	}
+
+	letters, invalid, err := readDeadLetters(snapshot)
+	if err != nil {
+		return err
+	}
+
+	err = keepInvalidDeadLetters(path, invalid)
+	if err != nil {
+		return err
+	}
+	result.Invalid += len(invalid)
+
+	var redriven, kept []*DeadLetter
+	for _, letter := range letters {
+		err := exportTransaction(exp, letter.Transaction, config)
+		if err == nil {
+			redriven = append(redriven, letter)
+			continue
+		}
+
+		failDeadLetter(letter, exp, err)
+		kept = append(kept, letter)
+	}
+
+	// an exporter holding on to exports (e.g. a BatchProcessor) has to be
+	// done with them before the dead letters are removed
+	if len(redriven) > 0 {
+		err = FlushExporter(context.Background(), exp)
+		if err != nil {
+			for _, letter := range redriven {
+				failDeadLetter(letter, exp, err)
+			}
+			kept = append(redriven, kept...)
+			redriven = nil
+		}
+	}
+
+	result.Redriven += len(redriven)
+	result.Failed += len(kept)
+
+	return s.restoreSnapshot(path, kept, result)
+}
+
+// record the failed redrive of a dead letter
+func failDeadLetter(letter *DeadLetter, exp LogExporter, err error) {
+	letter.Exporter = ExporterName(exp)
+	letter.Reason = err.Error()
+	letter.Attempts += exportAttempts(err)
+}
+
+// move a file aside for a redrive, adding it to a snapshot left by an earlier one
+func (s *DeadLetterSink) takeSnapshot(path string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := path + deadLetterRedriveExt
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return snapshot, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(snapshot); err != nil {
+		return snapshot, os.Rename(path, snapshot)
+	}
+
+	file, err := os.OpenFile(snapshot, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = file.Write(data)
+	err = errors.Join(err, file.Close())
+	if err != nil {
+		return "", err
+	}
+
+	return snapshot, os.Remove(path)
+}
+
+// put the dead letters that failed again back in front of the ones written
+// during the redrive and remove the snapshot
+func (s *DeadLetterSink) restoreSnapshot(path string, kept []*DeadLetter, result *RedriveResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(kept) > 0 {
+		added, invalid, err := readDeadLetters(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		// the file is rewritten without them
+		err = keepInvalidDeadLetters(path, invalid)
+		if err != nil {
+			return err
+		}
+		result.Invalid += len(invalid)
+
+		err = rewriteDeadLetters(path, append(kept, added...))
+		if err != nil {
+			return err
+		}
+	}
+
+	return os.Remove(path + deadLetterRedriveExt)
+}
+
+// get the dead-letter files and snapshots, oldest first (their names are
+// dates, a snapshot comes before the file of its day)
+func (s *DeadLetterSink) files() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(s.Dir, "*"+deadLetterExt))
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := filepath.Glob(filepath.Join(s.Dir, "*"+deadLetterExt+deadLetterRedriveExt))
+	if err != nil {
+		return nil, err
+	}
+
+	paths = append(paths, snapshots...)
+	sort.Slice(paths, func(i, j int) bool {
+		dayI, dayJ := strings.TrimSuffix(paths[i], deadLetterRedriveExt), strings.TrimSuffix(paths[j], deadLetterRedriveExt)
+		if dayI != dayJ {
+			return dayI < dayJ
+		}
+		return strings.HasSuffix(paths[i], deadLetterRedriveExt)
+	})
+
+	return paths, nil
+}
+
+// get the attempts from a RetryError, 1 for other errors
+func exportAttempts(err error) int {
+	var retryErr *RetryError
+	if errors.As(err, &retryErr) {
+		return retryErr.Attempts
+	}
+
+	return 1
+}
+
+// name an exporter by the type of the innermost exporter it wraps, e.g.
+// *logExporter.ElasticsearchExporter for a RetryExporter around one. exporters
+// with several children (MultiExporter, FailoverExporter) are named themselves
+func ExporterName(exp LogExporter) string {
+	for {
+		var inner LogExporter
+		switch wrapper := exp.(type) {
+		case *RetryExporter:
+			inner = wrapper.Exporter
+		case *CircuitBreakerExporter:
+			inner = wrapper.Exporter
+		case *BatchProcessor:
+			inner = wrapper.Exporter
+		case *DiskQueue:
+			inner = wrapper.Exporter
+		case *DeadLetterExporter:
+			if wrapper.Name != "" {
+				return wrapper.Name
+			}
+			inner = wrapper.Exporter
+		}
+
+		if inner == nil {
+			return fmt.Sprintf("%T", exp)
+		}
+		exp = inner
+	}
+}
+
+// read the dead letters of a file and the lines that aren't dead letters
+func readDeadLetters(path string) ([]*DeadLetter, [][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var letters []*DeadLetter
+	var invalid [][]byte
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		letter := &DeadLetter{}
+		err = json.Unmarshal(scanner.Bytes(), letter)
+		if err != nil {
+			invalid = append(invalid, bytes.Clone(scanner.Bytes()))
+			continue
+		}
+		letters = append(letters, letter)
+	}
+
+	return letters, invalid, scanner.Err()
+}
+
+// append the malformed lines of a dead-letter file to its .invalid file, so
+// they are kept for a look but don't stop the redrive
+func keepInvalidDeadLetters(path string, lines [][]byte) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(path+deadLetterInvalidExt, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		_, err = file.Write(append(line, '\n'))
+		if err != nil {
+			break
+		}
+	}
+
+	return errors.Join(err, file.Close())
+}
+
+// replace a dead-letter file in one step, removing it once it's empty
+func rewriteDeadLetters(path string, letters []*DeadLetter) error {
+	if len(letters) == 0 {
+		return os.Remove(path)
+	}
+
+	var buf bytes.Buffer
+	for _, letter := range letters {
+		line, err := json.Marshal(letter)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	err := os.WriteFile(path+".tmp", buf.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+// dead-letter the transactions another exporter (e.g. a RetryExporter) fails
+// to export, so the logger sees them as exported and they can be redriven later
+type DeadLetterExporter struct {
+	Exporter LogExporter
+	Sink     *DeadLetterSink
+	// recorded as the exporter of the dead letters, e.g. "elasticsearch"
+	// (default the type of the innermost exporter, see ExporterName)
+	Name string
+}
+
+// create an exporter dead-lettering the failed exports to the sink
+func NewDeadLetterExporter(exporter LogExporter, sink *DeadLetterSink) *DeadLetterExporter {
+	return &DeadLetterExporter{Exporter: exporter, Sink: sink}
+}
+
+// export the logs, dead-lettering them if that fails
+func (exp *DeadLetterExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	return exp.export(transactionFromLogs(traceID, logs), func() error {
+		return exp.Exporter.ExportLogs(traceID, logs, config)
+	})
+}
+
+// export the transaction, dead-lettering it if that fails
+func (exp *DeadLetterExporter) ExportTransaction(transactionLog *otel.TransactionLog, config map[string]string) error {
+	return exp.export(transactionLog, func() error {
+		return exportTransaction(exp.Exporter, transactionLog, config)
+	})
+}
+
+// flush the wrapped exporter
+func (exp *DeadLetterExporter) Flush(ctx context.Context) error {
+	return FlushExporter(ctx, exp.Exporter)
+}
+
+// shut down the wrapped exporter
+func (exp *DeadLetterExporter) Shutdown(ctx context.Context) error {
+	return ShutdownExporter(ctx, exp.Exporter)
+}
+
+func (exp *DeadLetterExporter) export(transactionLog *otel.TransactionLog, export func() error) error {
+	err := export()
+	if err == nil {
+		return nil
+	}
+
+	// only report the export error when the transaction couldn't be kept either
+	sinkErr := exp.Sink.add(err, ExporterName(exp), transactionLog)
+	if sinkErr != nil {
+		return errors.Join(err, fmt.Errorf("dead-lettering: %w", sinkErr))
+	}
+
+	return nil
+}
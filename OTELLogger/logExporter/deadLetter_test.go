@@ -0,0 +1,239 @@
+package logExporter_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"otellogger/logExporter"
+	"otellogger/otel"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportLogsDeadLetter(t *testing.T) {
+	t.Run("Dead-letter failed export successful", TestExportLogsDeadLetter_Success)
+	t.Run("Redrive dead letters successful", TestExportLogsDeadLetter_Redrive)
+	t.Run("Redrive dead letters while more are added successful", TestExportLogsDeadLetter_RedriveConcurrent)
+	t.Run("Redrive dead letters through a buffering exporter successful", TestExportLogsDeadLetter_RedriveFlush)
+	t.Run("Redrive dead letters with malformed lines successful", TestExportLogsDeadLetter_RedriveInvalid)
+	t.Run("Error dead-lettering export - no directory", TestExportLogsDeadLetter_ErrorNoDir)
+}
+
+func TestExportLogsDeadLetter_Success(t *testing.T) {
+	dir := t.TempDir()
+	sink := logExporter.NewDeadLetterSink(dir)
+
+	// the export is retried before being given up on
+	down := retryable("down")
+	retry := &logExporter.RetryExporter{
+		Exporter:        &testFlakyExporter{errs: []error{down, down, down}},
+		InitialInterval: time.Millisecond,
+		MaxAttempts:     3,
+	}
+	exp := logExporter.NewDeadLetterExporter(retry, sink)
+
+	err := exp.ExportTransaction(createTestTransaction(), nil)
+	assert.Equal(t, nil, err)
+
+	// exports that work aren't dead-lettered
+	err = exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+
+	letters, err := sink.Read()
+	assert.Equal(t, nil, err)
+	if !assert.Len(t, letters, 1) {
+		return
+	}
+
+	// named after the exporter inside the retries
+	assert.Equal(t, "*logExporter_test.testFlakyExporter", letters[0].Exporter)
+	assert.Equal(t, "giving up after 3 attempts: down", letters[0].Reason)
+	assert.Equal(t, 3, letters[0].Attempts)
+	assert.Equal(t, createTestTransaction().TraceID, letters[0].Transaction.TraceID)
+	assert.Equal(t, createTestTransaction().Attributes, letters[0].Transaction.Attributes)
+	assert.Len(t, letters[0].Transaction.Spans, len(createTestTransaction().Spans))
+
+	// one NDJSON file per day
+	content, err := os.ReadFile(filepath.Join(dir, letters[0].Time.Format("2006-01-02")+".ndjson"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, strings.Count(string(content), "\n"))
+
+	// or after the name it was given
+	exp.Exporter = &testExporter{err: errors.New("down")}
+	exp.Name = "archive"
+	err = exp.ExportLogs("1234567891", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+
+	letters, _ = sink.Read()
+	if assert.Len(t, letters, 2) {
+		assert.Equal(t, "archive", letters[1].Exporter)
+	}
+}
+
+func TestExportLogsDeadLetter_Redrive(t *testing.T) {
+	dir := t.TempDir()
+	sink := logExporter.NewDeadLetterSink(dir)
+
+	err := sink.Add(errors.New("down"), &testExporter{}, createTestTransaction())
+	assert.Equal(t, nil, err)
+
+	// a letter from an older day
+	transaction := createTestTransaction()
+	transaction.TraceID = "older"
+	err = sink.Write(&logExporter.DeadLetter{Time: time.Now().AddDate(0, 0, -1), Exporter: "old", Reason: "down", Attempts: 2, Transaction: transaction})
+	assert.Equal(t, nil, err)
+
+	// still failing, the letters are kept with one more attempt
+	stillDown := &testExporter{err: errors.New("still down")}
+	result, err := sink.Redrive(stillDown, nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, logExporter.RedriveResult{Redriven: 0, Failed: 2}, result)
+
+	letters, err := sink.Read()
+	assert.Equal(t, nil, err)
+	if assert.Len(t, letters, 2) {
+		assert.Equal(t, "older", letters[0].Transaction.TraceID)
+		assert.Equal(t, 3, letters[0].Attempts)
+		assert.Equal(t, "still down", letters[0].Reason)
+		assert.Equal(t, 2, letters[1].Attempts)
+	}
+
+	// the attempts of retries during the redrive all count
+	retry := &logExporter.RetryExporter{Exporter: stillDown, InitialInterval: time.Millisecond, MaxAttempts: 2}
+	stillDown.setErr(retryable("still down"))
+	result, err = sink.Redrive(retry, nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, logExporter.RedriveResult{Redriven: 0, Failed: 2}, result)
+
+	letters, _ = sink.Read()
+	if assert.Len(t, letters, 2) {
+		assert.Equal(t, 5, letters[0].Attempts)
+		assert.Equal(t, "*logExporter_test.testExporter", letters[0].Exporter)
+	}
+
+	// redriven as transactions, oldest first, and removed
+	up := &testTransactionExporter{}
+	result, err = sink.Redrive(up, map[string]string{"key": "val"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, logExporter.RedriveResult{Redriven: 2, Failed: 0}, result)
+	assert.Equal(t, []string{"older", createTestTransaction().TraceID}, up.exports)
+	assert.Len(t, up.transactions, 2)
+
+	letters, err = sink.Read()
+	assert.Equal(t, nil, err)
+	assert.Len(t, letters, 0)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	assert.Len(t, files, 0)
+}
+
+// exporter dead-lettering into the sink being redriven
+type testDeadLetteringExporter struct {
+	sink *logExporter.DeadLetterSink
+}
+
+func (exp *testDeadLetteringExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	err := errors.New("down again")
+	exp.sink.Add(err, exp, &otel.TransactionLog{TraceID: "new " + traceID, Spans: logs})
+	return err
+}
+
+func TestExportLogsDeadLetter_RedriveConcurrent(t *testing.T) {
+	sink := logExporter.NewDeadLetterSink(t.TempDir())
+	sink.Add(errors.New("down"), &testExporter{}, createTestTransaction())
+
+	// the sink isn't locked while exporting, dead letters added meanwhile are
+	// kept after the ones that failed again
+	result, err := sink.Redrive(&testDeadLetteringExporter{sink: sink}, nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, logExporter.RedriveResult{Redriven: 0, Failed: 1}, result)
+
+	letters, err := sink.Read()
+	assert.Equal(t, nil, err)
+	if assert.Len(t, letters, 2) {
+		assert.Equal(t, createTestTransaction().TraceID, letters[0].Transaction.TraceID)
+		assert.Equal(t, 2, letters[0].Attempts)
+		assert.Equal(t, "new "+createTestTransaction().TraceID, letters[1].Transaction.TraceID)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(sink.Dir, "*"))
+	assert.Len(t, files, 1)
+}
+
+// exporter whose flush fails
+type testFlushingExporter struct {
+	testExporter
+	flushErr error
+}
+
+func (exp *testFlushingExporter) Flush(ctx context.Context) error {
+	return exp.flushErr
+}
+
+func TestExportLogsDeadLetter_RedriveFlush(t *testing.T) {
+	sink := logExporter.NewDeadLetterSink(t.TempDir())
+	sink.Add(errors.New("down"), &testExporter{}, createTestTransaction())
+
+	// the batch is exported before the letter is removed
+	up := &testTransactionExporter{}
+	result, err := sink.Redrive(&logExporter.BatchProcessor{Exporter: up, BatchTimeout: time.Minute}, nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, logExporter.RedriveResult{Redriven: 1}, result)
+	assert.Len(t, up.transactions, 1)
+
+	// letters whose flush failed are kept
+	sink.Add(errors.New("down"), &testExporter{}, createTestTransaction())
+
+	flushing := &testFlushingExporter{flushErr: errors.New("flush failed")}
+	result, err = sink.Redrive(flushing, nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, logExporter.RedriveResult{Failed: 1}, result)
+	assert.Equal(t, 1, flushing.count())
+
+	letters, err := sink.Read()
+	assert.Equal(t, nil, err)
+	if assert.Len(t, letters, 1) {
+		assert.Equal(t, "flush failed", letters[0].Reason)
+		assert.Equal(t, 2, letters[0].Attempts)
+	}
+}
+
+func TestExportLogsDeadLetter_RedriveInvalid(t *testing.T) {
+	sink := logExporter.NewDeadLetterSink(t.TempDir())
+	sink.Add(errors.New("down"), &testExporter{}, createTestTransaction())
+
+	path := filepath.Join(sink.Dir, time.Now().Format("2006-01-02")+".ndjson")
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Error opening dead-letter file: %v", err)
+	}
+	file.WriteString("{not a dead letter\n")
+	file.Close()
+
+	// malformed lines don't stop the redrive, they are moved aside
+	up := &testExporter{}
+	result, err := sink.Redrive(up, nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, logExporter.RedriveResult{Redriven: 1, Invalid: 1}, result)
+	assert.Equal(t, 1, up.count())
+
+	content, err := os.ReadFile(path + ".invalid")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "{not a dead letter\n", string(content))
+
+	letters, err := sink.Read()
+	assert.Equal(t, nil, err)
+	assert.Len(t, letters, 0)
+}
+
+func TestExportLogsDeadLetter_ErrorNoDir(t *testing.T) {
+	exp := logExporter.NewDeadLetterExporter(&testExporter{err: errors.New("down")}, &logExporter.DeadLetterSink{})
+
+	// the export error is returned when the transaction couldn't be dead-lettered
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.EqualError(t, err, "down\ndead-lettering: no directory for the dead-letter sink")
+}
@@ -0,0 +1,788 @@
+package logExporter
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"otellogger/otel"
+	"otellogger/utils"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// keep transactions on disk until they are exported so they survive a crash
+//
+// exports are appended to a write-ahead log in Dir and return right away, a
+// worker exports them in order and only then acknowledges them. the log is
+// split in segment files of about SegmentSize, a segment is deleted once all
+// of its transactions are acknowledged. when the log grows over MaxDiskSize
+// the oldest segments are evicted, exported or not. transactions left over by
+// a previous run are replayed by Open (or the first export). SegmentSize
+// can't be bigger than MaxDiskSize.
+//
+// retryable errors are retried every RetryInterval until the export works,
+// other errors go to OnError and the transaction is acknowledged so it can't
+// block the queue. the zero values of the settings mean their defaults
+type DiskQueue struct {
+	Exporter LogExporter
+	Dir      string
+
+	// size of a segment file (default 8MiB)
+	SegmentSize int64
+	// most disk space used by the segments (default 256MiB)
+	MaxDiskSize int64
+	// wait between retries of a failed export (default 5s)
+	RetryInterval time.Duration
+	// fsync after every write, so transactions also survive the machine crashing
+	Sync bool
+	// called with the error of every transaction that failed for good
+	OnError func(err error, transactionLog *otel.TransactionLog)
+
+	openOnce sync.Once
+	openErr  error
+
+	mu       sync.Mutex
+	segments []*walSegment // oldest first, the last one is written to
+	writer   *os.File
+	reader   *os.File  // oldest segment, kept open for the worker
+	cursor   walCursor // first entry that isn't acknowledged
+	closed   bool
+	shutdown bool          // no more exports are taken
+	changed  chan struct{} // closed when entries are acknowledged or evicted
+
+	wake    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+
+	written  atomic.Uint64
+	exported atomic.Uint64
+	failed   atomic.Uint64
+	evicted  atomic.Uint64
+}
+
+// counters of a disk queue
+type DiskQueueStats struct {
+	// transactions written to disk, exported and failed for good
+	Written  uint64
+	Exported uint64
+	Failed   uint64
+	// transactions deleted unexported because the queue was over MaxDiskSize
+	Evicted uint64
+	// transactions on disk waiting to be exported
+	Pending int
+	// size of the segment files
+	DiskSize int64
+}
+
+// segment file of the write-ahead log
+type walSegment struct {
+	id      uint64
+	size    int64
+	entries int
+	acked   int
+}
+
+// position in the write-ahead log, saved in the cursor file
+type walCursor struct {
+	Segment uint64 `json:"segment"`
+	Offset  int64  `json:"offset"`
+}
+
+// what is stored for each transaction
+type walEntry struct {
+	Transaction *otel.TransactionLog `json:"transaction"`
+	Config      map[string]string    `json:"config,omitempty"`
+	// rebuilt from ExportLogs, exported with ExportLogs again
+	LogsOnly bool `json:"logsOnly,omitempty"`
+}
+
+const (
+	defaultDiskQueueSegmentSize   = 8 << 20
+	defaultDiskQueueMaxDiskSize   = 256 << 20
+	defaultDiskQueueRetryInterval = 5 * time.Second
+
+	walSegmentExt   = ".wal"
+	walCursorFile   = "cursor.json"
+	walHeaderSize   = 8 // payload length and crc32
+	walMaxEntrySize = 64 << 20
+)
+
+// returned when exporting to a DiskQueue that was closed
+var ErrQueueClosed = errors.New("disk queue is closed")
+
+var errWALCorrupt = errors.New("corrupt write-ahead log entry")
+
+// create a disk queue in the given directory with the default sizes
+func NewDiskQueue(exporter LogExporter, dir string) *DiskQueue {
+	return &DiskQueue{Exporter: exporter, Dir: dir}
+}
+
+// write the logs to disk for export
+func (q *DiskQueue) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	return q.append(walEntry{Transaction: transactionFromLogs(traceID, logs), Config: config, LogsOnly: true})
+}
+
+// write the transaction to disk for export
+func (q *DiskQueue) ExportTransaction(transactionLog *otel.TransactionLog, config map[string]string) error {
+	return q.append(walEntry{Transaction: transactionLog, Config: config})
+}
+
+// load the write-ahead log and start exporting what a previous run left behind
+func (q *DiskQueue) Open() error {
+	q.openOnce.Do(func() {
+		q.openErr = q.open()
+		if q.openErr == nil {
+			go q.work()
+		}
+	})
+
+	return q.openErr
+}
+
+// stop exporting, transactions not exported yet stay on disk for the next run.
+// waits for an export that is already running
+func (q *DiskQueue) Close() error {
+	if err := q.Open(); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	close(q.done)
+	q.mu.Unlock()
+
+	<-q.stopped
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return errors.Join(q.writer.Close(), q.closeReader())
+}
+
+// wait until everything written so far is exported (or the context is done),
+// then flush the wrapped exporter
+func (q *DiskQueue) Flush(ctx context.Context) error {
+	err := q.waitExported(ctx)
+	if err != nil {
+		return err
+	}
+
+	return FlushExporter(ctx, q.Exporter)
+}
+
+// stop taking exports, export everything on disk, stop the worker and shut
+// down the wrapped exporter. when the context is done first the worker is
+// stopped anyway, the rest stays on disk for the next run and the context
+// error is returned
+func (q *DiskQueue) Shutdown(ctx context.Context) error {
+	if err := q.Open(); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	q.shutdown = true
+	q.mu.Unlock()
+
+	err := q.waitExported(ctx)
+
+	err = errors.Join(err, q.Close())
+	if err != nil {
+		return err
+	}
+
+	return ShutdownExporter(ctx, q.Exporter)
+}
+
+// get the counters
+func (q *DiskQueue) Stats() DiskQueueStats {
+	stats := DiskQueueStats{
+		Written:  q.written.Load(),
+		Exported: q.exported.Load(),
+		Failed:   q.failed.Load(),
+		Evicted:  q.evicted.Load(),
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats.Pending = q.pending()
+	for _, segment := range q.segments {
+		stats.DiskSize += segment.size
+	}
+
+	return stats
+}
+
+// wait until everything written before the call is acknowledged, entries
+// written while waiting aren't waited for
+func (q *DiskQueue) waitExported(ctx context.Context) error {
+	if err := q.Open(); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	last := q.segments[len(q.segments)-1]
+	written := walCursor{Segment: last.id, Offset: last.size}
+	q.mu.Unlock()
+
+	for {
+		q.mu.Lock()
+		cursor, changed, closed := q.cursor, q.changed, q.closed
+		q.mu.Unlock()
+
+		if !cursor.before(written) {
+			return nil
+		}
+		if closed {
+			return ErrQueueClosed
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// number of entries not acknowledged yet, called with the lock held
+func (q *DiskQueue) pending() int {
+	pending := 0
+	for _, segment := range q.segments {
+		pending += segment.entries - segment.acked
+	}
+	return pending
+}
+
+// wake up the waiting flushes, called with the lock held
+func (q *DiskQueue) notifyChanged() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+func (q *DiskQueue) open() error {
+	if q.Dir == "" {
+		return errors.New("no directory for the disk queue")
+	}
+
+	// the segment being written to is never evicted, so it has to fit
+	segmentSize := positiveOr64(q.SegmentSize, defaultDiskQueueSegmentSize)
+	maxDiskSize := positiveOr64(q.MaxDiskSize, defaultDiskQueueMaxDiskSize)
+	if segmentSize > maxDiskSize {
+		return fmt.Errorf("disk queue SegmentSize %d is bigger than MaxDiskSize %d", segmentSize, maxDiskSize)
+	}
+
+	err := os.MkdirAll(q.Dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	ids, err := q.segmentIDs()
+	if err != nil {
+		return err
+	}
+
+	cursor, err := q.readCursor()
+	if err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		// fully acknowledged before the last run stopped
+		if id < cursor.Segment {
+			err = os.Remove(q.segmentPath(id))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		segment, err := q.scanSegment(id, cursor, i == len(ids)-1)
+		if err != nil {
+			return err
+		}
+		q.segments = append(q.segments, segment)
+	}
+
+	if len(q.segments) == 0 {
+		err = q.addSegment(cursor.Segment + 1)
+	} else {
+		q.writer, err = os.OpenFile(q.segmentPath(q.segments[len(q.segments)-1].id), os.O_WRONLY|os.O_APPEND, 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	// the cursor's segment is gone (e.g. evicted or all exported), start at the oldest one left
+	q.cursor = cursor
+	if q.segments[0].id != cursor.Segment {
+		q.cursor = walCursor{Segment: q.segments[0].id}
+	}
+
+	q.wake = make(chan struct{}, 1)
+	q.changed = make(chan struct{})
+	q.done = make(chan struct{})
+	q.stopped = make(chan struct{})
+
+	return nil
+}
+
+// get the ids of the segment files, oldest first
+func (q *DiskQueue) segmentIDs() ([]uint64, error) {
+	files, err := os.ReadDir(q.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint64
+	for _, file := range files {
+		name, ok := strings.CutSuffix(file.Name(), walSegmentExt)
+		if !ok {
+			continue
+		}
+
+		id, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids, nil
+}
+
+// count the entries of a segment. a broken entry at the end of the last
+// segment was being written when the process died and is cut off
+func (q *DiskQueue) scanSegment(id uint64, cursor walCursor, last bool) (*walSegment, error) {
+	file, err := os.Open(q.segmentPath(id))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	segment := &walSegment{id: id}
+	for {
+		_, size, err := readWALEntry(file, segment.size)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if !last {
+				// keep the rest of an older segment, the worker skips it
+				info, statErr := file.Stat()
+				if statErr != nil {
+					return nil, statErr
+				}
+				segment.size = info.Size()
+				break
+			}
+
+			err = os.Truncate(q.segmentPath(id), segment.size)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+
+		if id == cursor.Segment && segment.size < cursor.Offset {
+			segment.acked++
+		}
+		segment.entries++
+		segment.size += size
+	}
+
+	return segment, nil
+}
+
+func (q *DiskQueue) append(entry walEntry) error {
+	if err := q.Open(); err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	record := make([]byte, walHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	copy(record[walHeaderSize:], payload)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed || q.shutdown {
+		return ErrQueueClosed
+	}
+
+	segment := q.segments[len(q.segments)-1]
+	if segment.size > 0 && segment.size+int64(len(record)) > positiveOr64(q.SegmentSize, defaultDiskQueueSegmentSize) {
+		err = q.writer.Close()
+		if err != nil {
+			return err
+		}
+
+		err = q.addSegment(segment.id + 1)
+		if err != nil {
+			return err
+		}
+		segment = q.segments[len(q.segments)-1]
+	}
+
+	_, err = q.writer.Write(record)
+	if err == nil && q.Sync {
+		err = q.writer.Sync()
+	}
+	if err != nil {
+		return errors.Join(err, q.discardWrite(segment))
+	}
+
+	segment.size += int64(len(record))
+	segment.entries++
+	q.written.Add(1)
+
+	err = q.evict()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// start writing to a new segment
+func (q *DiskQueue) addSegment(id uint64) error {
+	writer, err := os.OpenFile(q.segmentPath(id), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	q.writer = writer
+	q.segments = append(q.segments, &walSegment{id: id})
+
+	return nil
+}
+
+// cut off what a failed write left at the end of the segment, so the next
+// record isn't appended after half of this one. if that fails too the next
+// records go to a new segment, the end of this one is never read as only
+// segment.size bytes of it are
+func (q *DiskQueue) discardWrite(segment *walSegment) error {
+	err := q.writer.Truncate(segment.size)
+	if err == nil {
+		return nil
+	}
+
+	q.writer.Close()
+
+	return q.addSegment(segment.id + 1)
+}
+
+// delete the oldest segments while over the max disk size, never the one being
+// written to (open makes sure it fits)
+func (q *DiskQueue) evict() error {
+	maxDiskSize := positiveOr64(q.MaxDiskSize, defaultDiskQueueMaxDiskSize)
+
+	var diskSize int64
+	for _, segment := range q.segments {
+		diskSize += segment.size
+	}
+
+	for diskSize > maxDiskSize && len(q.segments) > 1 {
+		oldest := q.segments[0]
+
+		// the worker reads from the oldest segment
+		q.closeReader()
+
+		err := os.Remove(q.segmentPath(oldest.id))
+		if err != nil {
+			return err
+		}
+
+		q.segments = q.segments[1:]
+		q.evicted.Add(uint64(oldest.entries - oldest.acked))
+		diskSize -= oldest.size
+		q.notifyChanged()
+
+		if q.cursor.Segment <= oldest.id {
+			q.cursor = walCursor{Segment: q.segments[0].id}
+			err = q.writeCursor()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// export the entries in order until the queue is closed
+func (q *DiskQueue) work() {
+	defer close(q.stopped)
+
+	for {
+		entry, cursor, next, err := q.next()
+		if err != nil {
+			// the disk failing isn't something retrying right away fixes
+			if !q.wait(q.retryInterval()) {
+				return
+			}
+			continue
+		}
+
+		if entry == nil {
+			select {
+			case <-q.wake:
+				continue
+			case <-q.done:
+				return
+			}
+		}
+
+		if !q.export(entry) {
+			return
+		}
+
+		err = q.ack(cursor, next)
+		if err != nil {
+			// exported already, at worst it's exported again after a restart
+			if !q.wait(q.retryInterval()) {
+				return
+			}
+		}
+	}
+}
+
+// export an entry, retrying retryable errors. false if the queue was closed first
+func (q *DiskQueue) export(entry *walEntry) bool {
+	for {
+		var err error
+		if entry.LogsOnly {
+			err = q.Exporter.ExportLogs(entry.Transaction.TraceID, entry.Transaction.Spans, entry.Config)
+		} else {
+			err = exportTransaction(q.Exporter, entry.Transaction, entry.Config)
+		}
+
+		if err == nil {
+			q.exported.Add(1)
+			return true
+		}
+
+		if !utils.IsRetryable(err) {
+			q.failed.Add(1)
+			if q.OnError != nil {
+				q.OnError(err, entry.Transaction)
+			}
+			return true
+		}
+
+		if !q.wait(q.retryInterval()) {
+			return false
+		}
+	}
+}
+
+// wait or stop early if the queue is closed, false if it was
+func (q *DiskQueue) wait(interval time.Duration) bool {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-q.done:
+		return false
+	}
+}
+
+// read the first entry that isn't acknowledged, nil if there is none
+func (q *DiskQueue) next() (*walEntry, walCursor, int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		segment := q.segments[0]
+		cursor := q.cursor
+
+		if cursor.Offset >= segment.size {
+			// wait for more to be written
+			if len(q.segments) == 1 {
+				return nil, cursor, 0, nil
+			}
+
+			// done with this segment
+			q.closeReader()
+
+			err := os.Remove(q.segmentPath(segment.id))
+			if err != nil {
+				return nil, cursor, 0, err
+			}
+
+			q.segments = q.segments[1:]
+			q.cursor = walCursor{Segment: q.segments[0].id}
+			err = q.writeCursor()
+			if err != nil {
+				return nil, cursor, 0, err
+			}
+			continue
+		}
+
+		// the segment stays open until the worker is done with it
+		if q.reader == nil {
+			reader, err := os.Open(q.segmentPath(segment.id))
+			if err != nil {
+				return nil, cursor, 0, err
+			}
+			q.reader = reader
+		}
+
+		payload, size, err := readWALEntry(q.reader, cursor.Offset)
+
+		entry := &walEntry{}
+		if err == nil {
+			err = json.Unmarshal(payload, entry)
+		}
+		if err != nil {
+			// skip the rest of a broken segment
+			q.failed.Add(uint64(segment.entries - segment.acked))
+			segment.acked = segment.entries
+			q.cursor.Offset = segment.size
+			q.notifyChanged()
+			continue
+		}
+
+		return entry, cursor, cursor.Offset + size, nil
+	}
+}
+
+// acknowledge the entry at the cursor, unless its segment was evicted meanwhile
+func (q *DiskQueue) ack(cursor walCursor, next int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.cursor != cursor {
+		return nil
+	}
+
+	q.segments[0].acked++
+	q.cursor.Offset = next
+	q.notifyChanged()
+
+	return q.writeCursor()
+}
+
+// close the segment the worker reads from, called with the lock held
+func (q *DiskQueue) closeReader() error {
+	if q.reader == nil {
+		return nil
+	}
+
+	err := q.reader.Close()
+	q.reader = nil
+
+	return err
+}
+
+// save the cursor, replacing the old one in one step so a crash can't leave half of it
+func (q *DiskQueue) writeCursor() error {
+	data, err := json.Marshal(q.cursor)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(q.Dir, walCursorFile)
+	err = os.WriteFile(path+".tmp", data, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+// check if the cursor hasn't reached the position yet
+func (c walCursor) before(position walCursor) bool {
+	return c.Segment < position.Segment || (c.Segment == position.Segment && c.Offset < position.Offset)
+}
+
+func (q *DiskQueue) readCursor() (walCursor, error) {
+	var cursor walCursor
+
+	data, err := os.ReadFile(filepath.Join(q.Dir, walCursorFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return cursor, nil
+	}
+	if err != nil {
+		return cursor, err
+	}
+
+	err = json.Unmarshal(data, &cursor)
+	if err != nil {
+		return cursor, fmt.Errorf("invalid disk queue cursor: %w", err)
+	}
+
+	return cursor, nil
+}
+
+func (q *DiskQueue) segmentPath(id uint64) string {
+	return filepath.Join(q.Dir, fmt.Sprintf("%020d%s", id, walSegmentExt))
+}
+
+func (q *DiskQueue) retryInterval() time.Duration {
+	if q.RetryInterval <= 0 {
+		return defaultDiskQueueRetryInterval
+	}
+	return q.RetryInterval
+}
+
+// read the entry at the offset, returning its payload and size on disk
+func readWALEntry(file *os.File, offset int64) ([]byte, int64, error) {
+	header := make([]byte, walHeaderSize)
+	n, err := file.ReadAt(header, offset)
+	if err == io.EOF && n == 0 {
+		return nil, 0, io.EOF
+	}
+	if err != nil {
+		return nil, 0, errWALCorrupt
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length > walMaxEntrySize {
+		return nil, 0, errWALCorrupt
+	}
+
+	payload := make([]byte, length)
+	_, err = file.ReadAt(payload, offset+walHeaderSize)
+	if err != nil || crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, 0, errWALCorrupt
+	}
+
+	return payload, walHeaderSize + int64(length), nil
+}
+
+func positiveOr64(value, fallback int64) int64 {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,299 @@
+package logExporter_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"otellogger/logExporter"
+	"otellogger/otel"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func walFiles(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*.wal"))
+	if err != nil {
+		t.Fatalf("Error listing segments: %v", err)
+	}
+	return files
+}
+
+func TestExportLogsDiskQueue(t *testing.T) {
+	t.Run("Export logs through disk queue successful", TestExportLogsDiskQueue_Success)
+	t.Run("Export logs left from previous run successful", TestExportLogsDiskQueue_Replay)
+	t.Run("Export logs after torn write successful", TestExportLogsDiskQueue_TornWrite)
+	t.Run("Export logs over max disk size - oldest evicted", TestExportLogsDiskQueue_Evict)
+	t.Run("Flush and shut down disk queue successful", TestExportLogsDiskQueue_Shutdown)
+	t.Run("Flush disk queue while logging successful", TestExportLogsDiskQueue_FlushWhileLogging)
+	t.Run("Error exporting logs through disk queue - not retryable", TestExportLogsDiskQueue_NotRetryable)
+	t.Run("Error exporting logs through disk queue - closed", TestExportLogsDiskQueue_Closed)
+}
+
+func TestExportLogsDiskQueue_Success(t *testing.T) {
+	dir := t.TempDir()
+	downstream := &testTransactionExporter{}
+
+	q := &logExporter.DiskQueue{Exporter: downstream, Dir: dir, SegmentSize: 1024}
+
+	for i := 0; i < 10; i++ {
+		err := q.ExportTransaction(createTestTransaction(), map[string]string{"key": strconv.Itoa(i)})
+		assert.Equal(t, nil, err)
+	}
+	err := q.ExportLogs("1234567890", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+
+	assert.Eventually(t, func() bool { return q.Stats().Pending == 0 }, time.Second, 10*time.Millisecond)
+
+	err = q.Close()
+	assert.Equal(t, nil, err)
+
+	// transactions stay transactions, logs stay logs
+	assert.Equal(t, 11, downstream.count())
+	assert.Len(t, downstream.transactions, 10)
+	assert.Equal(t, createTestTransaction().Attributes, downstream.transactions[0].Attributes)
+	assert.True(t, createTestTransaction().StartTime.Equal(downstream.transactions[0].StartTime))
+
+	stats := q.Stats()
+	assert.Equal(t, uint64(11), stats.Written)
+	assert.Equal(t, uint64(11), stats.Exported)
+
+	// exported segments are deleted, only the one being written to is left
+	assert.Len(t, walFiles(t, dir), 1)
+}
+
+func TestExportLogsDiskQueue_Replay(t *testing.T) {
+	dir := t.TempDir()
+
+	// the exporter is down until the process dies
+	down := &testExporter{err: retryable("down")}
+	q := &logExporter.DiskQueue{Exporter: down, Dir: dir, SegmentSize: 512, RetryInterval: 10 * time.Millisecond}
+
+	for i := 0; i < 5; i++ {
+		err := q.ExportLogs(strconv.Itoa(i), createTestLog(), nil)
+		assert.Equal(t, nil, err)
+	}
+
+	assert.Eventually(t, func() bool { return down.count() >= 2 }, time.Second, 5*time.Millisecond)
+	err := q.Close()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 5, q.Stats().Pending)
+
+	// the next run exports them in order
+	up := &testExporter{}
+	q = logExporter.NewDiskQueue(up, dir)
+
+	err = q.Open()
+	assert.Equal(t, nil, err)
+
+	assert.Eventually(t, func() bool { return up.count() == 5 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"0", "1", "2", "3", "4"}, up.exports)
+
+	err = q.Close()
+	assert.Equal(t, nil, err)
+
+	// nothing left for a third run
+	again := &testExporter{}
+	q = logExporter.NewDiskQueue(again, dir)
+	q.Open()
+	time.Sleep(50 * time.Millisecond)
+	q.Close()
+	assert.Equal(t, 0, again.count())
+}
+
+func TestExportLogsDiskQueue_TornWrite(t *testing.T) {
+	dir := t.TempDir()
+
+	q := &logExporter.DiskQueue{Exporter: &testExporter{err: retryable("down")}, Dir: dir, RetryInterval: time.Minute}
+	q.ExportLogs("1234567890", createTestLog(), nil)
+	q.Close()
+
+	// the process died halfway through writing the next entry
+	files := walFiles(t, dir)
+	if !assert.Len(t, files, 1) {
+		return
+	}
+
+	file, err := os.OpenFile(files[0], os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("Error opening segment: %v", err)
+	}
+	file.Write([]byte{0, 0, 1, 0, 42})
+	file.Close()
+
+	downstream := &testExporter{}
+	q = logExporter.NewDiskQueue(downstream, dir)
+
+	err = q.ExportLogs("0987654321", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+
+	assert.Eventually(t, func() bool { return downstream.count() == 2 }, time.Second, 10*time.Millisecond)
+	q.Close()
+	assert.Equal(t, []string{"1234567890", "0987654321"}, downstream.exports)
+}
+
+func TestExportLogsDiskQueue_Evict(t *testing.T) {
+	dir := t.TempDir()
+
+	q := &logExporter.DiskQueue{
+		Exporter:      &testExporter{err: retryable("down")},
+		Dir:           dir,
+		SegmentSize:   600,
+		MaxDiskSize:   2000,
+		RetryInterval: time.Minute,
+	}
+
+	for i := 0; i < 30; i++ {
+		err := q.ExportLogs(strconv.Itoa(i), createTestLog(), nil)
+		assert.Equal(t, nil, err)
+	}
+
+	stats := q.Stats()
+	assert.LessOrEqual(t, stats.DiskSize, int64(2000))
+	assert.Greater(t, stats.Evicted, uint64(0))
+	assert.Equal(t, 30, stats.Pending+int(stats.Evicted))
+
+	q.Close()
+
+	// the newest transactions are kept
+	downstream := &testExporter{}
+	q = logExporter.NewDiskQueue(downstream, dir)
+	q.Open()
+
+	assert.Eventually(t, func() bool { return downstream.count() == stats.Pending }, time.Second, 10*time.Millisecond)
+	q.Close()
+	assert.Equal(t, "29", downstream.exports[len(downstream.exports)-1])
+}
+
+func TestExportLogsDiskQueue_Shutdown(t *testing.T) {
+	dir := t.TempDir()
+	downstream := &testLifecycleExporter{}
+	downstream.delay = 20 * time.Millisecond
+
+	q := logExporter.NewDiskQueue(downstream, dir)
+
+	// nothing to wait for yet
+	err := q.Flush(context.Background())
+	assert.Equal(t, nil, err)
+
+	for i := 0; i < 3; i++ {
+		q.ExportLogs(strconv.Itoa(i), createTestLog(), nil)
+	}
+
+	err = q.Flush(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, downstream.count())
+	assert.Equal(t, 0, q.Stats().Pending)
+
+	q.ExportLogs("3", createTestLog(), nil)
+
+	err = q.Shutdown(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 4, downstream.count())
+	// each flush reaches the wrapped exporter, shutting down also closes it
+	assert.Equal(t, []string{"flush", "flush", "flush", "close"}, downstream.calls)
+
+	err = q.ExportLogs("1234567890", createTestLog(), nil)
+	assert.ErrorIs(t, err, logExporter.ErrQueueClosed)
+
+	// running out of time leaves the rest on disk
+	down := &testExporter{err: retryable("down")}
+	q = &logExporter.DiskQueue{Exporter: down, Dir: dir, RetryInterval: time.Minute}
+	q.ExportLogs("1234567890", createTestLog(), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err = q.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, q.Stats().Pending)
+}
+
+func TestExportLogsDiskQueue_FlushWhileLogging(t *testing.T) {
+	downstream := &testExporter{delay: time.Millisecond}
+	q := &logExporter.DiskQueue{Exporter: downstream, Dir: t.TempDir(), SegmentSize: 4096}
+	defer q.Close()
+
+	for i := 0; i < 5; i++ {
+		q.ExportLogs(strconv.Itoa(i), createTestLog(), nil)
+	}
+
+	// keep logging so something is always pending
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				q.ExportLogs("1234567890", createTestLog(), nil)
+			}
+		}
+	}()
+
+	// the flush only waits for what was written before it
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := q.Flush(ctx)
+	close(stop)
+	wg.Wait()
+
+	assert.Equal(t, nil, err)
+	assert.GreaterOrEqual(t, downstream.count(), 5)
+}
+
+func TestExportLogsDiskQueue_NotRetryable(t *testing.T) {
+	errInvalid := errors.New("invalid log")
+
+	var mu sync.Mutex
+	var failed []string
+
+	downstream := &testExporter{err: errInvalid}
+	q := &logExporter.DiskQueue{
+		Exporter: downstream,
+		Dir:      t.TempDir(),
+		OnError: func(err error, transactionLog *otel.TransactionLog) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			assert.Equal(t, errInvalid, err)
+			failed = append(failed, transactionLog.TraceID)
+		},
+	}
+
+	// a failing transaction doesn't hold up the ones after it
+	q.ExportLogs("1", createTestLog(), nil)
+	q.ExportLogs("2", createTestLog(), nil)
+
+	assert.Eventually(t, func() bool { return q.Stats().Pending == 0 }, time.Second, 10*time.Millisecond)
+	q.Close()
+
+	assert.Equal(t, []string{"1", "2"}, failed)
+	assert.Equal(t, uint64(2), q.Stats().Failed)
+}
+
+func TestExportLogsDiskQueue_Closed(t *testing.T) {
+	q := logExporter.NewDiskQueue(&testExporter{}, t.TempDir())
+
+	err := q.Close()
+	assert.Equal(t, nil, err)
+
+	err = q.ExportLogs("1234567890", createTestLog(), nil)
+	assert.ErrorIs(t, err, logExporter.ErrQueueClosed)
+
+	err = logExporter.NewDiskQueue(&testExporter{}, "").ExportLogs("1234567890", createTestLog(), nil)
+	assert.EqualError(t, err, "no directory for the disk queue")
+
+	// a segment that doesn't fit could never be evicted
+	q = &logExporter.DiskQueue{Exporter: &testExporter{}, Dir: t.TempDir(), SegmentSize: 4096, MaxDiskSize: 1024}
+	err = q.Open()
+	assert.EqualError(t, err, "disk queue SegmentSize 4096 is bigger than MaxDiskSize 1024")
+}
@@ -0,0 +1,241 @@
+package logExporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"otellogger/otel"
+	"otellogger/utils"
+	"strings"
+	"time"
+)
+
+// export logs to Elasticsearch or OpenSearch through the _bulk api
+//
+// every log is created with the ID traceID-spanID, so exporting a transaction
+// again after a partial failure doesn't duplicate the logs that made it. with
+// a BatchProcessor the logs of a whole batch go in one bulk request
+//
+// config keys (plus the shared HTTPConfig keys):
+//   - elasticsearchEndpoint: base url, e.g. "http://localhost:9200" (required)
+//   - elasticsearchIndex: index name pattern with the {service}, {logger} and
+//     {date} placeholders (default "logs-{service}-{date}")
+//   - elasticsearchDateFormat: Go layout used for {date} (default "2006.01.02")
+//   - elasticsearchUsername, elasticsearchPassword: basic auth
+//   - elasticsearchAPIKey: API key auth (base64 encoded id:key)
+type ElasticsearchExporter struct {
+	HTTPConfig
+}
+
+const (
+	defaultElasticsearchIndex      = "logs-{service}-{date}"
+	defaultElasticsearchDateFormat = "2006.01.02"
+)
+
+// error returned when some of the items in a bulk request failed
+type BulkError struct {
+	Items []BulkItemError
+}
+
+type BulkItemError struct {
+	ID     string
+	Index  string
+	Status int
+	Type   string
+	Reason string
+}
+
+func (e *BulkError) Error() string {
+	first := e.Items[0]
+	return fmt.Sprintf("%d bulk items failed, first: %s [%d] %s: %s", len(e.Items), first.ID, first.Status, first.Type, first.Reason)
+}
+
+type elasticsearchDocument struct {
+	Timestamp   string            `json:"@timestamp,omitempty"`
+	Message     string            `json:"message"`
+	Level       string            `json:"log.level"`
+	LoggerName  string            `json:"log.logger"`
+	ServiceName string            `json:"service.name"`
+	TraceID     string            `json:"trace.id"`
+	SpanID      string            `json:"span.id"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+}
+
+type bulkAction struct {
+	Create bulkActionMeta `json:"create"`
+}
+
+type bulkActionMeta struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id"`
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Index  string `json:"_index"`
+		ID     string `json:"_id"`
+		Status int    `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// export logs from a transaction in a single bulk request
+func (exp *ElasticsearchExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	return exp.bulk(logs, config)
+}
+
+// export the logs of several transactions in a single bulk request
+func (exp *ElasticsearchExporter) ExportTransactions(transactionLogs []*otel.TransactionLog, config map[string]string) error {
+	var logs []*otel.OTelLog
+	for _, transactionLog := range transactionLogs {
+		logs = append(logs, transactionLog.Spans...)
+	}
+
+	return exp.bulk(logs, config)
+}
+
+func (exp *ElasticsearchExporter) bulk(logs []*otel.OTelLog, config map[string]string) error {
+	// check if there are no logs to export
+	if len(logs) == 0 {
+		return nil
+	}
+
+	endpoint, err := configValue(config, "elasticsearchEndpoint")
+	if err != nil {
+		return err
+	}
+
+	body, err := bulkBody(logs, config)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(endpoint, "/")+"/_bulk", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	if apiKey := config["elasticsearchAPIKey"]; apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+apiKey)
+	} else if username := config["elasticsearchUsername"]; username != "" {
+		req.SetBasicAuth(username, config["elasticsearchPassword"])
+	}
+
+	respBody, err := exp.send(req, config)
+	if err != nil {
+		return err
+	}
+
+	return bulkResponseError(respBody)
+}
+
+// build the ndjson body with an action line and a document line per log
+func bulkBody(logs []*otel.OTelLog, config map[string]string) ([]byte, error) {
+	indexPattern := config["elasticsearchIndex"]
+	if indexPattern == "" {
+		indexPattern = defaultElasticsearchIndex
+	}
+
+	dateFormat := config["elasticsearchDateFormat"]
+	if dateFormat == "" {
+		dateFormat = defaultElasticsearchDateFormat
+	}
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+
+	for _, log := range logs {
+		document := elasticsearchDocument{
+			Message:     log.Message,
+			Level:       log.Severity,
+			LoggerName:  log.LoggerName,
+			ServiceName: log.ServiceName,
+			TraceID:     log.TraceID,
+			SpanID:      log.SpanID,
+			Attributes:  log.Attributes,
+		}
+
+		// fall back to the export time for the index date if the timestamp can't be parsed
+		date := time.Now()
+		if timestamp, err := parseTimestamp(log.Timestamp); err == nil {
+			date = timestamp
+			document.Timestamp = timestamp.Format(time.RFC3339)
+		}
+
+		index := strings.NewReplacer(
+			"{service}", log.ServiceName,
+			"{logger}", log.LoggerName,
+			"{date}", date.Format(dateFormat),
+		).Replace(indexPattern)
+
+		// index names have to be lowercase
+		err := encoder.Encode(bulkAction{Create: bulkActionMeta{
+			Index: strings.ToLower(index),
+			ID:    log.TraceID + "-" + log.SpanID,
+		}})
+		if err != nil {
+			return nil, err
+		}
+
+		err = encoder.Encode(document)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return body.Bytes(), nil
+}
+
+// collect the failed items of a bulk response
+func bulkResponseError(body []byte) error {
+	var resp bulkResponse
+	err := json.Unmarshal(body, &resp)
+	if err != nil {
+		return fmt.Errorf("invalid bulk response: %w", err)
+	}
+
+	if !resp.Errors {
+		return nil
+	}
+
+	bulkErr := &BulkError{}
+	retryable := false
+
+	for _, item := range resp.Items {
+		for _, result := range item {
+			// already created by an earlier attempt
+			if result.Error == nil || result.Status == http.StatusConflict {
+				continue
+			}
+
+			bulkErr.Items = append(bulkErr.Items, BulkItemError{
+				ID:     result.ID,
+				Index:  result.Index,
+				Status: result.Status,
+				Type:   result.Error.Type,
+				Reason: result.Error.Reason,
+			})
+
+			if result.Status == http.StatusTooManyRequests || result.Status >= 500 {
+				retryable = true
+			}
+		}
+	}
+
+	if len(bulkErr.Items) == 0 {
+		return nil
+	}
+
+	// exporting again is safe since the logs that made it will conflict
+	if retryable {
+		return &utils.RetryableError{Err: bulkErr}
+	}
+
+	return bulkErr
+}
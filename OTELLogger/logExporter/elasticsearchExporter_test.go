@@ -0,0 +1,196 @@
+package logExporter_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"otellogger/logExporter"
+	"otellogger/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testBulkRequest struct {
+	actions   []map[string]map[string]string
+	documents []map[string]any
+	header    http.Header
+}
+
+// start a stand-in for the _bulk endpoint answering with the given response
+func startTestBulk(t *testing.T, response string, request *testBulkRequest) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/_bulk", r.URL.Path)
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+
+		request.header = r.Header
+
+		// action and document lines alternate
+		scanner := bufio.NewScanner(r.Body)
+		for i := 0; scanner.Scan(); i++ {
+			if i%2 == 0 {
+				var action map[string]map[string]string
+				assert.Equal(t, nil, json.Unmarshal(scanner.Bytes(), &action))
+				request.actions = append(request.actions, action)
+			} else {
+				var document map[string]any
+				assert.Equal(t, nil, json.Unmarshal(scanner.Bytes(), &document))
+				request.documents = append(request.documents, document)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestExportLogsElasticsearch(t *testing.T) {
+	t.Run("Export logs to elasticsearch successful", TestExportLogsElasticsearch_Success)
+	t.Run("Export logs to elasticsearch with custom index and API key", TestExportLogsElasticsearch_IndexAPIKey)
+	t.Run("Export batch of transactions to elasticsearch successful", TestExportLogsElasticsearch_Batch)
+	t.Run("Error exporting logs to elasticsearch - failed bulk items", TestExportLogsElasticsearch_ItemErrors)
+	t.Run("Error exporting logs to elasticsearch - no endpoint in config", TestExportLogsElasticsearch_NoEndpoint)
+}
+
+func TestExportLogsElasticsearch_Success(t *testing.T) {
+	request := &testBulkRequest{}
+	server := startTestBulk(t, `{"took":3,"errors":false,"items":[]}`, request)
+
+	exp := logExporter.ElasticsearchExporter{}
+
+	err := exp.ExportLogs("1234567890", nil, nil)
+	assert.Equal(t, nil, err)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{
+		"elasticsearchEndpoint": server.URL,
+		"elasticsearchUsername": "elastic",
+		"elasticsearchPassword": "changeme",
+	})
+	assert.Equal(t, nil, err)
+
+	username, password, ok := (&http.Request{Header: request.header}).BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "elastic", username)
+	assert.Equal(t, "changeme", password)
+
+	assert.Equal(t, []map[string]map[string]string{
+		{"create": {"_index": "logs-default-2025.03.10", "_id": "1234567890-00000000000"}},
+		{"create": {"_index": "logs-default-2025.03.10", "_id": "1234567890-00000000001"}},
+	}, request.actions)
+
+	timestamp, _ := time.ParseInLocation(utils.TimestampFormat, "10.03.2025 17:00:00", time.Local)
+	assert.Equal(t, map[string]any{
+		"@timestamp":   timestamp.Format(time.RFC3339),
+		"message":      "test message 1",
+		"log.level":    "INFO",
+		"log.logger":   utils.LoggerName,
+		"service.name": utils.ServiceName,
+		"trace.id":     "1234567890",
+		"span.id":      "00000000000",
+		"attributes":   map[string]any{"key1": "val1"},
+	}, request.documents[0])
+}
+
+func TestExportLogsElasticsearch_IndexAPIKey(t *testing.T) {
+	request := &testBulkRequest{}
+	server := startTestBulk(t, `{"took":3,"errors":false,"items":[]}`, request)
+
+	exp := logExporter.ElasticsearchExporter{}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{
+		"elasticsearchEndpoint":   server.URL + "/",
+		"elasticsearchIndex":      "{service}-{logger}-{date}",
+		"elasticsearchDateFormat": "2006.01",
+		"elasticsearchAPIKey":     "aWQ6a2V5",
+	})
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, "ApiKey aWQ6a2V5", request.header.Get("Authorization"))
+	assert.Equal(t, "default-otellogger-2025.03", request.actions[0]["create"]["_index"])
+}
+
+func TestExportLogsElasticsearch_Batch(t *testing.T) {
+	request := &testBulkRequest{}
+	server := startTestBulk(t, `{"took":3,"errors":false,"items":[]}`, request)
+
+	exp := &logExporter.ElasticsearchExporter{}
+
+	second := createTestTransaction()
+	second.TraceID = "1234567891"
+	for _, log := range second.Spans {
+		log.TraceID = second.TraceID
+	}
+
+	// the batch processor hands over the whole batch
+	p := &logExporter.BatchProcessor{Exporter: exp, BatchSize: 2, BatchTimeout: time.Minute}
+	p.ExportTransaction(createTestTransaction(), map[string]string{"elasticsearchEndpoint": server.URL})
+	p.ExportTransaction(second, map[string]string{"elasticsearchEndpoint": server.URL})
+
+	err := p.Shutdown(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, uint64(2), p.Stats().Exported)
+
+	// one bulk request with the logs of both transactions
+	assert.Equal(t, []map[string]map[string]string{
+		{"create": {"_index": "logs-default-2025.03.10", "_id": "1234567890-00000000000"}},
+		{"create": {"_index": "logs-default-2025.03.10", "_id": "1234567890-00000000001"}},
+		{"create": {"_index": "logs-default-2025.03.10", "_id": "1234567891-00000000000"}},
+		{"create": {"_index": "logs-default-2025.03.10", "_id": "1234567891-00000000001"}},
+	}, request.actions)
+
+	err = exp.ExportTransactions(nil, nil)
+	assert.Equal(t, nil, err)
+}
+
+func TestExportLogsElasticsearch_ItemErrors(t *testing.T) {
+	config := func(url string) map[string]string {
+		return map[string]string{"elasticsearchEndpoint": url}
+	}
+	exp := logExporter.ElasticsearchExporter{}
+
+	// conflicts from an earlier attempt are ignored
+	server := startTestBulk(t, `{"errors":true,"items":[
+		{"create":{"_index":"logs","_id":"1234567890-00000000000","status":409,"error":{"type":"version_conflict_engine_exception","reason":"exists"}}},
+		{"create":{"_index":"logs","_id":"1234567890-00000000001","status":201}}]}`, &testBulkRequest{})
+	err := exp.ExportLogs("1234567890", createTestLog(), config(server.URL))
+	assert.Equal(t, nil, err)
+
+	// mapping errors can't be fixed by retrying
+	server = startTestBulk(t, `{"errors":true,"items":[
+		{"create":{"_index":"logs","_id":"1234567890-00000000000","status":400,"error":{"type":"mapper_parsing_exception","reason":"bad field"}}},
+		{"create":{"_index":"logs","_id":"1234567890-00000000001","status":201}}]}`, &testBulkRequest{})
+	err = exp.ExportLogs("1234567890", createTestLog(), config(server.URL))
+	assert.EqualError(t, err, "1 bulk items failed, first: 1234567890-00000000000 [400] mapper_parsing_exception: bad field")
+	assert.Equal(t, false, utils.IsRetryable(err))
+
+	var bulkErr *logExporter.BulkError
+	assert.True(t, errors.As(err, &bulkErr))
+	assert.Equal(t, "logs", bulkErr.Items[0].Index)
+
+	// rejected because the cluster is overloaded
+	server = startTestBulk(t, `{"errors":true,"items":[
+		{"create":{"_index":"logs","_id":"1234567890-00000000000","status":429,"error":{"type":"es_rejected_execution_exception","reason":"queue full"}}},
+		{"create":{"_index":"logs","_id":"1234567890-00000000001","status":400,"error":{"type":"mapper_parsing_exception","reason":"bad field"}}}]}`, &testBulkRequest{})
+	err = exp.ExportLogs("1234567890", createTestLog(), config(server.URL))
+	assert.True(t, errors.As(err, &bulkErr))
+	assert.Len(t, bulkErr.Items, 2)
+	assert.Equal(t, true, utils.IsRetryable(err))
+}
+
+func TestExportLogsElasticsearch_NoEndpoint(t *testing.T) {
+	exp := logExporter.ElasticsearchExporter{}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.EqualError(t, err, "no config provided")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{})
+	assert.EqualError(t, err, "no elasticsearchEndpoint in config")
+}
@@ -0,0 +1,262 @@
+package logExporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"otellogger/otel"
+	"sync"
+	"time"
+)
+
+// export to a primary exporter and fall back to other exporters (e.g. a local
+// JSONExporter) while the primary is failing
+//
+// once the primary fails, exports go straight to the fallbacks. every
+// RetryInterval the primary is tried again, by the first export after it or by
+// a probe running in the background until the primary is back or the exporter
+// is shut down, and when that succeeds the exporter switches back to the primary
+type FailoverExporter struct {
+	Primary   LogExporter
+	Fallbacks []LogExporter
+	// how long to wait before retrying a failed primary (default 30s)
+	RetryInterval time.Duration
+	// checks if the primary is back (e.g. dials its collector). without a Probe
+	// the last export that failed is sent to the primary again, so it can end
+	// up in a fallback and the primary
+	Probe func(ctx context.Context) error
+
+	mu          sync.Mutex
+	active      LogExporter // exporter that took the last export
+	primaryDown bool
+	failedAt    time.Time // when the primary failed
+	retriedAt   time.Time // when the primary was last tried
+	lastErr     error     // last error of the primary
+	lastExport  func(child LogExporter) error
+
+	probing   bool
+	stopped   bool
+	probeCtx  context.Context
+	stopProbe context.CancelFunc // called by Shutdown
+	probes    sync.WaitGroup
+}
+
+const defaultFailoverRetryInterval = 30 * time.Second
+
+// state of a failover exporter
+type FailoverStatus struct {
+	// exporter that took the last export (the primary before any export)
+	Active LogExporter
+	// true while exporting to the fallbacks
+	FailedOver bool
+	// when the primary failed and its last error
+	FailedAt  time.Time
+	LastError error
+	// from when the primary will be tried again
+	NextRetry time.Time
+}
+
+// create a failover exporter with the default retry interval
+func NewFailoverExporter(primary LogExporter, fallbacks ...LogExporter) *FailoverExporter {
+	return &FailoverExporter{Primary: primary, Fallbacks: fallbacks}
+}
+
+// export the logs to the first exporter that takes them
+func (exp *FailoverExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	return exp.export(func(child LogExporter) error {
+		return child.ExportLogs(traceID, logs, config)
+	})
+}
+
+// export the transaction to the first exporter that takes it
+func (exp *FailoverExporter) ExportTransaction(transactionLog *otel.TransactionLog, config map[string]string) error {
+	return exp.export(func(child LogExporter) error {
+		return exportTransaction(child, transactionLog, config)
+	})
+}
+
+// flush the primary and the fallbacks
+func (exp *FailoverExporter) Flush(ctx context.Context) error {
+	return flushAll(ctx, append([]LogExporter{exp.Primary}, exp.Fallbacks...)...)
+}
+
+// stop the probe, then shut down the primary and the fallbacks
+func (exp *FailoverExporter) Shutdown(ctx context.Context) error {
+	exp.mu.Lock()
+	exp.stopped = true
+	if exp.stopProbe != nil {
+		exp.stopProbe()
+	}
+	exp.mu.Unlock()
+
+	exp.probes.Wait()
+
+	return shutdownAll(ctx, append([]LogExporter{exp.Primary}, exp.Fallbacks...)...)
+}
+
+// get the active exporter and the state of the primary
+func (exp *FailoverExporter) Status() FailoverStatus {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	status := FailoverStatus{
+		Active:     exp.active,
+		FailedOver: exp.primaryDown,
+		FailedAt:   exp.failedAt,
+		LastError:  exp.lastErr,
+	}
+	if status.Active == nil {
+		status.Active = exp.Primary
+	}
+	if exp.primaryDown {
+		status.NextRetry = exp.retriedAt.Add(exp.retryInterval())
+	}
+
+	return status
+}
+
+func (exp *FailoverExporter) export(export func(child LogExporter) error) error {
+	var errs []error
+
+	if exp.shouldTryPrimary() {
+		err := export(exp.Primary)
+		exp.primaryResult(err, export)
+		if err == nil {
+			return nil
+		}
+
+		errs = append(errs, fmt.Errorf("primary (%T): %w", exp.Primary, err))
+	}
+
+	for i, fallback := range exp.Fallbacks {
+		err := export(fallback)
+		if err == nil {
+			exp.mu.Lock()
+			exp.active = fallback
+			exp.mu.Unlock()
+
+			return nil
+		}
+
+		errs = append(errs, fmt.Errorf("fallback %d (%T): %w", i, fallback, err))
+	}
+
+	// no fallbacks and the primary isn't due for a retry yet
+	if len(errs) == 0 {
+		exp.mu.Lock()
+		defer exp.mu.Unlock()
+
+		return fmt.Errorf("primary (%T) is down: %w", exp.Primary, exp.lastErr)
+	}
+
+	return errors.Join(errs...)
+}
+
+// the primary is tried while it is healthy and once per retry interval after it failed
+func (exp *FailoverExporter) shouldTryPrimary() bool {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	if !exp.primaryDown {
+		return true
+	}
+
+	now := time.Now()
+	if now.Sub(exp.retriedAt) < exp.retryInterval() {
+		return false
+	}
+
+	// only one export retries the primary, the others keep using the fallbacks
+	exp.retriedAt = now
+
+	return true
+}
+
+func (exp *FailoverExporter) primaryResult(err error, export func(child LogExporter) error) {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	if err == nil {
+		exp.active = exp.Primary
+		exp.primaryDown = false
+		exp.lastErr = nil
+		exp.lastExport = nil
+		return
+	}
+
+	if !exp.primaryDown {
+		exp.primaryDown = true
+		exp.failedAt = time.Now()
+	}
+	exp.retriedAt = time.Now()
+	exp.lastErr = err
+	if export != nil {
+		exp.lastExport = export
+	}
+
+	if !exp.probing && !exp.stopped {
+		if exp.probeCtx == nil {
+			exp.probeCtx, exp.stopProbe = context.WithCancel(context.Background())
+		}
+
+		exp.probing = true
+		exp.probes.Add(1)
+		go exp.probe()
+	}
+}
+
+// try the primary every retry interval until it is back or the exporter is shut down
+func (exp *FailoverExporter) probe() {
+	defer exp.probes.Done()
+
+	for {
+		exp.mu.Lock()
+		if !exp.primaryDown || exp.stopped {
+			exp.probing = false
+			exp.mu.Unlock()
+			return
+		}
+
+		// an export may have tried the primary in the meantime
+		wait := time.Until(exp.retriedAt.Add(exp.retryInterval()))
+		if wait <= 0 {
+			exp.retriedAt = time.Now()
+		}
+		export := exp.lastExport
+		exp.mu.Unlock()
+
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-exp.probeCtx.Done():
+				timer.Stop()
+			case <-timer.C:
+			}
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(exp.probeCtx, exp.retryInterval())
+		err := exp.probePrimary(ctx, export)
+		cancel()
+
+		// a probe cut short by Shutdown says nothing about the primary
+		if exp.probeCtx.Err() == nil {
+			exp.primaryResult(err, nil)
+		}
+	}
+}
+
+func (exp *FailoverExporter) probePrimary(ctx context.Context, export func(child LogExporter) error) error {
+	if exp.Probe != nil {
+		return exp.Probe(ctx)
+	}
+
+	return export(exp.Primary)
+}
+
+func (exp *FailoverExporter) retryInterval() time.Duration {
+	if exp.RetryInterval <= 0 {
+		return defaultFailoverRetryInterval
+	}
+	return exp.RetryInterval
+}
@@ -0,0 +1,195 @@
+package logExporter_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"otellogger/logExporter"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportLogsFailover(t *testing.T) {
+	t.Run("Export logs to primary successful", TestExportLogsFailover_Primary)
+	t.Run("Export logs to fallback and back to primary successful", TestExportLogsFailover_SwitchBack)
+	t.Run("Export logs to fallback until the probe finds the primary back successful", TestExportLogsFailover_Probe)
+	t.Run("Export logs to a file while the primary is down successful", TestExportLogsFailover_JSONFallback)
+	t.Run("Error exporting logs with failover - all exporters failed", TestExportLogsFailover_AllFailed)
+}
+
+func TestExportLogsFailover_Primary(t *testing.T) {
+	primary := &testExporter{}
+	fallback := &testExporter{}
+
+	exp := logExporter.NewFailoverExporter(primary, fallback)
+	assert.Equal(t, primary, exp.Status().Active)
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, primary.count())
+	assert.Equal(t, 0, fallback.count())
+
+	status := exp.Status()
+	assert.Equal(t, primary, status.Active)
+	assert.Equal(t, false, status.FailedOver)
+	assert.Equal(t, nil, status.LastError)
+}
+
+func TestExportLogsFailover_SwitchBack(t *testing.T) {
+	errDown := errors.New("collector down")
+	primary := &testExporter{err: errDown}
+	fallback := &testExporter{}
+
+	exp := &logExporter.FailoverExporter{
+		Primary:       primary,
+		Fallbacks:     []logExporter.LogExporter{fallback},
+		RetryInterval: 100 * time.Millisecond,
+	}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, primary.count())
+	assert.Equal(t, 1, fallback.count())
+
+	status := exp.Status()
+	assert.Equal(t, fallback, status.Active)
+	assert.Equal(t, true, status.FailedOver)
+	assert.Equal(t, errDown, status.LastError)
+	assert.WithinDuration(t, status.FailedAt.Add(100*time.Millisecond), status.NextRetry, 10*time.Millisecond)
+
+	// the primary isn't tried again until the retry is due
+	err = exp.ExportLogs("1234567891", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, primary.count())
+	assert.Equal(t, 2, fallback.count())
+
+	// the probe retries the last export in the background, a failed retry keeps using the fallback
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, 2, primary.count())
+	assert.Equal(t, true, exp.Status().FailedOver)
+
+	err = exp.ExportLogs("1234567892", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, primary.count())
+	assert.Equal(t, 3, fallback.count())
+
+	// once the primary is healthy the next probe switches back without any export
+	primary.setErr(nil)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 3, primary.count())
+
+	status = exp.Status()
+	assert.Equal(t, primary, status.Active)
+	assert.Equal(t, false, status.FailedOver)
+	assert.Equal(t, nil, status.LastError)
+
+	err = exp.ExportLogs("1234567893", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 4, primary.count())
+	assert.Equal(t, 3, fallback.count())
+
+	err = exp.Shutdown(context.Background())
+	assert.Equal(t, nil, err)
+}
+
+func TestExportLogsFailover_Probe(t *testing.T) {
+	errDown := errors.New("collector down")
+	primary := &testExporter{err: errDown}
+
+	var mu sync.Mutex
+	probes := 0
+	var probeErr error = errDown
+
+	exp := &logExporter.FailoverExporter{
+		Primary:       primary,
+		Fallbacks:     []logExporter.LogExporter{&testExporter{}},
+		RetryInterval: 20 * time.Millisecond,
+		Probe: func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			probes++
+			return probeErr
+		},
+	}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+
+	// the probe checks the primary without exporting to it
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	assert.GreaterOrEqual(t, probes, 2)
+	mu.Unlock()
+	assert.Equal(t, 1, primary.count())
+	assert.Equal(t, true, exp.Status().FailedOver)
+
+	mu.Lock()
+	probeErr = nil
+	mu.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, false, exp.Status().FailedOver)
+	assert.Equal(t, primary, exp.Status().Active)
+
+	// shutting down stops the probe
+	primary.setErr(errDown)
+	mu.Lock()
+	probeErr = errDown
+	mu.Unlock()
+
+	err = exp.ExportLogs("1234567891", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+
+	err = exp.Shutdown(context.Background())
+	assert.Equal(t, nil, err)
+
+	mu.Lock()
+	stoppedAt := probes
+	mu.Unlock()
+
+	time.Sleep(60 * time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, stoppedAt, probes)
+	mu.Unlock()
+}
+
+func TestExportLogsFailover_JSONFallback(t *testing.T) {
+	dir := t.TempDir() + "/"
+	primary := &testTransactionExporter{testExporter: testExporter{err: errors.New("collector down")}}
+
+	exp := logExporter.NewFailoverExporter(primary, &logExporter.JSONExporter{})
+
+	err := exp.ExportTransaction(createTestTransaction(), map[string]string{"filepath": dir, "filename": "fallback"})
+	assert.Equal(t, nil, err)
+	assert.Len(t, primary.transactions, 1)
+
+	_, err = os.Stat(dir + "fallback_1234567890.json")
+	assert.Equal(t, nil, err)
+}
+
+func TestExportLogsFailover_AllFailed(t *testing.T) {
+	errDown := errors.New("collector down")
+	errDisk := errors.New("disk full")
+
+	exp := &logExporter.FailoverExporter{
+		Primary:       &testExporter{err: errDown},
+		Fallbacks:     []logExporter.LogExporter{&testExporter{err: errDisk}},
+		RetryInterval: time.Minute,
+	}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.ErrorIs(t, err, errDown)
+	assert.ErrorIs(t, err, errDisk)
+	assert.EqualError(t, err, "primary (*logExporter_test.testExporter): collector down\n"+
+		"fallback 0 (*logExporter_test.testExporter): disk full")
+
+	// without fallbacks the error of the primary is kept until the next retry
+	exp.Fallbacks = nil
+	err = exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.ErrorIs(t, err, errDown)
+	assert.EqualError(t, err, "primary (*logExporter_test.testExporter) is down: collector down")
+}
@@ -0,0 +1,154 @@
+package logExporter
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"otellogger/otel"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// output paths of the file exporters (JSONExporter, TXTExporter)
+//
+// config keys:
+//   - filepath: base directory, files are never written outside of it
+//   - pathTemplate: path of the file inside the base directory, e.g.
+//     `{{.Service}}/{{.Date "2006/01/02"}}/{{.TraceID}}.json`
+//     (default `{{.Filename}}_{{.TraceID}}.json`, .txt or `{{.Filename}}.ndjson`)
+//   - dirPerm: permissions of the created directories, e.g. "0750" (default 0755)
+//   - filePerm: permissions of the created files, e.g. "0640" (default 0644)
+
+// values available in a path template. all of them except the Date layout
+// are sanitised so they stay a single, non-empty path element
+type pathData struct {
+	TraceID  string
+	Service  string
+	Logger   string
+	Filename string
+
+	now time.Time
+}
+
+// format the time of the export, e.g. {{.Date "2006/01/02"}}
+func (data pathData) Date(layout string) string {
+	return data.now.Format(layout)
+}
+
+const (
+	defaultDirPerm  = 0755
+	defaultFilePerm = 0644
+)
+
+var errPathEscapes = errors.New("path escapes the base directory")
+
+// parsed path templates by their text
+var pathTemplates sync.Map
+
+// render the path of the file for a transaction and create its directory
+func outputPath(config map[string]string, defaultTemplate string, transactionLog *otel.TransactionLog) (string, os.FileMode, error) {
+	text := config["pathTemplate"]
+	if text == "" {
+		text = defaultTemplate
+	}
+
+	tmpl, err := pathTemplate(text)
+	if err != nil {
+		return "", 0, err
+	}
+
+	dirPerm, err := configPerm(config, "dirPerm", defaultDirPerm)
+	if err != nil {
+		return "", 0, err
+	}
+
+	filePerm, err := configPerm(config, "filePerm", defaultFilePerm)
+	if err != nil {
+		return "", 0, err
+	}
+
+	data := pathData{
+		TraceID:  sanitizePathElement(transactionLog.TraceID),
+		Service:  sanitizePathElement(transactionLog.ServiceName),
+		Logger:   sanitizePathElement(transactionLog.LoggerName),
+		Filename: sanitizePathElement(config["filename"]),
+		now:      time.Now(),
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		return "", 0, configError("pathTemplate", "invalid pathTemplate in config: %w", err)
+	}
+
+	// the template itself may still point elsewhere (e.g. "../{{.TraceID}}")
+	rel := filepath.Clean(filepath.FromSlash(buf.String()))
+	if !filepath.IsLocal(rel) {
+		return "", 0, configError("pathTemplate", "invalid pathTemplate in config: %w", errPathEscapes)
+	}
+
+	path := filepath.Join(config["filepath"], rel)
+
+	err = os.MkdirAll(filepath.Dir(path), dirPerm)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return path, filePerm, nil
+}
+
+func pathTemplate(text string) (*template.Template, error) {
+	if tmpl, ok := pathTemplates.Load(text); ok {
+		return tmpl.(*template.Template), nil
+	}
+
+	tmpl, err := template.New("path").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, configError("pathTemplate", "invalid pathTemplate in config: %w", err)
+	}
+	pathTemplates.Store(text, tmpl)
+
+	return tmpl, nil
+}
+
+// keep a value from adding directories or leaving the base directory. an
+// empty value becomes "_" so e.g. {{.Service}}/x doesn't render as /x
+func sanitizePathElement(value string) string {
+	if value == "" {
+		return "_"
+	}
+
+	sanitized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, value)
+
+	if strings.Trim(sanitized, ".") == "" {
+		return strings.Repeat("_", len(sanitized))
+	}
+
+	return sanitized
+}
+
+// get file permissions in octal (e.g. "0750") from config
+func configPerm(config map[string]string, key string, defaultValue os.FileMode) (os.FileMode, error) {
+	value, ok := config[key]
+	if !ok || value == "" {
+		return defaultValue, nil
+	}
+
+	perm, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || perm > 0777 {
+		return 0, configError(key, "invalid %s in config: %q", key, value)
+	}
+
+	return os.FileMode(perm), nil
+}
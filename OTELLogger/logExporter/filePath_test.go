@@ -0,0 +1,120 @@
+package logExporter_test
+
+import (
+	"os"
+	"otellogger/logExporter"
+	"otellogger/otel"
+	"otellogger/utils"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportLogsFilePath(t *testing.T) {
+	t.Run("Export logs to templated path successful", TestExportLogsFilePath_Template)
+	t.Run("Export logs without trailing slash successful", TestExportLogsFilePath_NoTrailingSlash)
+	t.Run("Export logs with crafted trace ID - stays in base directory", TestExportLogsFilePath_Sanitized)
+	t.Run("Export transaction without service name successful", TestExportLogsFilePath_Empty)
+	t.Run("Error exporting logs to templated path - invalid config", TestExportLogsFilePath_ErrorConfig)
+}
+
+func TestExportLogsFilePath_Template(t *testing.T) {
+	dir := t.TempDir()
+	jsonLogExporter := logExporter.JSONExporter{}
+
+	config := map[string]string{
+		"filepath":     dir,
+		"pathTemplate": `{{.Service}}/{{.Date "2006/01/02"}}/{{.TraceID}}.json`,
+		"dirPerm":      "0750",
+		"filePerm":     "0600",
+	}
+
+	err := jsonLogExporter.ExportLogs("1234567890", createTestLog(), config)
+	assert.Equal(t, nil, err)
+
+	// the directories are created
+	path := filepath.Join(dir, utils.ServiceName, time.Now().Format("2006/01/02"), "1234567890.json")
+	info, err := os.Stat(path)
+	if !assert.Equal(t, nil, err) {
+		return
+	}
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	info, err = os.Stat(filepath.Dir(path))
+	assert.Equal(t, nil, err)
+	// the umask may take away more, but never adds any
+	assert.Equal(t, os.FileMode(0), info.Mode().Perm()&^0750)
+
+	// same for text files
+	txtLogExporter := logExporter.TXTExporter{}
+	config["pathTemplate"] = "{{.Logger}}/{{.TraceID}}.txt"
+
+	err = txtLogExporter.ExportLogs("1234567890", createTestLog(), config)
+	assert.Equal(t, nil, err)
+	assert.FileExists(t, filepath.Join(dir, utils.LoggerName, "1234567890.txt"))
+}
+
+func TestExportLogsFilePath_NoTrailingSlash(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	jsonLogExporter := logExporter.JSONExporter{}
+
+	// the base directory is joined, not concatenated, and created if missing
+	err := jsonLogExporter.ExportLogs("1234567890", createTestLog(), map[string]string{"filepath": dir, "filename": "test"})
+	assert.Equal(t, nil, err)
+	assert.FileExists(t, filepath.Join(dir, "test_1234567890.json"))
+}
+
+func TestExportLogsFilePath_Sanitized(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	jsonLogExporter := logExporter.JSONExporter{}
+	config := map[string]string{"filepath": dir, "pathTemplate": "{{.TraceID}}/logs.json"}
+
+	err := jsonLogExporter.ExportLogs("../../escaped", createTestLog(), config)
+	assert.Equal(t, nil, err)
+	assert.FileExists(t, filepath.Join(dir, ".._.._escaped", "logs.json"))
+
+	err = jsonLogExporter.ExportLogs("..", createTestLog(), config)
+	assert.Equal(t, nil, err)
+	assert.FileExists(t, filepath.Join(dir, "__", "logs.json"))
+
+	// nothing was written next to the base directory
+	entries, err := os.ReadDir(filepath.Dir(dir))
+	assert.Equal(t, nil, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestExportLogsFilePath_Empty(t *testing.T) {
+	dir := t.TempDir()
+	jsonLogExporter := logExporter.JSONExporter{}
+	config := map[string]string{"filepath": dir, "pathTemplate": "{{.Service}}/{{.TraceID}}.json"}
+
+	transactionLog := &otel.TransactionLog{TraceID: "1234567890", Spans: createTestLog()}
+
+	// an empty value still is a directory of its own
+	err := jsonLogExporter.ExportTransaction(transactionLog, config)
+	assert.Equal(t, nil, err)
+	assert.FileExists(t, filepath.Join(dir, "_", "1234567890.json"))
+}
+
+func TestExportLogsFilePath_ErrorConfig(t *testing.T) {
+	dir := t.TempDir()
+	jsonLogExporter := logExporter.JSONExporter{}
+
+	err := jsonLogExporter.ExportLogs("1234567890", createTestLog(), map[string]string{"filepath": dir, "pathTemplate": "../{{.TraceID}}.json"})
+	assert.EqualError(t, err, "invalid pathTemplate in config: path escapes the base directory")
+	assert.ErrorIs(t, err, utils.ErrInvalidConfig)
+
+	err = jsonLogExporter.ExportLogs("1234567890", createTestLog(), map[string]string{"filepath": dir, "pathTemplate": "/etc/{{.TraceID}}.json"})
+	assert.ErrorIs(t, err, utils.ErrInvalidConfig)
+
+	err = jsonLogExporter.ExportLogs("1234567890", createTestLog(), map[string]string{"filepath": dir, "pathTemplate": "{{.TraceID"})
+	assert.ErrorIs(t, err, utils.ErrInvalidConfig)
+
+	err = jsonLogExporter.ExportLogs("1234567890", createTestLog(), map[string]string{"filepath": dir, "pathTemplate": "{{.Missing}}.json"})
+	assert.ErrorIs(t, err, utils.ErrInvalidConfig)
+
+	err = jsonLogExporter.ExportLogs("1234567890", createTestLog(), map[string]string{"filepath": dir, "filename": "test", "dirPerm": "rwx"})
+	assert.EqualError(t, err, `invalid dirPerm in config: "rwx"`)
+}
@@ -0,0 +1,257 @@
+package logExporter
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"otellogger/otel"
+	"sync"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// export logs to Fluentd or Fluent Bit using the forward protocol
+//
+// logs are sent in PackedForward mode, one message per tag, tagged as
+// <prefix>.<service name>.<logger name>. delivery is at-least-once: a chunk
+// that wasn't acknowledged fails the export and the server doesn't
+// deduplicate chunks, so exporting it again may duplicate its records
+//
+// config keys:
+//   - fluentNetwork: "tcp" (default) or "unix"
+//   - fluentAddress: host:port or socket path (required)
+//   - fluentTagPrefix: first part of the tag (default "otellogger")
+//   - fluentCompression: "gzip" or "none" (default)
+//   - fluentAck: "true" to wait for the server to acknowledge each chunk
+//   - fluentTimeout: timeout for connecting, writing and waiting for acks (default 10s)
+type FluentExporter struct {
+	mu   sync.Mutex
+	conn streamConn[*fluentConn]
+}
+
+// connection to the server, the decoder reading the acks is kept with it
+// since it may buffer more than one ack
+type fluentConn struct {
+	net.Conn
+	decoder *msgpack.Decoder
+}
+
+const (
+	defaultFluentTagPrefix = "otellogger"
+	defaultFluentTimeout   = 10 * time.Second
+	// msgpack ext type of the forward protocol EventTime
+	fluentEventTimeExt = 0
+)
+
+type fluentRecord struct {
+	Message    string            `msgpack:"message"`
+	Severity   string            `msgpack:"severity"`
+	LoggerName string            `msgpack:"logger"`
+	Service    string            `msgpack:"service"`
+	TraceID    string            `msgpack:"trace_id"`
+	SpanID     string            `msgpack:"span_id"`
+	Attributes map[string]string `msgpack:"attributes,omitempty"`
+}
+
+// export logs from a transaction, one forward message per tag
+func (exp *FluentExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	// check if there are no logs to export
+	if len(logs) == 0 {
+		return nil
+	}
+
+	address, err := configValue(config, "fluentAddress")
+	if err != nil {
+		return err
+	}
+
+	network := config["fluentNetwork"]
+	if network == "" {
+		network = "tcp"
+	}
+	if network != "tcp" && network != "unix" {
+		return configError("fluentNetwork", "unsupported fluentNetwork %q", network)
+	}
+
+	compression := config["fluentCompression"]
+	if compression != "" && compression != "none" && compression != "gzip" {
+		return configError("fluentCompression", "unsupported fluentCompression %q", compression)
+	}
+
+	ack, err := configBool(config, "fluentAck")
+	if err != nil {
+		return err
+	}
+
+	timeout, err := configDuration(config, "fluentTimeout", defaultFluentTimeout)
+	if err != nil {
+		return err
+	}
+
+	prefix := config["fluentTagPrefix"]
+	if prefix == "" {
+		prefix = defaultFluentTagPrefix
+	}
+
+	// group the logs by tag keeping their order
+	var tags []string
+	logsByTag := make(map[string][]*otel.OTelLog)
+	for _, log := range logs {
+		tag := prefix + "." + log.ServiceName + "." + log.LoggerName
+		if _, ok := logsByTag[tag]; !ok {
+			tags = append(tags, tag)
+		}
+		logsByTag[tag] = append(logsByTag[tag], log)
+	}
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	for _, tag := range tags {
+		message, chunk, err := fluentMessage(tag, logsByTag[tag], compression == "gzip", ack)
+		if err != nil {
+			return err
+		}
+
+		err = exp.conn.write(network, address, func() (*fluentConn, error) {
+			conn, err := net.DialTimeout(network, address, timeout)
+			if err != nil {
+				return nil, err
+			}
+			return &fluentConn{Conn: conn, decoder: msgpack.NewDecoder(conn)}, nil
+		}, func(conn *fluentConn) (int, error) {
+			return fluentSend(conn, message, chunk, timeout)
+		})
+		if err != nil {
+			return streamError(err)
+		}
+	}
+
+	return nil
+}
+
+// close the connection to the server
+func (exp *FluentExporter) Close() error {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	return exp.conn.Close()
+}
+
+// write a message and wait for its ack, returns how much of it was written
+func fluentSend(conn *fluentConn, message []byte, chunk string, timeout time.Duration) (int, error) {
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	written, err := conn.Write(message)
+	if err != nil || chunk == "" {
+		return written, err
+	}
+
+	// the server answers with {"ack": chunk}
+	var resp struct {
+		Ack string `msgpack:"ack"`
+	}
+	err = conn.decoder.Decode(&resp)
+	if err != nil {
+		return written, fmt.Errorf("reading ack: %w", err)
+	}
+
+	if resp.Ack != chunk {
+		return written, fmt.Errorf("unexpected ack %q for chunk %q", resp.Ack, chunk)
+	}
+
+	return written, nil
+}
+
+// encode [tag, entries, option] where entries are the concatenated [EventTime, record] pairs
+func fluentMessage(tag string, logs []*otel.OTelLog, compress, ack bool) ([]byte, string, error) {
+	var entries bytes.Buffer
+	encoder := msgpack.NewEncoder(&entries)
+
+	for _, log := range logs {
+		err := encoder.EncodeArrayLen(2)
+		if err != nil {
+			return nil, "", err
+		}
+
+		err = encodeEventTime(encoder, &entries, log)
+		if err != nil {
+			return nil, "", err
+		}
+
+		err = encoder.Encode(fluentRecord{
+			Message:    log.Message,
+			Severity:   log.Severity,
+			LoggerName: log.LoggerName,
+			Service:    log.ServiceName,
+			TraceID:    log.TraceID,
+			SpanID:     log.SpanID,
+			Attributes: log.Attributes,
+		})
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	option := map[string]any{"size": len(logs)}
+
+	entriesData := entries.Bytes()
+	if compress {
+		compressed, err := gzipBytes(entriesData)
+		if err != nil {
+			return nil, "", err
+		}
+		entriesData = compressed
+		option["compressed"] = "gzip"
+	}
+
+	chunk := ""
+	if ack {
+		id := make([]byte, 16)
+		rand.Read(id)
+		chunk = base64.StdEncoding.EncodeToString(id)
+		option["chunk"] = chunk
+	}
+
+	var message bytes.Buffer
+	encoder = msgpack.NewEncoder(&message)
+	encoder.SetSortMapKeys(true)
+
+	err := encoder.EncodeArrayLen(3)
+	if err == nil {
+		err = encoder.EncodeString(tag)
+	}
+	if err == nil {
+		err = encoder.EncodeBytes(entriesData)
+	}
+	if err == nil {
+		err = encoder.Encode(option)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return message.Bytes(), chunk, nil
+}
+
+// EventTime is an ext type holding the seconds and nanoseconds as big endian uint32s
+func encodeEventTime(encoder *msgpack.Encoder, buf *bytes.Buffer, log *otel.OTelLog) error {
+	timestamp, err := parseTimestamp(log.Timestamp)
+	if err != nil {
+		timestamp = time.Now()
+	}
+
+	err = encoder.EncodeExtHeader(fluentEventTimeExt, 8)
+	if err != nil {
+		return err
+	}
+
+	binary.Write(buf, binary.BigEndian, uint32(timestamp.Unix()))
+	binary.Write(buf, binary.BigEndian, uint32(timestamp.Nanosecond()))
+
+	return nil
+}
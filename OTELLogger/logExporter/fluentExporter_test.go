@@ -0,0 +1,268 @@
+package logExporter_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"net"
+	"otellogger/logExporter"
+	"otellogger/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type testFluentEntry struct {
+	time   time.Time
+	record map[string]any
+}
+
+type testFluentMessage struct {
+	tag     string
+	entries []testFluentEntry
+	option  map[string]any
+}
+
+// stand-in for a forward input, dropping the first connections without acking
+func startTestFluent(t *testing.T, network, address string, dropConnections int) (net.Listener, chan testFluentMessage) {
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan testFluentMessage, 10)
+
+	go func() {
+		for connections := 0; ; connections++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn, drop bool) {
+				defer conn.Close()
+
+				decoder := msgpack.NewDecoder(conn)
+				for {
+					message, err := decodeFluentMessage(decoder)
+					if err != nil {
+						return
+					}
+					if drop {
+						return
+					}
+
+					messages <- message
+
+					if chunk, ok := message.option["chunk"]; ok {
+						ack, _ := msgpack.Marshal(map[string]any{"ack": chunk})
+						conn.Write(ack)
+					}
+				}
+			}(conn, connections < dropConnections)
+		}
+	}()
+
+	return listener, messages
+}
+
+func decodeFluentMessage(decoder *msgpack.Decoder) (testFluentMessage, error) {
+	var message testFluentMessage
+
+	_, err := decoder.DecodeArrayLen()
+	if err != nil {
+		return message, err
+	}
+
+	message.tag, err = decoder.DecodeString()
+	if err != nil {
+		return message, err
+	}
+
+	entries, err := decoder.DecodeBytes()
+	if err != nil {
+		return message, err
+	}
+
+	message.option, err = decoder.DecodeMap()
+	if err != nil {
+		return message, err
+	}
+
+	if message.option["compressed"] == "gzip" {
+		reader, err := gzip.NewReader(bytes.NewReader(entries))
+		if err != nil {
+			return message, err
+		}
+		entries, _ = io.ReadAll(reader)
+	}
+
+	entryDecoder := msgpack.NewDecoder(bytes.NewReader(entries))
+	for {
+		_, err := entryDecoder.DecodeArrayLen()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return message, err
+		}
+
+		_, _, err = entryDecoder.DecodeExtHeader()
+		if err != nil {
+			return message, err
+		}
+
+		eventTime := make([]byte, 8)
+		err = entryDecoder.ReadFull(eventTime)
+		if err != nil {
+			return message, err
+		}
+
+		record, err := entryDecoder.DecodeMap()
+		if err != nil {
+			return message, err
+		}
+
+		message.entries = append(message.entries, testFluentEntry{
+			time:   time.Unix(int64(binary.BigEndian.Uint32(eventTime[:4])), int64(binary.BigEndian.Uint32(eventTime[4:]))),
+			record: record,
+		})
+	}
+
+	return message, nil
+}
+
+func receiveFluentMessage(t *testing.T, messages chan testFluentMessage) testFluentMessage {
+	select {
+	case message := <-messages:
+		return message
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message received")
+		return testFluentMessage{}
+	}
+}
+
+func TestExportLogsFluent(t *testing.T) {
+	t.Run("Export logs to fluent over TCP successful", TestExportLogsFluent_TCP)
+	t.Run("Export logs to fluent with gzip and acks over unix socket", TestExportLogsFluent_GzipAckUnix)
+	t.Run("Export logs to fluent reconnecting after a lost connection", TestExportLogsFluent_Reconnect)
+	t.Run("Error exporting logs to fluent - invalid config", TestExportLogsFluent_InvalidConfig)
+}
+
+func TestExportLogsFluent_TCP(t *testing.T) {
+	listener, messages := startTestFluent(t, "tcp", "127.0.0.1:0", 0)
+
+	logs := createTestLog()
+	logs[1].LoggerName = "OtherLogger"
+
+	exp := &logExporter.FluentExporter{}
+	defer exp.Close()
+
+	err := exp.ExportLogs("1234567890", nil, nil)
+	assert.Equal(t, nil, err)
+
+	err = exp.ExportLogs("1234567890", logs, map[string]string{"fluentAddress": listener.Addr().String()})
+	assert.Equal(t, nil, err)
+
+	// one message per service and logger name
+	message := receiveFluentMessage(t, messages)
+	assert.Equal(t, "otellogger.Default.OTelLogger", message.tag)
+	assert.Equal(t, map[string]any{"size": int8(1)}, message.option)
+
+	timestamp, _ := time.ParseInLocation(utils.TimestampFormat, "10.03.2025 17:00:00", time.Local)
+	if assert.Len(t, message.entries, 1) {
+		assert.True(t, timestamp.Equal(message.entries[0].time))
+		assert.Equal(t, map[string]any{
+			"message":    "test message 1",
+			"severity":   "INFO",
+			"logger":     utils.LoggerName,
+			"service":    utils.ServiceName,
+			"trace_id":   "1234567890",
+			"span_id":    "00000000000",
+			"attributes": map[string]any{"key1": "val1"},
+		}, message.entries[0].record)
+	}
+
+	message = receiveFluentMessage(t, messages)
+	assert.Equal(t, "otellogger.Default.OtherLogger", message.tag)
+	assert.Len(t, message.entries, 1)
+}
+
+func TestExportLogsFluent_GzipAckUnix(t *testing.T) {
+	path := t.TempDir() + "/fluent.sock"
+	_, messages := startTestFluent(t, "unix", path, 0)
+
+	exp := &logExporter.FluentExporter{}
+	defer exp.Close()
+
+	config := map[string]string{
+		"fluentNetwork":     "unix",
+		"fluentAddress":     path,
+		"fluentTagPrefix":   "app",
+		"fluentCompression": "gzip",
+		"fluentAck":         "true",
+	}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), config)
+	assert.Equal(t, nil, err)
+
+	message := receiveFluentMessage(t, messages)
+	assert.Equal(t, "app.Default.OTelLogger", message.tag)
+	assert.Equal(t, "gzip", message.option["compressed"])
+	assert.NotEmpty(t, message.option["chunk"])
+	if assert.Len(t, message.entries, 2) {
+		assert.Equal(t, "test message 2", message.entries[1].record["message"])
+	}
+
+	// the next chunk is acked over the same connection
+	err = exp.ExportLogs("1234567890", createTestLog(), config)
+	assert.Equal(t, nil, err)
+	assert.NotEqual(t, message.option["chunk"], receiveFluentMessage(t, messages).option["chunk"])
+}
+
+func TestExportLogsFluent_Reconnect(t *testing.T) {
+	listener, messages := startTestFluent(t, "tcp", "127.0.0.1:0", 1)
+
+	exp := &logExporter.FluentExporter{}
+	defer exp.Close()
+
+	config := map[string]string{
+		"fluentAddress": listener.Addr().String(),
+		"fluentAck":     "true",
+		"fluentTimeout": "1s",
+	}
+
+	// the first connection is dropped before the ack, the chunk may have
+	// made it so it isn't sent again on its own
+	err := exp.ExportLogs("1234567890", createTestLog(), config)
+	assert.ErrorContains(t, err, "reading ack")
+	assert.Equal(t, true, utils.IsRetryable(err))
+
+	// exporting again goes over a new connection
+	err = exp.ExportLogs("1234567890", createTestLog(), config)
+	assert.Equal(t, nil, err)
+
+	message := receiveFluentMessage(t, messages)
+	assert.Len(t, message.entries, 2)
+}
+
+func TestExportLogsFluent_InvalidConfig(t *testing.T) {
+	exp := &logExporter.FluentExporter{}
+	defer exp.Close()
+
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{})
+	assert.EqualError(t, err, "no fluentAddress in config")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"fluentAddress": "127.0.0.1:24224", "fluentNetwork": "udp"})
+	assert.EqualError(t, err, `unsupported fluentNetwork "udp"`)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"fluentAddress": "127.0.0.1:24224", "fluentCompression": "zstd"})
+	assert.EqualError(t, err, `unsupported fluentCompression "zstd"`)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"fluentNetwork": "unix", "fluentAddress": t.TempDir() + "/missing.sock"})
+	assert.Equal(t, true, utils.IsRetryable(err))
+}
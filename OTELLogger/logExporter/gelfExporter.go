@@ -0,0 +1,240 @@
+package logExporter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"otellogger/otel"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// export logs to Graylog as GELF 1.1 messages
+//
+// config keys:
+//   - gelfNetwork: "udp" (default) or "tcp"
+//   - gelfAddress: host:port of the GELF input (required)
+//   - gelfCompression: "gzip", "zlib" or "none" (default), udp only
+//   - gelfChunkSize: maximum datagram size before chunking (default 1420)
+//   - gelfHost: host field (default the machine hostname)
+//   - gelfTimeout: timeout for connecting and writing, e.g. "5s" (default 10s)
+type GELFExporter struct {
+	mu   sync.Mutex
+	conn streamConn[net.Conn]
+}
+
+const (
+	defaultGELFChunkSize = 1420
+	defaultGELFTimeout   = 10 * time.Second
+	gelfChunkHeaderSize  = 12
+	gelfMaxChunks        = 128
+)
+
+// additional field names allowed by the spec
+var gelfFieldName = regexp.MustCompile(`^[\w.\-]+$`)
+
+// export each log from a transaction as a GELF message
+func (exp *GELFExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	// check if there are no logs to export
+	if len(logs) == 0 {
+		return nil
+	}
+
+	address, err := configValue(config, "gelfAddress")
+	if err != nil {
+		return err
+	}
+
+	network := config["gelfNetwork"]
+	if network == "" {
+		network = "udp"
+	}
+	if network != "udp" && network != "tcp" {
+		return configError("gelfNetwork", "unsupported gelfNetwork %q", network)
+	}
+
+	compression := config["gelfCompression"]
+	switch compression {
+	case "", "none":
+	case "gzip", "zlib":
+		if network == "tcp" {
+			return configError("gelfCompression", "gelfCompression is not supported over tcp")
+		}
+	default:
+		return configError("gelfCompression", "unsupported gelfCompression %q", compression)
+	}
+
+	chunkSize, err := configInt(config, "gelfChunkSize", defaultGELFChunkSize)
+	if err != nil {
+		return err
+	}
+	if chunkSize <= gelfChunkHeaderSize {
+		return configError("gelfChunkSize", "gelfChunkSize must be bigger than %d", gelfChunkHeaderSize)
+	}
+
+	timeout, err := configDuration(config, "gelfTimeout", defaultGELFTimeout)
+	if err != nil {
+		return err
+	}
+
+	host := config["gelfHost"]
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+
+	dial := func() (net.Conn, error) {
+		return net.DialTimeout(network, address, timeout)
+	}
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	for _, log := range logs {
+		payload, err := json.Marshal(gelfMessage(log, host))
+		if err != nil {
+			return err
+		}
+
+		if network == "tcp" {
+			// messages are delimited by a null byte
+			err = exp.write([][]byte{append(payload, 0)}, network, address, dial, timeout)
+		} else {
+			var datagrams [][]byte
+			payload, err = gelfCompress(payload, compression)
+			if err == nil {
+				datagrams, err = gelfChunks(payload, chunkSize)
+			}
+			if err == nil {
+				err = exp.write(datagrams, network, address, dial, timeout)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// close the connection to the GELF input
+func (exp *GELFExporter) Close() error {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	return exp.conn.Close()
+}
+
+// write the datagrams (or the tcp frame)
+func (exp *GELFExporter) write(packets [][]byte, network, address string, dial func() (net.Conn, error), timeout time.Duration) error {
+	err := exp.conn.write(network, address, dial, func(conn net.Conn) (int, error) {
+		conn.SetWriteDeadline(time.Now().Add(timeout))
+
+		written := 0
+		for _, packet := range packets {
+			n, err := conn.Write(packet)
+			written += n
+			if err != nil {
+				return written, err
+			}
+		}
+		return written, nil
+	})
+
+	return streamError(err)
+}
+
+// map a log to the GELF fields, everything that isn't part of the spec
+// goes into additional fields prefixed with '_'
+func gelfMessage(log *otel.OTelLog, host string) map[string]any {
+	message := map[string]any{
+		"version":       "1.1",
+		"host":          host,
+		"short_message": log.Message,
+		"level":         syslogSeverity(log.Severity),
+		"_severity":     log.Severity,
+		"_logger":       log.LoggerName,
+		"_service":      log.ServiceName,
+		"_trace_id":     log.TraceID,
+		"_span_id":      log.SpanID,
+	}
+
+	timestamp, err := parseTimestamp(log.Timestamp)
+	if err != nil {
+		timestamp = time.Now()
+	}
+	message["timestamp"] = float64(timestamp.UnixMilli()) / 1000
+
+	for key, value := range log.Attributes {
+		// _id is reserved and invalid names would make graylog drop the message
+		if key == "id" || !gelfFieldName.MatchString(key) {
+			continue
+		}
+		if _, ok := message["_"+key]; ok {
+			continue
+		}
+		message["_"+key] = value
+	}
+
+	return message
+}
+
+func gelfCompress(payload []byte, compression string) ([]byte, error) {
+	switch compression {
+	case "gzip":
+		return gzipBytes(payload)
+	case "zlib":
+		var buf bytes.Buffer
+		writer := zlib.NewWriter(&buf)
+
+		_, err := writer.Write(payload)
+		if err != nil {
+			return nil, err
+		}
+
+		err = writer.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	default:
+		return payload, nil
+	}
+}
+
+// split a payload bigger than the chunk size into chunks of
+// magic bytes (0x1e 0x0f), message ID (8 bytes), sequence number, sequence count and data
+func gelfChunks(payload []byte, chunkSize int) ([][]byte, error) {
+	if len(payload) <= chunkSize {
+		return [][]byte{payload}, nil
+	}
+
+	dataSize := chunkSize - gelfChunkHeaderSize
+	count := (len(payload) + dataSize - 1) / dataSize
+	if count > gelfMaxChunks {
+		return nil, fmt.Errorf("GELF message of %d bytes needs %d chunks, the maximum is %d", len(payload), count, gelfMaxChunks)
+	}
+
+	messageID := make([]byte, 8)
+	rand.Read(messageID)
+
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		data := payload[i*dataSize : min((i+1)*dataSize, len(payload))]
+
+		chunk := make([]byte, 0, gelfChunkHeaderSize+len(data))
+		chunk = append(chunk, 0x1e, 0x0f)
+		chunk = append(chunk, messageID...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, data...)
+
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks, nil
+}
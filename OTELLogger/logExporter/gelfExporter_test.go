@@ -0,0 +1,227 @@
+package logExporter_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"net"
+	"otellogger/logExporter"
+	"otellogger/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// read GELF datagrams, reassembling chunked messages and decompressing them
+func readGELFMessages(t *testing.T, conn net.PacketConn, count int) ([]map[string]any, int) {
+	var messages []map[string]any
+	chunks := make(map[string][][]byte)
+	datagrams := 0
+
+	buf := make([]byte, 64*1024)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	for len(messages) < count {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("Error reading datagram: %v", err)
+		}
+		datagrams++
+		payload := append([]byte{}, buf[:n]...)
+
+		if payload[0] == 0x1e && payload[1] == 0x0f {
+			id, seq, total := string(payload[2:10]), int(payload[10]), int(payload[11])
+			if chunks[id] == nil {
+				chunks[id] = make([][]byte, total)
+			}
+			chunks[id][seq] = payload[12:]
+
+			complete := true
+			for _, chunk := range chunks[id] {
+				complete = complete && chunk != nil
+			}
+			if !complete {
+				continue
+			}
+
+			payload = bytes.Join(chunks[id], nil)
+			delete(chunks, id)
+		}
+
+		var reader io.Reader = bytes.NewReader(payload)
+		switch {
+		case payload[0] == 0x1f && payload[1] == 0x8b:
+			reader, err = gzip.NewReader(reader)
+		case payload[0] == 0x78:
+			reader, err = zlib.NewReader(reader)
+		}
+		if err != nil {
+			t.Fatalf("Error decompressing message: %v", err)
+		}
+
+		var message map[string]any
+		err = json.NewDecoder(reader).Decode(&message)
+		assert.Equal(t, nil, err)
+		messages = append(messages, message)
+	}
+
+	return messages, datagrams
+}
+
+func startTestGELFUDP(t *testing.T) net.PacketConn {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func TestExportLogsGELF(t *testing.T) {
+	t.Run("Export logs as GELF over UDP successful", TestExportLogsGELF_UDP)
+	t.Run("Export logs as chunked GELF over UDP successful", TestExportLogsGELF_Chunked)
+	t.Run("Export logs as GELF over TCP successful", TestExportLogsGELF_TCP)
+	t.Run("Error exporting logs as GELF - invalid config", TestExportLogsGELF_InvalidConfig)
+}
+
+func TestExportLogsGELF_UDP(t *testing.T) {
+	conn := startTestGELFUDP(t)
+
+	logs := createTestLog()
+	logs[0].Attributes = map[string]string{"key1": "val1", "id": "reserved", "bad key": "dropped"}
+
+	exp := &logExporter.GELFExporter{}
+	defer exp.Close()
+
+	err := exp.ExportLogs("1234567890", nil, nil)
+	assert.Equal(t, nil, err)
+
+	err = exp.ExportLogs("1234567890", logs, map[string]string{
+		"gelfAddress":     conn.LocalAddr().String(),
+		"gelfCompression": "gzip",
+		"gelfHost":        "host",
+	})
+	assert.Equal(t, nil, err)
+
+	messages, datagrams := readGELFMessages(t, conn, 2)
+	assert.Equal(t, 2, datagrams)
+
+	timestamp, _ := time.ParseInLocation(utils.TimestampFormat, "10.03.2025 17:00:00", time.Local)
+	assert.Equal(t, map[string]any{
+		"version":       "1.1",
+		"host":          "host",
+		"short_message": "test message 1",
+		"timestamp":     float64(timestamp.Unix()),
+		"level":         float64(6),
+		"_severity":     "INFO",
+		"_logger":       utils.LoggerName,
+		"_service":      utils.ServiceName,
+		"_trace_id":     "1234567890",
+		"_span_id":      "00000000000",
+		"_key1":         "val1",
+	}, messages[0])
+	assert.Equal(t, "val2", messages[1]["_key2"])
+}
+
+func TestExportLogsGELF_Chunked(t *testing.T) {
+	conn := startTestGELFUDP(t)
+
+	logs := createTestLog()[:1]
+	logs[0].Message = strings.Repeat("long message ", 500)
+
+	exp := &logExporter.GELFExporter{}
+	defer exp.Close()
+
+	for _, compression := range []string{"none", "zlib"} {
+		err := exp.ExportLogs("1234567890", logs, map[string]string{
+			"gelfAddress":     conn.LocalAddr().String(),
+			"gelfCompression": compression,
+			"gelfChunkSize":   "512",
+			"gelfHost":        "host",
+		})
+		assert.Equal(t, nil, err)
+
+		messages, datagrams := readGELFMessages(t, conn, 1)
+		assert.Equal(t, logs[0].Message, messages[0]["short_message"], compression)
+		if compression == "none" {
+			assert.Equal(t, 14, datagrams)
+		}
+	}
+
+	// more than 128 chunks are not allowed
+	err := exp.ExportLogs("1234567890", logs, map[string]string{
+		"gelfAddress":   conn.LocalAddr().String(),
+		"gelfChunkSize": "50",
+	})
+	assert.Contains(t, err.Error(), "the maximum is 128")
+}
+
+func TestExportLogsGELF_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	defer listener.Close()
+
+	received := make(chan []string)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		var frames []string
+		for len(frames) < 2 {
+			frame, err := reader.ReadString(0)
+			if err != nil {
+				return
+			}
+			frames = append(frames, strings.TrimSuffix(frame, "\x00"))
+		}
+		received <- frames
+	}()
+
+	exp := &logExporter.GELFExporter{}
+	defer exp.Close()
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{
+		"gelfNetwork": "tcp",
+		"gelfAddress": listener.Addr().String(),
+	})
+	assert.Equal(t, nil, err)
+
+	select {
+	case frames := <-received:
+		var message map[string]any
+		assert.Equal(t, nil, json.Unmarshal([]byte(frames[1]), &message))
+		assert.Equal(t, "test message 2", message["short_message"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("no messages received")
+	}
+}
+
+func TestExportLogsGELF_InvalidConfig(t *testing.T) {
+	exp := &logExporter.GELFExporter{}
+	defer exp.Close()
+
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{})
+	assert.EqualError(t, err, "no gelfAddress in config")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"gelfAddress": "127.0.0.1:12201", "gelfNetwork": "http"})
+	assert.EqualError(t, err, `unsupported gelfNetwork "http"`)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"gelfAddress": "127.0.0.1:12201", "gelfNetwork": "tcp", "gelfCompression": "gzip"})
+	assert.EqualError(t, err, "gelfCompression is not supported over tcp")
+	assert.ErrorIs(t, err, utils.ErrInvalidConfig)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"gelfAddress": "127.0.0.1:12201", "gelfChunkSize": "12"})
+	assert.EqualError(t, err, "gelfChunkSize must be bigger than 12")
+}
@@ -0,0 +1,313 @@
+package logExporter
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"maps"
+	"os"
+	"otellogger/otel"
+	"otellogger/utils"
+	"slices"
+	"sync"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// export logs to an OTLP collector over gRPC
+//
+// config keys:
+//   - otlpEndpoint: collector address, e.g. "localhost:4317" (required)
+//   - otlpHeaders: extra request metadata as "key=value,key2=value2"
+//   - otlpInsecure: "true" to connect without TLS
+//   - otlpCACert, otlpClientCert, otlpClientKey: PEM files for TLS
+//   - otlpCompression: "gzip" or "none" (default)
+//   - otlpTimeout: deadline for each export call, e.g. "5s" (default 10s)
+type GRPCExporter struct {
+	// custom TLS settings, used instead of the otlp*Cert config keys when set
+	TLSConfig *tls.Config
+	// extra options used when connecting (e.g. a custom dialer)
+	DialOptions []grpc.DialOption
+
+	mu       sync.Mutex
+	conn     *grpc.ClientConn
+	client   collogspb.LogsServiceClient
+	endpoint string
+}
+
+const defaultGRPCTimeout = 10 * time.Second
+
+// export logs from a transaction as a single ExportLogsServiceRequest
+func (exp *GRPCExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	// check if there are no logs to export
+	if len(logs) == 0 {
+		return nil
+	}
+
+	client, err := exp.getClient(config)
+	if err != nil {
+		return err
+	}
+
+	timeout, err := configDuration(config, "otlpTimeout", defaultGRPCTimeout)
+	if err != nil {
+		return err
+	}
+
+	headers, err := parsePairs(config["otlpHeaders"])
+	if err != nil {
+		return configError("otlpHeaders", "invalid otlpHeaders in config: %w", err)
+	}
+
+	var callOptions []grpc.CallOption
+	switch config["otlpCompression"] {
+	case "", "none":
+	case "gzip":
+		callOptions = append(callOptions, grpc.UseCompressor(gzip.Name))
+	default:
+		return configError("otlpCompression", "unsupported otlpCompression %q", config["otlpCompression"])
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if len(headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(headers))
+	}
+
+	resp, err := client.Export(ctx, newLogsRequest(logs), callOptions...)
+	if err != nil {
+		return grpcError(err)
+	}
+
+	// the collector accepted the request but dropped some of the records
+	partial := resp.GetPartialSuccess()
+	if partial != nil && partial.GetRejectedLogRecords() > 0 {
+		return fmt.Errorf("collector rejected %d log records: %s", partial.GetRejectedLogRecords(), partial.GetErrorMessage())
+	}
+
+	return nil
+}
+
+// close the connection to the collector
+func (exp *GRPCExporter) Close() error {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	if exp.conn == nil {
+		return nil
+	}
+
+	err := exp.conn.Close()
+	exp.conn = nil
+	exp.client = nil
+	exp.endpoint = ""
+
+	return err
+}
+
+// get the client for the configured endpoint, connecting on first use
+func (exp *GRPCExporter) getClient(config map[string]string) (collogspb.LogsServiceClient, error) {
+	endpoint, err := configValue(config, "otlpEndpoint")
+	if err != nil {
+		return nil, err
+	}
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	if exp.conn != nil && exp.endpoint == endpoint {
+		return exp.client, nil
+	}
+
+	creds, err := exp.credentials(config)
+	if err != nil {
+		return nil, err
+	}
+
+	options := append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, exp.DialOptions...)
+	conn, err := grpc.NewClient(endpoint, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the endpoint changed, drop the old connection
+	if exp.conn != nil {
+		exp.conn.Close()
+	}
+
+	exp.conn = conn
+	exp.client = collogspb.NewLogsServiceClient(conn)
+	exp.endpoint = endpoint
+
+	return exp.client, nil
+}
+
+// build the transport credentials from config
+func (exp *GRPCExporter) credentials(config map[string]string) (credentials.TransportCredentials, error) {
+	insecureConn, err := configBool(config, "otlpInsecure")
+	if err != nil {
+		return nil, err
+	}
+
+	if insecureConn {
+		return insecure.NewCredentials(), nil
+	}
+
+	if exp.TLSConfig != nil {
+		return credentials.NewTLS(exp.TLSConfig), nil
+	}
+
+	tlsConfig, err := tlsFromConfig(config, "otlpCACert", "otlpClientCert", "otlpClientKey")
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// build a TLS config from the PEM files named by the given config keys
+func tlsFromConfig(config map[string]string, caKey, certKey, keyKey string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile := config[caKey]; caFile != "" {
+		caCert, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, configError(caKey, "no certificates found in %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	certFile, keyFile := config[certKey], config[keyKey]
+	if (certFile == "") != (keyFile == "") {
+		return nil, configError(certKey, "%s and %s must be provided together", certKey, keyKey)
+	}
+
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// mark the status codes the OTLP spec considers transient as retryable
+func grpcError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	switch st.Code() {
+	case codes.Canceled,
+		codes.DeadlineExceeded,
+		codes.ResourceExhausted,
+		codes.Aborted,
+		codes.OutOfRange,
+		codes.Unavailable,
+		codes.DataLoss:
+		return &utils.RetryableError{Err: err}
+	default:
+		return err
+	}
+}
+
+// group the logs by service (resource) and logger name (scope)
+func newLogsRequest(logs []*otel.OTelLog) *collogspb.ExportLogsServiceRequest {
+	request := &collogspb.ExportLogsServiceRequest{}
+	resources := make(map[string]*logspb.ResourceLogs)
+	scopes := make(map[[2]string]*logspb.ScopeLogs)
+
+	for _, log := range logs {
+		resource, ok := resources[log.ServiceName]
+		if !ok {
+			resource = &logspb.ResourceLogs{
+				Resource: &resourcepb.Resource{
+					Attributes: []*commonpb.KeyValue{stringKeyValue("service.name", log.ServiceName)},
+				},
+			}
+			resources[log.ServiceName] = resource
+			request.ResourceLogs = append(request.ResourceLogs, resource)
+		}
+
+		scopeKey := [2]string{log.ServiceName, log.LoggerName}
+		scope, ok := scopes[scopeKey]
+		if !ok {
+			scope = &logspb.ScopeLogs{
+				Scope: &commonpb.InstrumentationScope{Name: log.LoggerName},
+			}
+			scopes[scopeKey] = scope
+			resource.ScopeLogs = append(resource.ScopeLogs, scope)
+		}
+
+		scope.LogRecords = append(scope.LogRecords, newLogRecord(log))
+	}
+
+	return request
+}
+
+func newLogRecord(log *otel.OTelLog) *logspb.LogRecord {
+	record := &logspb.LogRecord{
+		ObservedTimeUnixNano: uint64(time.Now().UnixNano()),
+		SeverityNumber:       severityNumber(log.Severity),
+		SeverityText:         log.Severity,
+		Body:                 &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: log.Message}},
+		TraceId:              traceIDBytes(log.TraceID),
+		SpanId:               spanIDBytes(log.SpanID),
+	}
+
+	// keep the record even if the timestamp can't be parsed, the observed time is still set
+	timestamp, err := parseTimestamp(log.Timestamp)
+	if err == nil {
+		record.TimeUnixNano = uint64(timestamp.UnixNano())
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(log.Attributes)) {
+		record.Attributes = append(record.Attributes, stringKeyValue(key, log.Attributes[key]))
+	}
+
+	return record
+}
+
+func stringKeyValue(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+// map the logger levels to OTLP severity numbers
+func severityNumber(severity string) logspb.SeverityNumber {
+	switch severity {
+	case "DEBUG":
+		return logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG
+	case "INFO":
+		return logspb.SeverityNumber_SEVERITY_NUMBER_INFO
+	case "WARNING":
+		return logspb.SeverityNumber_SEVERITY_NUMBER_WARN
+	case "ERROR":
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+	default:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED
+	}
+}
@@ -0,0 +1,189 @@
+package logExporter_test
+
+import (
+	"context"
+	"net"
+	"otellogger/logExporter"
+	"otellogger/utils"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// in-process collector recording the requests it receives
+type testCollector struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	mu       sync.Mutex
+	requests []*collogspb.ExportLogsServiceRequest
+	metadata []metadata.MD
+	err      error
+	response *collogspb.ExportLogsServiceResponse
+}
+
+func (c *testCollector) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	c.requests = append(c.requests, req)
+	c.metadata = append(c.metadata, md)
+
+	if c.err != nil {
+		return nil, c.err
+	}
+	if c.response != nil {
+		return c.response, nil
+	}
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+// start the collector on a bufconn listener and return an exporter dialing it
+func startTestCollector(t *testing.T, collector *testCollector) *logExporter.GRPCExporter {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(server, collector)
+
+	go server.Serve(listener)
+
+	exp := &logExporter.GRPCExporter{
+		DialOptions: []grpc.DialOption{
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+		},
+	}
+
+	t.Cleanup(func() {
+		exp.Close()
+		server.Stop()
+	})
+
+	return exp
+}
+
+func grpcTestConfig() map[string]string {
+	return map[string]string{
+		"otlpEndpoint": "passthrough:///bufnet",
+		"otlpInsecure": "true",
+	}
+}
+
+func TestExportLogsGRPC(t *testing.T) {
+	t.Run("Export logs over gRPC successful", TestExportLogsGRPC_Success)
+	t.Run("Export logs over gRPC with headers and compression", TestExportLogsGRPC_HeadersCompression)
+	t.Run("Error exporting logs over gRPC - no endpoint in config", TestExportLogsGRPC_NoEndpoint)
+	t.Run("Error exporting logs over gRPC - status codes", TestExportLogsGRPC_StatusCodes)
+	t.Run("Error exporting logs over gRPC - partial success", TestExportLogsGRPC_PartialSuccess)
+}
+
+func TestExportLogsGRPC_Success(t *testing.T) {
+	collector := &testCollector{}
+	exp := startTestCollector(t, collector)
+
+	err := exp.ExportLogs("1234567890", nil, nil)
+	assert.Equal(t, nil, err)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), grpcTestConfig())
+	assert.Equal(t, nil, err)
+
+	if !assert.Len(t, collector.requests, 1) {
+		return
+	}
+
+	resourceLogs := collector.requests[0].ResourceLogs
+	assert.Len(t, resourceLogs, 1)
+	assert.Equal(t, "service.name", resourceLogs[0].Resource.Attributes[0].Key)
+	assert.Equal(t, utils.ServiceName, resourceLogs[0].Resource.Attributes[0].Value.GetStringValue())
+	assert.Equal(t, utils.LoggerName, resourceLogs[0].ScopeLogs[0].Scope.Name)
+
+	records := resourceLogs[0].ScopeLogs[0].LogRecords
+	assert.Len(t, records, 2)
+	assert.Equal(t, "test message 1", records[0].Body.GetStringValue())
+	assert.Equal(t, "INFO", records[0].SeverityText)
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_INFO, records[0].SeverityNumber)
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x49, 0x96, 0x02, 0xd2}, records[0].TraceId)
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 1}, records[1].SpanId)
+	assert.Equal(t, "key2", records[1].Attributes[0].Key)
+	assert.Equal(t, "val2", records[1].Attributes[0].Value.GetStringValue())
+	assert.NotEqual(t, uint64(0), records[0].TimeUnixNano)
+}
+
+func TestExportLogsGRPC_HeadersCompression(t *testing.T) {
+	collector := &testCollector{}
+	exp := startTestCollector(t, collector)
+
+	config := grpcTestConfig()
+	config["otlpHeaders"] = "api-key=secret,tenant=a"
+	config["otlpCompression"] = "gzip"
+
+	err := exp.ExportLogs("1234567890", createTestLog(), config)
+	assert.Equal(t, nil, err)
+
+	if !assert.Len(t, collector.metadata, 1) {
+		return
+	}
+	assert.Equal(t, []string{"secret"}, collector.metadata[0].Get("api-key"))
+	assert.Equal(t, []string{"a"}, collector.metadata[0].Get("tenant"))
+
+	config["otlpCompression"] = "zstd"
+	err = exp.ExportLogs("1234567890", createTestLog(), config)
+	assert.EqualError(t, err, `unsupported otlpCompression "zstd"`)
+}
+
+func TestExportLogsGRPC_NoEndpoint(t *testing.T) {
+	exp := logExporter.GRPCExporter{}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.EqualError(t, err, "no config provided")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"otlpInsecure": "true"})
+	assert.EqualError(t, err, "no otlpEndpoint in config")
+}
+
+func TestExportLogsGRPC_StatusCodes(t *testing.T) {
+	tests := []struct {
+		code      codes.Code
+		retryable bool
+	}{
+		{codes.Unavailable, true},
+		{codes.ResourceExhausted, true},
+		{codes.DeadlineExceeded, true},
+		{codes.InvalidArgument, false},
+		{codes.Unauthenticated, false},
+		{codes.PermissionDenied, false},
+	}
+
+	for _, test := range tests {
+		collector := &testCollector{err: status.Error(test.code, "collector error")}
+		exp := startTestCollector(t, collector)
+
+		err := exp.ExportLogs("1234567890", createTestLog(), grpcTestConfig())
+		assert.Equal(t, test.code, status.Code(err), test.code.String())
+		assert.Equal(t, test.retryable, utils.IsRetryable(err), test.code.String())
+	}
+}
+
+func TestExportLogsGRPC_PartialSuccess(t *testing.T) {
+	collector := &testCollector{
+		response: &collogspb.ExportLogsServiceResponse{
+			PartialSuccess: &collogspb.ExportLogsPartialSuccess{
+				RejectedLogRecords: 1,
+				ErrorMessage:       "record too large",
+			},
+		},
+	}
+	exp := startTestCollector(t, collector)
+
+	err := exp.ExportLogs("1234567890", createTestLog(), grpcTestConfig())
+	assert.EqualError(t, err, "collector rejected 1 log records: record too large")
+	assert.Equal(t, false, utils.IsRetryable(err))
+}
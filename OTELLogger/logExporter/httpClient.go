@@ -0,0 +1,130 @@
+package logExporter
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"otellogger/utils"
+	"strconv"
+	"time"
+)
+
+// settings shared by the exporters that send logs over http
+//
+// config keys:
+//   - httpTimeout: timeout for each request, e.g. "5s" (default 10s)
+//   - httpHeaders: extra request headers as "key=value,key2=value2"
+//   - httpCACert, httpClientCert, httpClientKey: PEM files for TLS
+type HTTPConfig struct {
+	// client used to send the requests, built from config when nil
+	Client *http.Client
+}
+
+const defaultHTTPTimeout = 10 * time.Second
+
+// error returned when the backend answers with a non-2xx status
+type HTTPStatusError struct {
+	StatusCode int
+	Body       string
+	// how long the backend asked us to wait before trying again (Retry-After header)
+	RetryAfter time.Duration
+}
+
+func (e *HTTPStatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("unexpected status code %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Body)
+}
+
+// get the configured client or build one from config
+func (cfg *HTTPConfig) client(config map[string]string) (*http.Client, error) {
+	if cfg.Client != nil {
+		return cfg.Client, nil
+	}
+
+	timeout, err := configDuration(config, "httpTimeout", defaultHTTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: timeout}
+
+	// only build a dedicated transport if custom certificates are needed
+	if config["httpCACert"] != "" || config["httpClientCert"] != "" {
+		tlsConfig, err := tlsFromConfig(config, "httpCACert", "httpClientCert", "httpClientKey")
+		if err != nil {
+			return nil, err
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		client.Transport = transport
+	}
+
+	return client, nil
+}
+
+// send a request with the headers from config and return the response body
+func (cfg *HTTPConfig) send(req *http.Request, config map[string]string) ([]byte, error) {
+	_, body, err := cfg.do(req, config)
+	return body, err
+}
+
+// same as send, but also returns the response for exporters that need its headers
+func (cfg *HTTPConfig) do(req *http.Request, config map[string]string) (*http.Response, []byte, error) {
+	client, err := cfg.client(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	headers, err := parsePairs(config["httpHeaders"])
+	if err != nil {
+		return nil, nil, configError("httpHeaders", "invalid httpHeaders in config: %w", err)
+	}
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// network errors are usually temporary
+		return nil, nil, &utils.RetryableError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp, nil, &utils.RetryableError{Err: err}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, body, httpStatusError(resp, body)
+	}
+
+	return resp, body, nil
+}
+
+// turn a non-2xx response into an error, marking the transient ones as retryable
+func httpStatusError(resp *http.Response, body []byte) error {
+	statusErr := &HTTPStatusError{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		statusErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	switch resp.StatusCode {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return &utils.RetryableError{Err: statusErr}
+	default:
+		return statusErr
+	}
+}
@@ -0,0 +1,49 @@
+package logExporter
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"hash/fnv"
+	"otellogger/utils"
+	"strconv"
+	"time"
+)
+
+// trace and span IDs are generated as decimal strings, while most backends
+// expect them as 16 and 8 byte values (or the hex encoding of those)
+func idValue(id string) uint64 {
+	value, err := strconv.ParseUint(id, 10, 64)
+	if err == nil {
+		return value
+	}
+
+	// custom IDs that aren't numbers still need a stable value
+	hash := fnv.New64a()
+	hash.Write([]byte(id))
+	return hash.Sum64()
+}
+
+func traceIDBytes(traceID string) []byte {
+	id := make([]byte, 16)
+	binary.BigEndian.PutUint64(id[8:], idValue(traceID))
+	return id
+}
+
+func spanIDBytes(spanID string) []byte {
+	id := make([]byte, 8)
+	binary.BigEndian.PutUint64(id, idValue(spanID))
+	return id
+}
+
+func traceIDHex(traceID string) string {
+	return hex.EncodeToString(traceIDBytes(traceID))
+}
+
+func spanIDHex(spanID string) string {
+	return hex.EncodeToString(spanIDBytes(spanID))
+}
+
+// log timestamps are stored as local time strings
+func parseTimestamp(timestamp string) (time.Time, error) {
+	return time.ParseInLocation(utils.TimestampFormat, timestamp, time.Local)
+}
@@ -0,0 +1,216 @@
+//go:build linux
+
+package logExporter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"maps"
+	"net"
+	"os"
+	"otellogger/otel"
+	"otellogger/utils"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// export logs straight to the systemd journal using its native protocol, so
+// fields like TRACE_ID can be queried with journalctl TRACE_ID=...
+//
+// attributes become upper-cased journal fields prefixed with ATTR_ (e.g.
+// user.id -> ATTR_USER_ID), so they can't overwrite fields like MESSAGE or
+// PRIORITY or the trusted fields journald adds itself
+//
+// config keys:
+//   - journaldSocket: journal socket path (default "/run/systemd/journal/socket")
+type JournaldExporter struct {
+	mu   sync.Mutex
+	conn *net.UnixConn
+}
+
+const defaultJournaldSocket = "/run/systemd/journal/socket"
+
+// export each log from a transaction as a journal entry
+func (exp *JournaldExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	// check if there are no logs to export
+	if len(logs) == 0 {
+		return nil
+	}
+
+	socket := config["journaldSocket"]
+	if socket == "" {
+		socket = defaultJournaldSocket
+	}
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	for _, log := range logs {
+		err := exp.send(journalEntry(log), socket)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// close the connection to the journal
+func (exp *JournaldExporter) Close() error {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	return exp.closeConn()
+}
+
+func (exp *JournaldExporter) closeConn() error {
+	if exp.conn == nil {
+		return nil
+	}
+
+	err := exp.conn.Close()
+	exp.conn = nil
+
+	return err
+}
+
+// send an entry, recreating the socket once if sending fails
+func (exp *JournaldExporter) send(entry []byte, socket string) error {
+	addr := &net.UnixAddr{Name: socket, Net: "unixgram"}
+	var err error
+
+	for attempt := 0; attempt < 2; attempt++ {
+		err = exp.open()
+		if err != nil {
+			return &utils.RetryableError{Err: err}
+		}
+
+		_, _, err = exp.conn.WriteMsgUnix(entry, nil, addr)
+		if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+			// too big for a datagram, pass it through a sealed memfd instead
+			return exp.sendMemfd(entry, addr)
+		}
+		if err == nil {
+			return nil
+		}
+
+		exp.closeConn()
+	}
+
+	return &utils.RetryableError{Err: err}
+}
+
+// open an unconnected datagram socket, the journal address is given on every send
+// so a restarted journal is picked up without reconnecting
+func (exp *JournaldExporter) open() error {
+	if exp.conn != nil {
+		return nil
+	}
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: "", Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	exp.conn = conn
+
+	return nil
+}
+
+// write the entry to a sealed memfd and send its file descriptor with an empty datagram
+func (exp *JournaldExporter) sendMemfd(entry []byte, addr *net.UnixAddr) error {
+	fd, err := unix.MemfdCreate("journal-entry", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return err
+	}
+
+	file := os.NewFile(uintptr(fd), "journal-entry")
+	defer file.Close()
+
+	_, err = file.Write(entry)
+	if err != nil {
+		return err
+	}
+
+	// journald only accepts sealed memfds
+	_, err = unix.FcntlInt(file.Fd(), unix.F_ADD_SEALS, unix.F_SEAL_SHRINK|unix.F_SEAL_GROW|unix.F_SEAL_WRITE|unix.F_SEAL_SEAL)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = exp.conn.WriteMsgUnix(nil, unix.UnixRights(int(file.Fd())), addr)
+	if err != nil {
+		return &utils.RetryableError{Err: err}
+	}
+
+	return nil
+}
+
+// serialize a log in the native protocol
+func journalEntry(log *otel.OTelLog) []byte {
+	var entry bytes.Buffer
+
+	writeJournalField(&entry, "MESSAGE", log.Message)
+	writeJournalField(&entry, "PRIORITY", strconv.Itoa(syslogSeverity(log.Severity)))
+	writeJournalField(&entry, "SYSLOG_IDENTIFIER", log.ServiceName)
+	writeJournalField(&entry, "SERVICE_NAME", log.ServiceName)
+	writeJournalField(&entry, "LOGGER_NAME", log.LoggerName)
+	writeJournalField(&entry, "SEVERITY", log.Severity)
+	writeJournalField(&entry, "LOG_TIMESTAMP", log.Timestamp)
+	writeJournalField(&entry, "TRACE_ID", log.TraceID)
+	writeJournalField(&entry, "SPAN_ID", log.SpanID)
+
+	for _, key := range slices.Sorted(maps.Keys(log.Attributes)) {
+		name := journalFieldName(key)
+		if name == "" {
+			continue
+		}
+		writeJournalField(&entry, name, log.Attributes[key])
+	}
+
+	return entry.Bytes()
+}
+
+// fields are KEY=value lines, values containing newlines are written as
+// KEY\n followed by their little endian 64 bit length and the raw value
+func writeJournalField(entry *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		entry.WriteString(name + "=" + value + "\n")
+		return
+	}
+
+	entry.WriteString(name + "\n")
+	binary.Write(entry, binary.LittleEndian, uint64(len(value)))
+	entry.WriteString(value + "\n")
+}
+
+// field names only contain A-Z, 0-9 and '_' and can't start with '_' or a
+// digit, the ATTR_ prefix takes care of the start and keeps attributes apart
+// from the fields set by us and journald
+func journalFieldName(key string) string {
+	if key == "" {
+		return ""
+	}
+
+	name := "ATTR_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	return name
+}
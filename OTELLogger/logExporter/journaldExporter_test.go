@@ -0,0 +1,164 @@
+//go:build linux
+
+package logExporter_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"otellogger/logExporter"
+	"otellogger/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+// stand-in for journald listening on a unix datagram socket
+func startTestJournal(t *testing.T) (*net.UnixConn, string) {
+	path := t.TempDir() + "/journal.sock"
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn, path
+}
+
+// read an entry, either from the datagram or from the memfd passed with it
+func readJournalEntry(t *testing.T, conn *net.UnixConn) map[string]string {
+	buf := make([]byte, 64*1024)
+	oob := make([]byte, 1024)
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		t.Fatalf("Error reading entry: %v", err)
+	}
+
+	data := buf[:n]
+	if oobn > 0 {
+		messages, err := unix.ParseSocketControlMessage(oob[:oobn])
+		assert.Equal(t, nil, err)
+
+		fds, err := unix.ParseUnixRights(&messages[0])
+		assert.Equal(t, nil, err)
+
+		file := os.NewFile(uintptr(fds[0]), "memfd")
+		defer file.Close()
+
+		// the exporter shares the file offset, read from the start
+		data, err = io.ReadAll(io.NewSectionReader(file, 0, 1<<30))
+		assert.Equal(t, nil, err)
+	}
+
+	return parseJournalEntry(t, data)
+}
+
+// parse the native protocol back into fields
+func parseJournalEntry(t *testing.T, data []byte) map[string]string {
+	fields := make(map[string]string)
+
+	for len(data) > 0 {
+		line, rest, _ := bytes.Cut(data, []byte("\n"))
+
+		name, value, ok := bytes.Cut(line, []byte("="))
+		if ok {
+			fields[string(name)] = string(value)
+			data = rest
+			continue
+		}
+
+		// binary field with length prefix
+		length := binary.LittleEndian.Uint64(rest[:8])
+		fields[string(line)] = string(rest[8 : 8+length])
+		if !assert.Equal(t, byte('\n'), rest[8+length]) {
+			break
+		}
+		data = rest[9+length:]
+	}
+
+	return fields
+}
+
+func TestExportLogsJournald(t *testing.T) {
+	t.Run("Export logs to journald successful", TestExportLogsJournald_Success)
+	t.Run("Export large log to journald through memfd", TestExportLogsJournald_Memfd)
+	t.Run("Error exporting logs to journald - no socket", TestExportLogsJournald_NoSocket)
+}
+
+func TestExportLogsJournald_Success(t *testing.T) {
+	conn, path := startTestJournal(t)
+
+	exp := &logExporter.JournaldExporter{}
+	defer exp.Close()
+
+	err := exp.ExportLogs("1234567890", nil, nil)
+	assert.Equal(t, nil, err)
+
+	logs := createTestLog()
+	logs[1].Severity = "ERROR"
+	logs[1].Message = "multi\nline"
+	logs[1].Attributes = map[string]string{"user.id": "42", "_private": "x", "2fa": "on", "message": "attr", "_PID": "1"}
+
+	err = exp.ExportLogs("1234567890", logs, map[string]string{"journaldSocket": path})
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, map[string]string{
+		"MESSAGE":           "test message 1",
+		"PRIORITY":          "6",
+		"SYSLOG_IDENTIFIER": utils.ServiceName,
+		"SERVICE_NAME":      utils.ServiceName,
+		"LOGGER_NAME":       utils.LoggerName,
+		"SEVERITY":          "INFO",
+		"LOG_TIMESTAMP":     "10.03.2025 17:00:00",
+		"TRACE_ID":          "1234567890",
+		"SPAN_ID":           "00000000000",
+		"ATTR_KEY1":         "val1",
+	}, readJournalEntry(t, conn))
+
+	entry := readJournalEntry(t, conn)
+	assert.Equal(t, "multi\nline", entry["MESSAGE"])
+	assert.Equal(t, "3", entry["PRIORITY"])
+	assert.Equal(t, "42", entry["ATTR_USER_ID"])
+	assert.Equal(t, "x", entry["ATTR__PRIVATE"])
+	assert.Equal(t, "on", entry["ATTR_2FA"])
+
+	// attributes can't overwrite our fields or the trusted ones
+	assert.Equal(t, "attr", entry["ATTR_MESSAGE"])
+	assert.Equal(t, "1", entry["ATTR__PID"])
+	assert.NotContains(t, entry, "_PID")
+}
+
+func TestExportLogsJournald_Memfd(t *testing.T) {
+	conn, path := startTestJournal(t)
+
+	exp := &logExporter.JournaldExporter{}
+	defer exp.Close()
+
+	// bigger than the maximum datagram size
+	logs := createTestLog()[:1]
+	logs[0].Message = strings.Repeat("a", 1024*1024)
+
+	err := exp.ExportLogs("1234567890", logs, map[string]string{"journaldSocket": path})
+	assert.Equal(t, nil, err)
+
+	entry := readJournalEntry(t, conn)
+	assert.Equal(t, logs[0].Message, entry["MESSAGE"])
+	assert.Equal(t, "1234567890", entry["TRACE_ID"])
+}
+
+func TestExportLogsJournald_NoSocket(t *testing.T) {
+	exp := &logExporter.JournaldExporter{}
+	defer exp.Close()
+
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{"journaldSocket": t.TempDir() + "/missing.sock"})
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, true, utils.IsRetryable(err))
+}
@@ -0,0 +1,62 @@
+package logExporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// flush an exporter if it holds on to exports, see Flusher
+func FlushExporter(ctx context.Context, exp LogExporter) error {
+	if flusher, ok := exp.(Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+
+	return nil
+}
+
+// shut an exporter down with its Shutdown method, exporters without one are
+// flushed and closed if they are an io.Closer (like the socket exporters)
+func ShutdownExporter(ctx context.Context, exp LogExporter) error {
+	if shutdowner, ok := exp.(Shutdowner); ok {
+		return shutdowner.Shutdown(ctx)
+	}
+
+	err := FlushExporter(ctx, exp)
+	if closer, ok := exp.(io.Closer); ok {
+		err = errors.Join(err, closer.Close())
+	}
+
+	return err
+}
+
+// flush every exporter of a wrapper, skipping the unset ones (e.g. no fallback)
+func flushAll(ctx context.Context, exporters ...LogExporter) error {
+	return forEachExporter(exporters, func(exp LogExporter) error {
+		return FlushExporter(ctx, exp)
+	})
+}
+
+// shut down every exporter of a wrapper, skipping the unset ones
+func shutdownAll(ctx context.Context, exporters ...LogExporter) error {
+	return forEachExporter(exporters, func(exp LogExporter) error {
+		return ShutdownExporter(ctx, exp)
+	})
+}
+
+func forEachExporter(exporters []LogExporter, do func(exp LogExporter) error) error {
+	var errs []error
+	for i, exp := range exporters {
+		if exp == nil {
+			continue
+		}
+
+		err := do(exp)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("exporter %d (%T): %w", i, exp, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
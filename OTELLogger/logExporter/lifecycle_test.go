@@ -0,0 +1,84 @@
+package logExporter_test
+
+import (
+	"context"
+	"errors"
+	"otellogger/logExporter"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// exporter keeping track of how it was flushed and stopped
+type testLifecycleExporter struct {
+	testExporter
+	calls    []string
+	closeErr error
+}
+
+func (exp *testLifecycleExporter) Flush(ctx context.Context) error {
+	exp.record("flush")
+	return nil
+}
+
+func (exp *testLifecycleExporter) Close() error {
+	exp.record("close")
+	return exp.closeErr
+}
+
+func (exp *testLifecycleExporter) record(call string) {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	exp.calls = append(exp.calls, call)
+}
+
+func TestExporterLifecycle(t *testing.T) {
+	t.Run("Flush wrapped exporters successful", TestExporterLifecycle_Flush)
+	t.Run("Shut down wrapped exporters successful", TestExporterLifecycle_Shutdown)
+	t.Run("Error shutting down wrapped exporters - close returns error", TestExporterLifecycle_ErrorClose)
+}
+
+func TestExporterLifecycle_Flush(t *testing.T) {
+	primary, fallback, breaker := &testLifecycleExporter{}, &testLifecycleExporter{}, &testLifecycleExporter{}
+
+	exp := logExporter.NewMultiExporter(
+		logExporter.NewFailoverExporter(&logExporter.RetryExporter{Exporter: primary}, fallback),
+		&logExporter.CircuitBreakerExporter{Exporter: logExporter.NewDeadLetterExporter(breaker, nil)},
+	)
+
+	err := logExporter.FlushExporter(context.Background(), exp)
+	assert.Equal(t, nil, err)
+
+	// flushing doesn't close anything
+	assert.Equal(t, []string{"flush"}, primary.calls)
+	assert.Equal(t, []string{"flush"}, fallback.calls)
+	assert.Equal(t, []string{"flush"}, breaker.calls)
+
+	// exporters that don't buffer have nothing to flush
+	err = logExporter.FlushExporter(context.Background(), &testExporter{})
+	assert.Equal(t, nil, err)
+}
+
+func TestExporterLifecycle_Shutdown(t *testing.T) {
+	downstream := &testLifecycleExporter{}
+	p := logExporter.NewBatchProcessor(&logExporter.RetryExporter{Exporter: downstream})
+
+	err := p.ExportLogs("1234567890", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+
+	// the queue is drained before the exporter is flushed and closed
+	err = logExporter.ShutdownExporter(context.Background(), logExporter.NewMultiExporter(p))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"1234567890"}, downstream.exports)
+	assert.Equal(t, []string{"flush", "close"}, downstream.calls)
+}
+
+func TestExporterLifecycle_ErrorClose(t *testing.T) {
+	errClose := errors.New("close failed")
+	exp := logExporter.NewMultiExporter(&testExporter{}, &testLifecycleExporter{closeErr: errClose})
+
+	err := logExporter.ShutdownExporter(context.Background(), exp)
+	assert.ErrorIs(t, err, errClose)
+	assert.EqualError(t, err, "exporter 1 (*logExporter_test.testLifecycleExporter): close failed")
+}
@@ -1,17 +1,52 @@
 package logExporter
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"os"
 	"otellogger/otel"
+	"otellogger/utils"
+	"sync"
 )
 
+// driver interface, implemented by every exporter
+type LogExporter interface {
+	ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error
+}
+
+// optional driver interface for exporters that need the whole transaction
+// (e.g. its attributes and timespan) instead of just the logs
+type TransactionExporter interface {
+	ExportTransaction(transactionLog *otel.TransactionLog, config map[string]string) error
+}
+
+// optional driver interface for exporters that hold on to exports (e.g. in
+// batches or a queue), Flush returns once those are exported
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// optional driver interface for exporters that have to be stopped, Shutdown
+// exports what is left and releases the connections, files or workers
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// optional driver interface for exporters that can give up on an export once
+// the context is done (e.g. while waiting between retries or for queue space)
+type ContextExporter interface {
+	ExportLogsContext(ctx context.Context, traceID string, logs []*otel.OTelLog, config map[string]string) error
+	ExportTransactionContext(ctx context.Context, transactionLog *otel.TransactionLog, config map[string]string) error
+}
+
 // the provided exporter drivers
 // more can be added out of the box
 type DefaultExporter struct{}
-type JSONExporter struct{}
+type JSONExporter struct {
+	mu sync.Mutex // keeps appends to the ndjson file whole
+}
 type TXTExporter struct{}
 
 func parse(log *otel.OTelLog) ([]byte, error) {
@@ -39,30 +74,69 @@ func (exp *DefaultExporter) ExportLogs(traceID string, logs []*otel.OTelLog, con
 }
 
 // export logs as jsons
+//
+// config keys:
+//   - jsonMode: "indented" (default) writes an indented array per trace to
+//     filename_traceID.json, "ndjson" appends one line per log to filename.ndjson
+//     (both in the filepath directory, see filePath.go for templated paths)
+//   - jsonEnvelope: "transaction" to write one line per transaction instead of per log (ndjson only)
 func (exp *JSONExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	return exp.export(transactionFromLogs(traceID, logs), config)
+}
+
+// export the transaction as json, with its attributes and timespan when written as one line
+func (exp *JSONExporter) ExportTransaction(transactionLog *otel.TransactionLog, config map[string]string) error {
+	return exp.export(transactionLog, config)
+}
+
+func (exp *JSONExporter) export(transactionLog *otel.TransactionLog, config map[string]string) error {
 	// check if there are no logs to export
-	if len(logs) == 0 {
+	if len(transactionLog.Spans) == 0 {
 		return nil
 	}
 
 	if config == nil {
-		return errors.New("no config provided")
+		return utils.ErrNoConfig
 	}
 
-	// get the filepath from config
-	filepath, ok := config["filepath"]
+	// check the base directory and the way the filename will look like are in the config
+	_, ok := config["filepath"]
 	if !ok {
-		return errors.New("no filepath in config")
+		return configError("filepath", "no filepath in config")
 	}
 
-	// get the way the filename will look like
-	filename, ok := config["filename"]
-	if !ok {
-		return errors.New("no filename in config")
+	_, ok = config["filename"]
+	if !ok && config["pathTemplate"] == "" {
+		return configError("filename", "no filename in config")
+	}
+
+	envelope := config["jsonEnvelope"]
+	if envelope != "" && envelope != "log" && envelope != "transaction" {
+		return configError("jsonEnvelope", "unsupported jsonEnvelope %q", envelope)
+	}
+
+	mode := config["jsonMode"]
+	switch mode {
+	case "", "indented":
+		// by default logfiles will have the format filename_1234567890.json to be able to recognize it by traceID
+		path, perm, err := outputPath(config, "{{.Filename}}_{{.TraceID}}.json", transactionLog)
+		if err != nil {
+			return err
+		}
+		return writeIndentedJSON(path, perm, transactionLog.Spans)
+	case "ndjson":
+		path, perm, err := outputPath(config, "{{.Filename}}.ndjson", transactionLog)
+		if err != nil {
+			return err
+		}
+		return exp.appendNDJSON(path, perm, transactionLog, envelope == "transaction")
+	default:
+		return configError("jsonMode", "unsupported jsonMode %q", mode)
 	}
+}
 
-	// all logfiles will have the format filename_1234567890.json to be able to recognize it by traceID
-	file, err := os.Create(filepath + filename + "_" + traceID + ".json")
+func writeIndentedJSON(path string, perm os.FileMode, logs []*otel.OTelLog) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
 	if err != nil {
 		return err
 	}
@@ -79,6 +153,39 @@ func (exp *JSONExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config
 	return nil
 }
 
+// append the logs (or the whole transaction) as lines to a file shared by all traces
+func (exp *JSONExporter) appendNDJSON(path string, perm os.FileMode, transactionLog *otel.TransactionLog, wholeTransaction bool) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+
+	if wholeTransaction {
+		err := encoder.Encode(transactionLog)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, log := range transactionLog.Spans {
+			err := encoder.Encode(log)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// one write per transaction so the lines of other writers never end up in between
+	_, err = file.Write(buf.Bytes())
+	return err
+}
+
 // export logs as text files
 func (exp *TXTExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
 	// check if there are no logs to export
@@ -87,22 +194,26 @@ func (exp *TXTExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config
 	}
 
 	if config == nil {
-		return errors.New("no config provided")
+		return utils.ErrNoConfig
 	}
 
-	// get the filepath from config
-	filepath, ok := config["filepath"]
+	// check the base directory and the way the filename will look like are in the config
+	_, ok := config["filepath"]
 	if !ok {
-		return errors.New("no filepath in config")
+		return configError("filepath", "no filepath in config")
 	}
 
-	// get the way the filename will look like
-	filename, ok := config["filename"]
-	if !ok {
-		return errors.New("no filename in config")
+	_, ok = config["filename"]
+	if !ok && config["pathTemplate"] == "" {
+		return configError("filename", "no filename in config")
+	}
+
+	path, perm, err := outputPath(config, "{{.Filename}}_{{.TraceID}}.txt", transactionFromLogs(traceID, logs))
+	if err != nil {
+		return err
 	}
 
-	file, err := os.OpenFile(filepath+filename+"_"+traceID+".txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, perm)
 	if err != nil {
 		return err
 	}
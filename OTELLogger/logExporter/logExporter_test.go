@@ -9,6 +9,7 @@ import (
 	"otellogger/logExporter"
 	"otellogger/otel"
 	"otellogger/utils"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -77,6 +78,9 @@ func TestExportLogsJSON(t *testing.T) {
 	t.Run("Error exporting logs to json - no config provided", TestExportLogsJSON_NoConfig)
 	t.Run("Error exporting logs to json - no filepath in config", TestExportLogsJSON_NoFilepath)
 	t.Run("Error exporting logs to json - no filename in config", TestExportLogsJSON_NoFilename)
+	t.Run("Export logs to ndjson successful", TestExportLogsJSON_NDJSON)
+	t.Run("Export transactions to ndjson successful", TestExportLogsJSON_NDJSONTransaction)
+	t.Run("Error exporting logs to json - unsupported mode", TestExportLogsJSON_ErrorMode)
 }
 
 func TestExportLogsJSON_Success(t *testing.T) {
@@ -145,6 +149,7 @@ func TestExportLogsJSON_NoConfig(t *testing.T) {
 
 	err := jsonLogExporter.ExportLogs("1234567890", otellogs, nil)
 	assert.Equal(t, errors.New("no config provided"), err)
+	assert.ErrorIs(t, err, utils.ErrNoConfig)
 }
 
 func TestExportLogsJSON_NoFilepath(t *testing.T) {
@@ -152,7 +157,8 @@ func TestExportLogsJSON_NoFilepath(t *testing.T) {
 	otellogs := createTestLog()
 
 	err := jsonLogExporter.ExportLogs("1234567890", otellogs, map[string]string{"filename": "test_filepath"})
-	assert.Equal(t, errors.New("no filepath in config"), err)
+	assert.EqualError(t, err, "no filepath in config")
+	assert.ErrorIs(t, err, utils.ErrInvalidConfig)
 }
 
 func TestExportLogsJSON_NoFilename(t *testing.T) {
@@ -160,7 +166,104 @@ func TestExportLogsJSON_NoFilename(t *testing.T) {
 	otellogs := createTestLog()
 
 	err := jsonLogExporter.ExportLogs("1234567890", otellogs, map[string]string{"filepath": ""})
-	assert.Equal(t, errors.New("no filename in config"), err)
+	assert.EqualError(t, err, "no filename in config")
+	assert.ErrorIs(t, err, utils.ErrInvalidConfig)
+}
+
+func TestExportLogsJSON_NDJSON(t *testing.T) {
+	jsonLogExporter := logExporter.JSONExporter{}
+	dir := t.TempDir() + "/"
+	config := map[string]string{"filepath": dir, "filename": "test", "jsonMode": "ndjson"}
+
+	// the same trace from several goroutines, nothing is overwritten or interleaved
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := jsonLogExporter.ExportLogs("1234567890", createTestLog(), config)
+			assert.Equal(t, nil, err)
+		}()
+	}
+	wg.Wait()
+
+	file, err := os.Open(dir + "test.ndjson")
+	if err != nil {
+		t.Fatalf("Error opening file: %v", err)
+	}
+	defer file.Close()
+
+	// one log per line
+	var logs []*otel.OTelLog
+	decoder := json.NewDecoder(file)
+	for decoder.More() {
+		var log otel.OTelLog
+		err = decoder.Decode(&log)
+		if !assert.Equal(t, nil, err) {
+			return
+		}
+		logs = append(logs, &log)
+	}
+
+	assert.Len(t, logs, 20)
+	assert.Equal(t, createTestLog()[0], logs[0])
+	assert.Equal(t, createTestLog()[1], logs[1])
+
+	content, err := os.ReadFile(dir + "test.ndjson")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 20, bytes.Count(content, []byte("\n")))
+}
+
+func TestExportLogsJSON_NDJSONTransaction(t *testing.T) {
+	jsonLogExporter := logExporter.JSONExporter{}
+	dir := t.TempDir() + "/"
+	config := map[string]string{"filepath": dir, "filename": "test", "jsonMode": "ndjson", "jsonEnvelope": "transaction"}
+
+	err := jsonLogExporter.ExportTransaction(createTestTransaction(), config)
+	assert.Equal(t, nil, err)
+
+	err = jsonLogExporter.ExportLogs("1234567890", createTestLog(), config)
+	assert.Equal(t, nil, err)
+
+	content, err := os.ReadFile(dir + "test.ndjson")
+	assert.Equal(t, nil, err)
+
+	lines := bytes.Split(bytes.TrimSuffix(content, []byte("\n")), []byte("\n"))
+	if !assert.Len(t, lines, 2) {
+		return
+	}
+
+	// the transaction keeps its attributes and timespan
+	var transaction otel.TransactionLog
+	err = json.Unmarshal(lines[0], &transaction)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, createTestTransaction().TraceID, transaction.TraceID)
+	assert.Equal(t, createTestTransaction().Attributes, transaction.Attributes)
+	assert.True(t, createTestTransaction().StartTime.Equal(transaction.StartTime))
+	assert.Len(t, transaction.Spans, len(createTestTransaction().Spans))
+
+	err = json.Unmarshal(lines[1], &transaction)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "1234567890", transaction.TraceID)
+	assert.Equal(t, createTestLog(), transaction.Spans)
+
+	// indented files still work as transactions
+	err = jsonLogExporter.ExportTransaction(createTestTransaction(), map[string]string{"filepath": dir, "filename": "test"})
+	assert.Equal(t, nil, err)
+	assert.FileExists(t, dir+"test_"+createTestTransaction().TraceID+".json")
+}
+
+func TestExportLogsJSON_ErrorMode(t *testing.T) {
+	jsonLogExporter := logExporter.JSONExporter{}
+	otellogs := createTestLog()
+
+	err := jsonLogExporter.ExportLogs("1234567890", otellogs, map[string]string{"filepath": "", "filename": "test", "jsonMode": "yaml"})
+	assert.EqualError(t, err, `unsupported jsonMode "yaml"`)
+	assert.ErrorIs(t, err, utils.ErrInvalidConfig)
+
+	err = jsonLogExporter.ExportLogs("1234567890", otellogs, map[string]string{"filepath": "", "filename": "test", "jsonMode": "ndjson", "jsonEnvelope": "batch"})
+	assert.EqualError(t, err, `unsupported jsonEnvelope "batch"`)
 }
 
 func TestExportLogsTxt(t *testing.T) {
@@ -200,6 +303,7 @@ func TestExportLogsTxt_NoConfig(t *testing.T) {
 
 	err := txtLogExporter.ExportLogs("1234567890", otellogs, nil)
 	assert.Equal(t, errors.New("no config provided"), err)
+	assert.ErrorIs(t, err, utils.ErrNoConfig)
 }
 
 func TestExportLogsTxt_NoFilepath(t *testing.T) {
@@ -207,7 +311,8 @@ func TestExportLogsTxt_NoFilepath(t *testing.T) {
 	otellogs := createTestLog()
 
 	err := txtLogExporter.ExportLogs("1234567890", otellogs, map[string]string{"filename": "test_filepath"})
-	assert.Equal(t, errors.New("no filepath in config"), err)
+	assert.EqualError(t, err, "no filepath in config")
+	assert.ErrorIs(t, err, utils.ErrInvalidConfig)
 }
 
 func TestExportLogsTxt_NoFilename(t *testing.T) {
@@ -215,5 +320,6 @@ func TestExportLogsTxt_NoFilename(t *testing.T) {
 	otellogs := createTestLog()
 
 	err := txtLogExporter.ExportLogs("1234567890", otellogs, map[string]string{"filepath": ""})
-	assert.Equal(t, errors.New("no filename in config"), err)
+	assert.EqualError(t, err, "no filename in config")
+	assert.ErrorIs(t, err, utils.ErrInvalidConfig)
 }
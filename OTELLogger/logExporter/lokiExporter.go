@@ -0,0 +1,316 @@
+package logExporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"otellogger/otel"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// export logs to Grafana Loki through the push api
+//
+// logs are grouped into streams by a small label set, trace and span IDs and
+// the attributes go into the structured metadata of each entry to keep the
+// label cardinality low
+//
+// config keys (plus the shared HTTPConfig keys):
+//   - lokiEndpoint: Loki base url, e.g. "http://localhost:3100" (required)
+//   - lokiLabels: labels to group by out of "service", "logger" and "level"
+//     (default "service,logger,level")
+//   - lokiStaticLabels: extra labels as "env=prod,region=eu"
+//   - lokiEncoding: "json" (default), "gzip" (gzipped json) or "snappy" (snappy compressed protobuf)
+//   - lokiLineFormat: "message" (default) or "json" for the whole log as the line
+//   - lokiTenant: tenant ID sent as X-Scope-OrgID
+type LokiExporter struct {
+	HTTPConfig
+}
+
+const defaultLokiLabels = "service,logger,level"
+
+var lokiLabelNames = map[string]string{
+	"service": "service_name",
+	"logger":  "logger",
+	"level":   "level",
+}
+
+type lokiStream struct {
+	labels  map[string]string
+	entries []lokiEntry
+}
+
+type lokiEntry struct {
+	timestamp time.Time
+	line      string
+	metadata  map[string]string
+}
+
+// export logs from a transaction in a single push request
+func (exp *LokiExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	// check if there are no logs to export
+	if len(logs) == 0 {
+		return nil
+	}
+
+	endpoint, err := configValue(config, "lokiEndpoint")
+	if err != nil {
+		return err
+	}
+
+	streams, err := lokiStreams(logs, config)
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+	contentType, contentEncoding := "application/json", ""
+
+	switch config["lokiEncoding"] {
+	case "", "json":
+		body, err = lokiJSON(streams)
+	case "gzip":
+		body, err = lokiJSON(streams)
+		if err == nil {
+			body, err = gzipBytes(body)
+		}
+		contentEncoding = "gzip"
+	case "snappy":
+		body = snappy.Encode(nil, lokiProtobuf(streams))
+		contentType = "application/x-protobuf"
+	default:
+		return configError("lokiEncoding", "unsupported lokiEncoding %q", config["lokiEncoding"])
+	}
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(endpoint, "/")+"/loki/api/v1/push", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+	if tenant := config["lokiTenant"]; tenant != "" {
+		req.Header.Set("X-Scope-OrgID", tenant)
+	}
+
+	_, err = exp.send(req, config)
+
+	// entries older than what the stream already has are rejected for good,
+	// rate limits (429) are already marked as retryable
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusBadRequest &&
+		(strings.Contains(statusErr.Body, "out of order") || strings.Contains(statusErr.Body, "too far behind")) {
+		return fmt.Errorf("loki rejected out of order entries: %w", err)
+	}
+
+	return err
+}
+
+// group the logs into streams keyed by their labels
+func lokiStreams(logs []*otel.OTelLog, config map[string]string) ([]*lokiStream, error) {
+	labelConfig := config["lokiLabels"]
+	if labelConfig == "" {
+		labelConfig = defaultLokiLabels
+	}
+
+	var labelKeys []string
+	for _, key := range strings.Split(labelConfig, ",") {
+		key = strings.TrimSpace(key)
+		if _, ok := lokiLabelNames[key]; !ok {
+			return nil, configError("lokiLabels", "unsupported label %q in lokiLabels", key)
+		}
+		labelKeys = append(labelKeys, key)
+	}
+
+	staticLabels, err := parsePairs(config["lokiStaticLabels"])
+	if err != nil {
+		return nil, configError("lokiStaticLabels", "invalid lokiStaticLabels in config: %w", err)
+	}
+
+	var streams []*lokiStream
+	streamsByKey := make(map[string]*lokiStream)
+
+	for i, log := range logs {
+		labels := maps.Clone(staticLabels)
+		for _, key := range labelKeys {
+			switch key {
+			case "service":
+				labels[lokiLabelNames[key]] = log.ServiceName
+			case "logger":
+				labels[lokiLabelNames[key]] = log.LoggerName
+			case "level":
+				labels[lokiLabelNames[key]] = strings.ToLower(log.Severity)
+			}
+		}
+
+		entry, err := lokiLogEntry(log, config["lokiLineFormat"])
+		if err != nil {
+			return nil, err
+		}
+
+		// timestamps only have second precision, keep the logs of the same
+		// second in order by spacing them a nanosecond apart
+		entry.timestamp = entry.timestamp.Add(time.Duration(i))
+
+		key := lokiLabelString(labels)
+		stream, ok := streamsByKey[key]
+		if !ok {
+			stream = &lokiStream{labels: labels}
+			streamsByKey[key] = stream
+			streams = append(streams, stream)
+		}
+		stream.entries = append(stream.entries, entry)
+	}
+
+	// loki rejects entries older than the latest one in a stream
+	for _, stream := range streams {
+		slices.SortStableFunc(stream.entries, func(a, b lokiEntry) int {
+			return a.timestamp.Compare(b.timestamp)
+		})
+	}
+
+	return streams, nil
+}
+
+func lokiLogEntry(log *otel.OTelLog, lineFormat string) (lokiEntry, error) {
+	entry := lokiEntry{
+		timestamp: time.Now(),
+		metadata:  maps.Clone(log.Attributes),
+	}
+	if entry.metadata == nil {
+		entry.metadata = make(map[string]string)
+	}
+	entry.metadata["trace_id"] = log.TraceID
+	entry.metadata["span_id"] = log.SpanID
+
+	if timestamp, err := parseTimestamp(log.Timestamp); err == nil {
+		entry.timestamp = timestamp
+	}
+
+	switch lineFormat {
+	case "", "message":
+		entry.line = log.Message
+	case "json":
+		parsedLog, err := parse(log)
+		if err != nil {
+			return entry, err
+		}
+		entry.line = string(parsedLog)
+	default:
+		return entry, configError("lokiLineFormat", "unsupported lokiLineFormat %q", lineFormat)
+	}
+
+	return entry, nil
+}
+
+// format labels as {name="value", ...} sorted by name
+func lokiLabelString(labels map[string]string) string {
+	var pairs []string
+	for _, name := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, name+"="+strconv.Quote(labels[name]))
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// {"streams": [{"stream": {labels}, "values": [["<unix ns>", "<line>", {metadata}]]}]}
+func lokiJSON(streams []*lokiStream) ([]byte, error) {
+	type jsonStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][]any           `json:"values"`
+	}
+
+	request := struct {
+		Streams []jsonStream `json:"streams"`
+	}{}
+
+	for _, stream := range streams {
+		values := make([][]any, 0, len(stream.entries))
+		for _, entry := range stream.entries {
+			values = append(values, []any{strconv.FormatInt(entry.timestamp.UnixNano(), 10), entry.line, entry.metadata})
+		}
+
+		request.Streams = append(request.Streams, jsonStream{Stream: stream.labels, Values: values})
+	}
+
+	return json.Marshal(request)
+}
+
+// encode the logproto PushRequest message:
+//
+//	PushRequest { repeated Stream streams = 1 }
+//	Stream { string labels = 1; repeated Entry entries = 2 }
+//	Entry { Timestamp timestamp = 1; string line = 2; repeated LabelPair structuredMetadata = 3 }
+//	LabelPair { string name = 1; string value = 2 }
+func lokiProtobuf(streams []*lokiStream) []byte {
+	var request []byte
+
+	for _, stream := range streams {
+		var streamMsg []byte
+		streamMsg = protowire.AppendTag(streamMsg, 1, protowire.BytesType)
+		streamMsg = protowire.AppendString(streamMsg, lokiLabelString(stream.labels))
+
+		for _, entry := range stream.entries {
+			var timestamp []byte
+			timestamp = protowire.AppendTag(timestamp, 1, protowire.VarintType)
+			timestamp = protowire.AppendVarint(timestamp, uint64(entry.timestamp.Unix()))
+			timestamp = protowire.AppendTag(timestamp, 2, protowire.VarintType)
+			timestamp = protowire.AppendVarint(timestamp, uint64(entry.timestamp.Nanosecond()))
+
+			var entryMsg []byte
+			entryMsg = protowire.AppendTag(entryMsg, 1, protowire.BytesType)
+			entryMsg = protowire.AppendBytes(entryMsg, timestamp)
+			entryMsg = protowire.AppendTag(entryMsg, 2, protowire.BytesType)
+			entryMsg = protowire.AppendString(entryMsg, entry.line)
+
+			for _, name := range slices.Sorted(maps.Keys(entry.metadata)) {
+				var pair []byte
+				pair = protowire.AppendTag(pair, 1, protowire.BytesType)
+				pair = protowire.AppendString(pair, name)
+				pair = protowire.AppendTag(pair, 2, protowire.BytesType)
+				pair = protowire.AppendString(pair, entry.metadata[name])
+
+				entryMsg = protowire.AppendTag(entryMsg, 3, protowire.BytesType)
+				entryMsg = protowire.AppendBytes(entryMsg, pair)
+			}
+
+			streamMsg = protowire.AppendTag(streamMsg, 2, protowire.BytesType)
+			streamMsg = protowire.AppendBytes(streamMsg, entryMsg)
+		}
+
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, streamMsg)
+	}
+
+	return request
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	writer := gzip.NewWriter(&buf)
+	_, err := writer.Write(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,247 @@
+package logExporter_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"otellogger/logExporter"
+	"otellogger/utils"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type testLokiPush struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][]any           `json:"values"`
+	} `json:"streams"`
+}
+
+type testLokiRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// start a stand-in for the push endpoint answering with the given status and body
+func startTestLoki(t *testing.T, statusCode int, response string, request *testLokiRequest) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/loki/api/v1/push", r.URL.Path)
+
+		body, _ := io.ReadAll(r.Body)
+		request.header = r.Header
+		request.body = body
+
+		if statusCode == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "3")
+		}
+		w.WriteHeader(statusCode)
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func lokiTimestamp(timestamp string, offset int) string {
+	parsed, _ := time.ParseInLocation(utils.TimestampFormat, timestamp, time.Local)
+	return strconv.FormatInt(parsed.UnixNano()+int64(offset), 10)
+}
+
+// walk a protobuf message calling fn for each length delimited field
+func consumeFields(t *testing.T, msg []byte, fn func(num protowire.Number, value []byte)) {
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if !assert.True(t, n > 0) {
+			return
+		}
+		msg = msg[n:]
+
+		if typ == protowire.VarintType {
+			_, n = protowire.ConsumeVarint(msg)
+			fn(num, nil)
+		} else {
+			var value []byte
+			value, n = protowire.ConsumeBytes(msg)
+			fn(num, value)
+		}
+		if !assert.True(t, n > 0) {
+			return
+		}
+		msg = msg[n:]
+	}
+}
+
+func TestExportLogsLoki(t *testing.T) {
+	t.Run("Export logs to loki as json successful", TestExportLogsLoki_JSON)
+	t.Run("Export logs to loki as gzipped json successful", TestExportLogsLoki_Gzip)
+	t.Run("Export logs to loki as snappy protobuf successful", TestExportLogsLoki_Snappy)
+	t.Run("Error exporting logs to loki - rejected pushes", TestExportLogsLoki_Rejected)
+	t.Run("Error exporting logs to loki - invalid config", TestExportLogsLoki_InvalidConfig)
+}
+
+func TestExportLogsLoki_JSON(t *testing.T) {
+	request := &testLokiRequest{}
+	server := startTestLoki(t, http.StatusNoContent, "", request)
+
+	logs := createTestLog()
+	logs[1].Severity = "ERROR"
+
+	exp := logExporter.LokiExporter{}
+
+	err := exp.ExportLogs("1234567890", nil, nil)
+	assert.Equal(t, nil, err)
+
+	err = exp.ExportLogs("1234567890", logs, map[string]string{
+		"lokiEndpoint":     server.URL,
+		"lokiStaticLabels": "env=test",
+		"lokiTenant":       "team-a",
+	})
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, "application/json", request.header.Get("Content-Type"))
+	assert.Equal(t, "team-a", request.header.Get("X-Scope-OrgID"))
+
+	var push testLokiPush
+	assert.Equal(t, nil, json.Unmarshal(request.body, &push))
+	if !assert.Len(t, push.Streams, 2) {
+		return
+	}
+
+	// one stream per level, trace IDs stay out of the labels
+	assert.Equal(t, map[string]string{"env": "test", "service_name": utils.ServiceName, "logger": utils.LoggerName, "level": "info"}, push.Streams[0].Stream)
+	assert.Equal(t, [][]any{{
+		lokiTimestamp("10.03.2025 17:00:00", 0),
+		"test message 1",
+		map[string]any{"key1": "val1", "trace_id": "1234567890", "span_id": "00000000000"},
+	}}, push.Streams[0].Values)
+
+	assert.Equal(t, "error", push.Streams[1].Stream["level"])
+	assert.Equal(t, lokiTimestamp("10.03.2025 17:01:00", 1), push.Streams[1].Values[0][0])
+}
+
+func TestExportLogsLoki_Gzip(t *testing.T) {
+	request := &testLokiRequest{}
+	server := startTestLoki(t, http.StatusNoContent, "", request)
+
+	logs := createTestLog()
+	logs[1].Timestamp = logs[0].Timestamp
+
+	exp := logExporter.LokiExporter{}
+	err := exp.ExportLogs("1234567890", logs, map[string]string{
+		"lokiEndpoint":   server.URL,
+		"lokiEncoding":   "gzip",
+		"lokiLabels":     "service",
+		"lokiLineFormat": "json",
+	})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "gzip", request.header.Get("Content-Encoding"))
+
+	reader, err := gzip.NewReader(bytes.NewReader(request.body))
+	if err != nil {
+		t.Fatalf("Error reading gzip body: %v", err)
+	}
+	body, _ := io.ReadAll(reader)
+
+	var push testLokiPush
+	assert.Equal(t, nil, json.Unmarshal(body, &push))
+	if !assert.Len(t, push.Streams, 1) {
+		return
+	}
+
+	assert.Equal(t, map[string]string{"service_name": utils.ServiceName}, push.Streams[0].Stream)
+
+	// logs from the same second keep their order
+	values := push.Streams[0].Values
+	assert.Equal(t, lokiTimestamp("10.03.2025 17:00:00", 0), values[0][0])
+	assert.Equal(t, lokiTimestamp("10.03.2025 17:00:00", 1), values[1][0])
+
+	var line map[string]any
+	assert.Equal(t, nil, json.Unmarshal([]byte(values[1][1].(string)), &line))
+	assert.Equal(t, "test message 2", line["Message"])
+}
+
+func TestExportLogsLoki_Snappy(t *testing.T) {
+	request := &testLokiRequest{}
+	server := startTestLoki(t, http.StatusNoContent, "", request)
+
+	exp := logExporter.LokiExporter{}
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{
+		"lokiEndpoint": server.URL,
+		"lokiEncoding": "snappy",
+		"lokiLabels":   "service,level",
+	})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "application/x-protobuf", request.header.Get("Content-Type"))
+
+	body, err := snappy.Decode(nil, request.body)
+	if err != nil {
+		t.Fatalf("Error decoding snappy body: %v", err)
+	}
+
+	var labels []string
+	var lines []string
+	var metadata []string
+
+	consumeFields(t, body, func(_ protowire.Number, stream []byte) {
+		consumeFields(t, stream, func(num protowire.Number, value []byte) {
+			if num == 1 {
+				labels = append(labels, string(value))
+				return
+			}
+
+			consumeFields(t, value, func(num protowire.Number, value []byte) {
+				switch num {
+				case 2:
+					lines = append(lines, string(value))
+				case 3:
+					consumeFields(t, value, func(_ protowire.Number, value []byte) {
+						metadata = append(metadata, string(value))
+					})
+				}
+			})
+		})
+	})
+
+	assert.Equal(t, []string{`{level="info", service_name="Default"}`}, labels)
+	assert.Equal(t, []string{"test message 1", "test message 2"}, lines)
+	assert.Equal(t, []string{"key1", "val1", "span_id", "00000000000", "trace_id", "1234567890"}, metadata[:6])
+}
+
+func TestExportLogsLoki_Rejected(t *testing.T) {
+	exp := logExporter.LokiExporter{}
+
+	server := startTestLoki(t, http.StatusTooManyRequests, "ingestion rate limit exceeded", &testLokiRequest{})
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{"lokiEndpoint": server.URL})
+	assert.Equal(t, true, utils.IsRetryable(err))
+
+	var statusErr *logExporter.HTTPStatusError
+	assert.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, 3*time.Second, statusErr.RetryAfter)
+
+	server = startTestLoki(t, http.StatusBadRequest, "entry with timestamp 2025-03-10 has been rejected: out of order", &testLokiRequest{})
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"lokiEndpoint": server.URL})
+	assert.EqualError(t, err, "loki rejected out of order entries: unexpected status code 400: entry with timestamp 2025-03-10 has been rejected: out of order")
+	assert.Equal(t, false, utils.IsRetryable(err))
+}
+
+func TestExportLogsLoki_InvalidConfig(t *testing.T) {
+	exp := logExporter.LokiExporter{}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{})
+	assert.EqualError(t, err, "no lokiEndpoint in config")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"lokiEndpoint": "http://localhost", "lokiLabels": "service,trace"})
+	assert.EqualError(t, err, `unsupported label "trace" in lokiLabels`)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"lokiEndpoint": "http://localhost", "lokiEncoding": "zstd"})
+	assert.EqualError(t, err, `unsupported lokiEncoding "zstd"`)
+}
@@ -0,0 +1,172 @@
+package logExporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"otellogger/otel"
+	"otellogger/utils"
+	"sync"
+	"time"
+)
+
+// when a MultiExporter reports an error
+type MultiPolicy int
+
+const (
+	// fail if any of the exporters fails (default)
+	FailAny MultiPolicy = iota
+	// fail only if all of the exporters fail
+	FailAll
+)
+
+// export to several exporters at the same time (e.g. console and a collector)
+//
+// every exporter gets the same config, the errors of the failed ones are
+// joined with errors.Join so errors.Is/As still find them
+type MultiExporter struct {
+	Exporters []MultiChild
+	Policy    MultiPolicy
+	// how long to wait for each exporter without its own timeout, 0 to wait
+	// as long as it takes
+	Timeout time.Duration
+}
+
+// an exporter of a MultiExporter
+//
+// exporters implementing ContextExporter get a context that is cancelled at
+// the timeout. any other exporter can't be cancelled, one that times out
+// keeps running in the background and its error is lost
+type MultiChild struct {
+	Exporter LogExporter
+	// how long to wait for this exporter, 0 to use MultiExporter.Timeout
+	Timeout time.Duration
+}
+
+// create a multi exporter failing if any of the exporters fails
+func NewMultiExporter(exporters ...LogExporter) *MultiExporter {
+	children := make([]MultiChild, len(exporters))
+	for i, exporter := range exporters {
+		children[i] = MultiChild{Exporter: exporter}
+	}
+
+	return &MultiExporter{Exporters: children}
+}
+
+// export the logs with all exporters
+func (exp *MultiExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	return exp.export(func(ctx context.Context, child LogExporter) error {
+		return exportLogsContext(ctx, child, traceID, logs, config)
+	})
+}
+
+// export the transaction with all exporters, as a transaction to the ones that support it
+func (exp *MultiExporter) ExportTransaction(transactionLog *otel.TransactionLog, config map[string]string) error {
+	return exp.export(func(ctx context.Context, child LogExporter) error {
+		return exportTransactionContext(ctx, child, transactionLog, config)
+	})
+}
+
+// flush all exporters
+func (exp *MultiExporter) Flush(ctx context.Context) error {
+	return flushAll(ctx, exp.exporters()...)
+}
+
+// shut down all exporters
+func (exp *MultiExporter) Shutdown(ctx context.Context) error {
+	return shutdownAll(ctx, exp.exporters()...)
+}
+
+func (exp *MultiExporter) exporters() []LogExporter {
+	exporters := make([]LogExporter, len(exp.Exporters))
+	for i, child := range exp.Exporters {
+		exporters[i] = child.Exporter
+	}
+	return exporters
+}
+
+// run the export on every exporter concurrently and apply the policy to the errors
+func (exp *MultiExporter) export(export func(ctx context.Context, child LogExporter) error) error {
+	errs := make([]error, len(exp.Exporters))
+
+	var wg sync.WaitGroup
+	for i, child := range exp.Exporters {
+		wg.Add(1)
+
+		go func(i int, child MultiChild) {
+			defer wg.Done()
+
+			timeout := child.Timeout
+			if timeout <= 0 {
+				timeout = exp.Timeout
+			}
+
+			err := exportWithTimeout(child.Exporter, timeout, export)
+			if err != nil {
+				errs[i] = fmt.Errorf("exporter %d (%T): %w", i, child.Exporter, err)
+			}
+		}(i, child)
+	}
+
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+
+	if failed == 0 || (exp.Policy == FailAll && failed < len(exp.Exporters)) {
+		return nil
+	}
+
+	return errors.Join(errs...)
+}
+
+// run the export, cancelling its context and giving up waiting for it after the timeout
+func exportWithTimeout(child LogExporter, timeout time.Duration, export func(ctx context.Context, child LogExporter) error) error {
+	if timeout <= 0 {
+		return export(context.Background(), child)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// buffered so the export can finish after we stopped waiting
+	done := make(chan error, 1)
+	go func() {
+		done <- export(ctx, child)
+	}()
+
+	timedOut := &utils.RetryableError{Err: fmt.Errorf("export timed out after %s", timeout)}
+
+	select {
+	case err := <-done:
+		// an exporter giving up because of the context timed out as well
+		if err != nil && ctx.Err() != nil {
+			return timedOut
+		}
+		return err
+	case <-ctx.Done():
+		return timedOut
+	}
+}
+
+// export the logs with ExportLogsContext if the exporter supports it
+func exportLogsContext(ctx context.Context, exp LogExporter, traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	if ctxExp, ok := exp.(ContextExporter); ok {
+		return ctxExp.ExportLogsContext(ctx, traceID, logs, config)
+	}
+
+	return exp.ExportLogs(traceID, logs, config)
+}
+
+// export the transaction with ExportTransactionContext if the exporter supports it
+func exportTransactionContext(ctx context.Context, exp LogExporter, transactionLog *otel.TransactionLog, config map[string]string) error {
+	if ctxExp, ok := exp.(ContextExporter); ok {
+		return ctxExp.ExportTransactionContext(ctx, transactionLog, config)
+	}
+
+	return exportTransaction(exp, transactionLog, config)
+}
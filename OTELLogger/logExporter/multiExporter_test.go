@@ -0,0 +1,188 @@
+package logExporter_test
+
+import (
+	"errors"
+	"otellogger/logExporter"
+	"otellogger/otel"
+	"otellogger/utils"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// exporter recording its exports, failing with err and taking delay for each of them
+type testExporter struct {
+	mu      sync.Mutex
+	err     error
+	delay   time.Duration
+	exports []string // trace IDs of the exports
+}
+
+func (exp *testExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	time.Sleep(exp.delay)
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	exp.exports = append(exp.exports, traceID)
+	return exp.err
+}
+
+func (exp *testExporter) setErr(err error) {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	exp.err = err
+}
+
+func (exp *testExporter) count() int {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	return len(exp.exports)
+}
+
+// test exporter also taking whole transactions
+type testTransactionExporter struct {
+	testExporter
+	transactions []*otel.TransactionLog
+}
+
+func (exp *testTransactionExporter) ExportTransaction(transactionLog *otel.TransactionLog, config map[string]string) error {
+	exp.mu.Lock()
+	exp.transactions = append(exp.transactions, transactionLog)
+	exp.mu.Unlock()
+
+	return exp.ExportLogs(transactionLog.TraceID, transactionLog.Spans, config)
+}
+
+func TestExportLogsMulti(t *testing.T) {
+	t.Run("Export logs to multiple exporters successful", TestExportLogsMulti_Success)
+	t.Run("Export logs to multiple exporters concurrently successful", TestExportLogsMulti_Concurrent)
+	t.Run("Error exporting logs to multiple exporters - fail any", TestExportLogsMulti_FailAny)
+	t.Run("Error exporting logs to multiple exporters - fail all", TestExportLogsMulti_FailAll)
+	t.Run("Error exporting logs to multiple exporters - timeout", TestExportLogsMulti_Timeout)
+	t.Run("Error exporting logs to multiple exporters - timeout per exporter", TestExportLogsMulti_ChildTimeout)
+	t.Run("Error exporting logs to multiple exporters - timeout cancels the export", TestExportLogsMulti_TimeoutContext)
+}
+
+func TestExportLogsMulti_Success(t *testing.T) {
+	first := &testExporter{}
+	second := &testTransactionExporter{}
+
+	exp := logExporter.NewMultiExporter(first, second)
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"1234567890"}, first.exports)
+	assert.Equal(t, []string{"1234567890"}, second.exports)
+
+	// exporters taking transactions get the whole transaction
+	transaction := createTestTransaction()
+	err = exp.ExportTransaction(transaction, nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, first.count())
+	assert.Equal(t, []*otel.TransactionLog{transaction}, second.transactions)
+}
+
+func TestExportLogsMulti_Concurrent(t *testing.T) {
+	exporters := []logExporter.LogExporter{
+		&testExporter{delay: 100 * time.Millisecond},
+		&testExporter{delay: 100 * time.Millisecond},
+		&testExporter{delay: 100 * time.Millisecond},
+	}
+
+	start := time.Now()
+	err := logExporter.NewMultiExporter(exporters...).ExportLogs("1234567890", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+	assert.Less(t, time.Since(start), 250*time.Millisecond)
+}
+
+func TestExportLogsMulti_FailAny(t *testing.T) {
+	errDisk := errors.New("disk full")
+	errDown := &utils.RetryableError{Err: errors.New("collector down")}
+
+	ok := &testExporter{}
+	exp := logExporter.NewMultiExporter(&testExporter{err: errDisk}, ok, &testExporter{err: errDown})
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.ErrorIs(t, err, errDisk)
+	assert.ErrorIs(t, err, errDown)
+	assert.Equal(t, true, utils.IsRetryable(err))
+	assert.Contains(t, err.Error(), "exporter 0 (*logExporter_test.testExporter): disk full")
+	assert.Contains(t, err.Error(), "exporter 2 (*logExporter_test.testExporter): collector down")
+
+	// the other exporters still got the logs
+	assert.Equal(t, 1, ok.count())
+}
+
+func TestExportLogsMulti_FailAll(t *testing.T) {
+	failing := &testExporter{err: errors.New("disk full")}
+	ok := &testExporter{}
+
+	exp := &logExporter.MultiExporter{
+		Exporters: []logExporter.MultiChild{{Exporter: failing}, {Exporter: ok}},
+		Policy:    logExporter.FailAll,
+	}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+
+	ok.setErr(errors.New("collector down"))
+	err = exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.EqualError(t, err, "exporter 0 (*logExporter_test.testExporter): disk full\n"+
+		"exporter 1 (*logExporter_test.testExporter): collector down")
+}
+
+func TestExportLogsMulti_Timeout(t *testing.T) {
+	slow := &testExporter{delay: time.Second}
+	fast := &testExporter{}
+
+	exp := &logExporter.MultiExporter{
+		Exporters: []logExporter.MultiChild{{Exporter: slow}, {Exporter: fast}},
+		Timeout:   50 * time.Millisecond,
+	}
+
+	start := time.Now()
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.EqualError(t, err, "exporter 0 (*logExporter_test.testExporter): export timed out after 50ms")
+	assert.Equal(t, true, utils.IsRetryable(err))
+	assert.Equal(t, 1, fast.count())
+}
+
+func TestExportLogsMulti_ChildTimeout(t *testing.T) {
+	slow := &testExporter{delay: 200 * time.Millisecond}
+	slower := &testExporter{delay: time.Second}
+
+	exp := &logExporter.MultiExporter{
+		Exporters: []logExporter.MultiChild{
+			{Exporter: slow, Timeout: 500 * time.Millisecond},
+			{Exporter: slower},
+		},
+		Timeout: 50 * time.Millisecond,
+	}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.EqualError(t, err, "exporter 1 (*logExporter_test.testExporter): export timed out after 50ms")
+	assert.Equal(t, 1, slow.count())
+}
+
+func TestExportLogsMulti_TimeoutContext(t *testing.T) {
+	failing := &testExporter{err: &utils.RetryableError{Err: errors.New("collector down")}}
+	retry := &logExporter.RetryExporter{Exporter: failing, InitialInterval: 20 * time.Millisecond, Jitter: -1}
+
+	exp := &logExporter.MultiExporter{
+		Exporters: []logExporter.MultiChild{{Exporter: retry, Timeout: 50 * time.Millisecond}},
+	}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.EqualError(t, err, "exporter 0 (*logExporter.RetryExporter): export timed out after 50ms")
+
+	// the retries stopped with the timeout
+	attempts := failing.count()
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, attempts, failing.count())
+}
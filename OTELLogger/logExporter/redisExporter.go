@@ -0,0 +1,194 @@
+package logExporter
+
+import (
+	"net"
+	"otellogger/otel"
+	"otellogger/utils"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// export logs to redis streams, one stream per service
+//
+// each log is added with XADD <prefix><service name> MAXLEN ~ <max length> *
+// and the fields timestamp, severity, message, logger, service, trace_id,
+// span_id plus attr.<key> for the attributes. all logs of an export are
+// pipelined, if the connection is lost the export is sent again on a new
+// connection so a log can be added twice
+//
+// config keys:
+//   - redisNetwork: "tcp" (default) or "unix"
+//   - redisAddress: host:port or socket path (required)
+//   - redisUsername, redisPassword: AUTH credentials (only the password for redis < 6)
+//   - redisDB: database number to SELECT
+//   - redisStreamPrefix: prefix of the stream keys (default "logs:")
+//   - redisMaxLen: approximate maximum length of the streams (default 100000, 0 to not trim)
+//   - redisTimeout: timeout for connecting and each pipeline, e.g. "5s" (default 10s)
+type RedisExporter struct {
+	mu   sync.Mutex
+	conn streamConn[*respConn]
+}
+
+const (
+	defaultRedisStreamPrefix = "logs:"
+	defaultRedisMaxLen       = 100000
+	defaultRedisTimeout      = 10 * time.Second
+)
+
+// export each log from a transaction as a stream entry
+func (exp *RedisExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	// check if there are no logs to export
+	if len(logs) == 0 {
+		return nil
+	}
+
+	address, err := configValue(config, "redisAddress")
+	if err != nil {
+		return err
+	}
+
+	network := config["redisNetwork"]
+	if network == "" {
+		network = "tcp"
+	}
+	if network != "tcp" && network != "unix" {
+		return configError("redisNetwork", "unsupported redisNetwork %q", network)
+	}
+
+	maxLen, err := configInt(config, "redisMaxLen", defaultRedisMaxLen)
+	if err != nil {
+		return err
+	}
+
+	timeout, err := configDuration(config, "redisTimeout", defaultRedisTimeout)
+	if err != nil {
+		return err
+	}
+
+	prefix, ok := config["redisStreamPrefix"]
+	if !ok {
+		prefix = defaultRedisStreamPrefix
+	}
+
+	commands := make([][]string, 0, len(logs))
+	for _, log := range logs {
+		commands = append(commands, redisXAdd(prefix+log.ServiceName, maxLen, log))
+	}
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	var replies []any
+	err = exp.conn.write(network, address, func() (*respConn, error) {
+		return redisDial(network, address, config, timeout)
+	}, func(conn *respConn) (int, error) {
+		conn.conn.SetDeadline(time.Now().Add(timeout))
+
+		written, err := conn.send(commands)
+		if err != nil {
+			return written, err
+		}
+
+		replies, err = conn.receive(len(commands))
+		return written, err
+	})
+	if err != nil {
+		return redisError(err)
+	}
+
+	return redisError(respReplyError(replies))
+}
+
+// close the connection to redis
+func (exp *RedisExporter) Close() error {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	return exp.conn.Close()
+}
+
+// connect and authenticate, keeping the connection for the next exports
+func redisDial(network, address string, config map[string]string, timeout time.Duration) (*respConn, error) {
+	netConn, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := newRESPConn(netConn)
+
+	var setup [][]string
+	if password := config["redisPassword"]; password != "" {
+		if username := config["redisUsername"]; username != "" {
+			setup = append(setup, []string{"AUTH", username, password})
+		} else {
+			setup = append(setup, []string{"AUTH", password})
+		}
+	}
+	if db := config["redisDB"]; db != "" {
+		setup = append(setup, []string{"SELECT", db})
+	}
+
+	if len(setup) > 0 {
+		netConn.SetDeadline(time.Now().Add(timeout))
+
+		replies, err := conn.pipeline(setup)
+		if err == nil {
+			err = respReplyError(replies)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// mark the errors worth retrying, error replies are only retryable if
+// the server is temporarily unavailable
+func redisError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if replyErr, ok := err.(respError); ok {
+		if replyErr.retryable() {
+			return &utils.RetryableError{Err: err}
+		}
+		return err
+	}
+
+	// network errors
+	return &utils.RetryableError{Err: err}
+}
+
+func redisXAdd(stream string, maxLen int, log *otel.OTelLog) []string {
+	command := []string{"XADD", stream}
+	if maxLen > 0 {
+		// ~ lets redis trim whole nodes, which is a lot cheaper than exact trimming
+		command = append(command, "MAXLEN", "~", strconv.Itoa(maxLen))
+	}
+
+	command = append(command, "*",
+		"timestamp", log.Timestamp,
+		"severity", log.Severity,
+		"message", log.Message,
+		"logger", log.LoggerName,
+		"service", log.ServiceName,
+		"trace_id", log.TraceID,
+		"span_id", log.SpanID,
+	)
+
+	keys := make([]string, 0, len(log.Attributes))
+	for key := range log.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		command = append(command, "attr."+key, log.Attributes[key])
+	}
+
+	return command
+}
@@ -0,0 +1,300 @@
+package logExporter_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"otellogger/logExporter"
+	"otellogger/utils"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// in-process stand-in for redis speaking just enough RESP for the exporter
+type testRedis struct {
+	mu          sync.Mutex
+	password    string
+	commands    [][]string
+	connections int
+	dropAfter   int // close the first connection after this many XADDs without answering
+	loading     bool
+}
+
+func startTestRedis(t *testing.T, redis *testRedis) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			redis.mu.Lock()
+			redis.connections++
+			first := redis.connections == 1
+			redis.mu.Unlock()
+
+			go redis.serve(conn, first)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func (redis *testRedis) serve(conn net.Conn, first bool) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	authenticated := redis.password == ""
+	added := 0
+
+	for {
+		command, err := readTestRESPCommand(reader)
+		if err != nil {
+			return
+		}
+
+		redis.mu.Lock()
+		redis.commands = append(redis.commands, command)
+		redis.mu.Unlock()
+
+		var reply string
+		switch {
+		case command[0] == "AUTH":
+			if command[len(command)-1] != redis.password {
+				reply = "-WRONGPASS invalid username-password pair or user is disabled.\r\n"
+				break
+			}
+			authenticated = true
+			reply = "+OK\r\n"
+		case !authenticated:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case command[0] == "SELECT":
+			reply = "+OK\r\n"
+		case command[0] == "XADD" && redis.loading:
+			reply = "-LOADING Redis is loading the dataset in memory\r\n"
+		case command[0] == "XADD" && command[1] == "logs:Broken":
+			reply = "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+		case command[0] == "XADD":
+			added++
+			if first && added > redis.dropAfter && redis.dropAfter > 0 {
+				return
+			}
+			id := fmt.Sprintf("1741622400000-%d", added)
+			reply = fmt.Sprintf("$%d\r\n%s\r\n", len(id), id)
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+
+		_, err = io.WriteString(conn, reply)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func readTestRESPCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+
+	command := make([]string, count)
+	for i := range command {
+		line, err = reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+
+		data := make([]byte, size+2)
+		_, err = io.ReadFull(reader, data)
+		if err != nil {
+			return nil, err
+		}
+		command[i] = string(data[:size])
+	}
+
+	return command, nil
+}
+
+func (redis *testRedis) xadds() [][]string {
+	redis.mu.Lock()
+	defer redis.mu.Unlock()
+
+	var xadds [][]string
+	for _, command := range redis.commands {
+		if command[0] == "XADD" {
+			xadds = append(xadds, command)
+		}
+	}
+	return xadds
+}
+
+func TestExportLogsRedis(t *testing.T) {
+	t.Run("Export logs to redis streams successful", TestExportLogsRedis_XAdd)
+	t.Run("Export logs to redis with auth successful", TestExportLogsRedis_Auth)
+	t.Run("Export logs to redis after lost connection successful", TestExportLogsRedis_Reconnect)
+	t.Run("Error exporting logs to redis - error replies", TestExportLogsRedis_ErrorReply)
+	t.Run("Error exporting logs to redis - invalid config", TestExportLogsRedis_InvalidConfig)
+}
+
+func TestExportLogsRedis_XAdd(t *testing.T) {
+	redis := &testRedis{}
+	address := startTestRedis(t, redis)
+
+	exp := &logExporter.RedisExporter{}
+	defer exp.Close()
+
+	err := exp.ExportLogs("1234567890", nil, nil)
+	assert.Equal(t, nil, err)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"redisAddress": address})
+	assert.Equal(t, nil, err)
+
+	xadds := redis.xadds()
+	if !assert.Len(t, xadds, 2) {
+		return
+	}
+	assert.Equal(t, []string{
+		"XADD", "logs:Default", "MAXLEN", "~", "100000", "*",
+		"timestamp", "10.03.2025 17:00:00",
+		"severity", "INFO",
+		"message", "test message 1",
+		"logger", utils.LoggerName,
+		"service", utils.ServiceName,
+		"trace_id", "1234567890",
+		"span_id", "00000000000",
+		"attr.key1", "val1",
+	}, xadds[0])
+
+	// streams are keyed by service and trimming can be turned off
+	logs := createTestLog()
+	logs[1].ServiceName = "billing"
+
+	err = exp.ExportLogs("1234567890", logs, map[string]string{
+		"redisAddress":      address,
+		"redisStreamPrefix": "otel:",
+		"redisMaxLen":       "0",
+	})
+	assert.Equal(t, nil, err)
+
+	xadds = redis.xadds()
+	assert.Equal(t, []string{"XADD", "otel:Default", "*"}, xadds[2][:3])
+	assert.Equal(t, []string{"XADD", "otel:billing", "*"}, xadds[3][:3])
+
+	// the connection is reused
+	assert.Equal(t, 1, redis.connections)
+}
+
+func TestExportLogsRedis_Auth(t *testing.T) {
+	redis := &testRedis{password: "secret"}
+	address := startTestRedis(t, redis)
+
+	exp := &logExporter.RedisExporter{}
+	defer exp.Close()
+
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{
+		"redisAddress":  address,
+		"redisUsername": "logger",
+		"redisPassword": "secret",
+		"redisDB":       "2",
+	})
+	assert.Equal(t, nil, err)
+
+	redis.mu.Lock()
+	assert.Equal(t, []string{"AUTH", "logger", "secret"}, redis.commands[0])
+	assert.Equal(t, []string{"SELECT", "2"}, redis.commands[1])
+	redis.mu.Unlock()
+	assert.Len(t, redis.xadds(), 2)
+
+	// a wrong password isn't worth retrying
+	other := &logExporter.RedisExporter{}
+	defer other.Close()
+
+	err = other.ExportLogs("1234567890", createTestLog(), map[string]string{"redisAddress": address, "redisPassword": "wrong"})
+	assert.ErrorContains(t, err, "WRONGPASS")
+	assert.Equal(t, false, utils.IsRetryable(err))
+}
+
+func TestExportLogsRedis_Reconnect(t *testing.T) {
+	redis := &testRedis{dropAfter: 1}
+	address := startTestRedis(t, redis)
+
+	exp := &logExporter.RedisExporter{}
+	defer exp.Close()
+
+	// the pipeline was sent, so it isn't sent again on its own
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{"redisAddress": address})
+	assert.Equal(t, true, utils.IsRetryable(err))
+	assert.Equal(t, 1, redis.connections)
+
+	// exporting again goes over a new connection
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"redisAddress": address})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, redis.connections)
+	assert.GreaterOrEqual(t, len(redis.xadds()), 3)
+
+	// unreachable servers are retryable
+	exp.Close()
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"redisAddress": "127.0.0.1:1"})
+	assert.Equal(t, true, utils.IsRetryable(err))
+}
+
+func TestExportLogsRedis_ErrorReply(t *testing.T) {
+	redis := &testRedis{}
+	address := startTestRedis(t, redis)
+
+	exp := &logExporter.RedisExporter{}
+	defer exp.Close()
+
+	// the other logs are still added
+	logs := createTestLog()
+	logs[0].ServiceName = "Broken"
+
+	err := exp.ExportLogs("1234567890", logs, map[string]string{"redisAddress": address})
+	assert.EqualError(t, err, "redis: WRONGTYPE Operation against a key holding the wrong kind of value")
+	assert.Equal(t, false, utils.IsRetryable(err))
+	assert.Len(t, redis.xadds(), 2)
+
+	redis.mu.Lock()
+	redis.loading = true
+	redis.mu.Unlock()
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"redisAddress": address})
+	assert.ErrorContains(t, err, "LOADING")
+	assert.Equal(t, true, utils.IsRetryable(err))
+}
+
+func TestExportLogsRedis_InvalidConfig(t *testing.T) {
+	exp := &logExporter.RedisExporter{}
+	defer exp.Close()
+
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{})
+	assert.EqualError(t, err, "no redisAddress in config")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"redisAddress": "127.0.0.1:6379", "redisNetwork": "udp"})
+	assert.EqualError(t, err, `unsupported redisNetwork "udp"`)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"redisAddress": "127.0.0.1:6379", "redisMaxLen": "lots"})
+	assert.ErrorContains(t, err, "invalid redisMaxLen in config")
+}
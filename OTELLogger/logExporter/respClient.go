@@ -0,0 +1,163 @@
+package logExporter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// minimal client for the redis protocol (RESP2), just enough to pipeline
+// commands and read their replies without pulling in a redis driver
+
+// error reply sent by the server (e.g. "WRONGTYPE Operation against a key...")
+type respError string
+
+func (e respError) Error() string {
+	return "redis: " + string(e)
+}
+
+// error replies that go away by themselves (server starting, failover...)
+func (e respError) retryable() bool {
+	switch strings.SplitN(string(e), " ", 2)[0] {
+	case "LOADING", "BUSY", "TRYAGAIN", "CLUSTERDOWN", "MASTERDOWN":
+		return true
+	default:
+		return false
+	}
+}
+
+type respConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newRESPConn(conn net.Conn) *respConn {
+	return &respConn{conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// send all commands in one write and read a reply for each of them, error
+// replies are returned in the replies so a failing command doesn't hide the
+// result of the others
+func (c *respConn) pipeline(commands [][]string) ([]any, error) {
+	_, err := c.send(commands)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.receive(len(commands))
+}
+
+// write the commands in one write, returns how much of it was written
+func (c *respConn) send(commands [][]string) (int, error) {
+	var buf []byte
+	for _, command := range commands {
+		buf = appendRESPCommand(buf, command)
+	}
+
+	return c.conn.Write(buf)
+}
+
+// read the replies to count commands
+func (c *respConn) receive(count int) ([]any, error) {
+	replies := make([]any, count)
+	for i := range replies {
+		var err error
+		replies[i], err = c.readReply()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return replies, nil
+}
+
+// encode a command as an array of bulk strings
+func appendRESPCommand(buf []byte, command []string) []byte {
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(command)), 10)
+	buf = append(buf, '\r', '\n')
+
+	for _, arg := range command {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+
+	return buf
+}
+
+// read a reply: string, respError, int64, nil (null bulk/array) or []any
+func (c *respConn) readReply() (any, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("invalid redis reply %q", line)
+	}
+
+	kind, value := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return value, nil
+	case '-':
+		return respError(value), nil
+	case ':':
+		return strconv.ParseInt(value, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis bulk length %q", value)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+
+		data := make([]byte, size+2)
+		_, err = io.ReadFull(c.reader, data)
+		if err != nil {
+			return nil, err
+		}
+
+		return string(data[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis array length %q", value)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+
+		items := make([]any, count)
+		for i := range items {
+			items[i], err = c.readReply()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unknown redis reply type %q", kind)
+	}
+}
+
+// first error reply, if any
+func respReplyError(replies []any) error {
+	for _, reply := range replies {
+		if err, ok := reply.(respError); ok {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *respConn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,174 @@
+package logExporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"otellogger/otel"
+	"otellogger/utils"
+	"time"
+)
+
+// retry failed exports of another exporter with exponential backoff
+//
+// only errors marked as retryable (utils.RetryableError) are retried, a
+// HTTPStatusError with a Retry-After delay waits at least that long. the zero
+// values of the settings mean their defaults
+type RetryExporter struct {
+	Exporter LogExporter
+
+	// wait before the first retry (default 500ms), multiplied by Multiplier
+	// (default 2) after each retry up to MaxInterval (default 30s)
+	InitialInterval time.Duration
+	Multiplier      float64
+	MaxInterval     time.Duration
+	// randomize each wait by +/- this fraction (default 0.5, negative for no jitter)
+	Jitter float64
+	// give up once this much time has passed since the first attempt (default 2m)
+	MaxElapsedTime time.Duration
+	// give up after this many attempts, 0 for no limit
+	MaxAttempts int
+}
+
+const (
+	defaultRetryInitialInterval = 500 * time.Millisecond
+	defaultRetryMultiplier      = 2
+	defaultRetryMaxInterval     = 30 * time.Second
+	defaultRetryJitter          = 0.5
+	defaultRetryMaxElapsedTime  = 2 * time.Minute
+)
+
+// returned when a RetryExporter gives up, with the error of the last attempt
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("giving up after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// create a retry exporter with the default backoff
+func NewRetryExporter(exporter LogExporter) *RetryExporter {
+	return &RetryExporter{Exporter: exporter}
+}
+
+// export the logs, retrying retryable failures
+func (exp *RetryExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	return exp.ExportLogsContext(context.Background(), traceID, logs, config)
+}
+
+// export the transaction, retrying retryable failures
+func (exp *RetryExporter) ExportTransaction(transactionLog *otel.TransactionLog, config map[string]string) error {
+	return exp.ExportTransactionContext(context.Background(), transactionLog, config)
+}
+
+// same as ExportLogs, but stops retrying once the context is done
+func (exp *RetryExporter) ExportLogsContext(ctx context.Context, traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	return exp.retry(ctx, func() error {
+		return exp.Exporter.ExportLogs(traceID, logs, config)
+	})
+}
+
+// same as ExportTransaction, but stops retrying once the context is done
+func (exp *RetryExporter) ExportTransactionContext(ctx context.Context, transactionLog *otel.TransactionLog, config map[string]string) error {
+	return exp.retry(ctx, func() error {
+		return exportTransaction(exp.Exporter, transactionLog, config)
+	})
+}
+
+// flush the wrapped exporter
+func (exp *RetryExporter) Flush(ctx context.Context) error {
+	return FlushExporter(ctx, exp.Exporter)
+}
+
+// shut down the wrapped exporter
+func (exp *RetryExporter) Shutdown(ctx context.Context) error {
+	return ShutdownExporter(ctx, exp.Exporter)
+}
+
+func (exp *RetryExporter) retry(ctx context.Context, export func() error) error {
+	start := time.Now()
+	interval := exp.initialInterval()
+	maxElapsed := exp.maxElapsedTime()
+
+	for attempt := 1; ; attempt++ {
+		err := export()
+		if err == nil || !utils.IsRetryable(err) {
+			return err
+		}
+
+		if exp.MaxAttempts > 0 && attempt >= exp.MaxAttempts {
+			return &RetryError{Attempts: attempt, Err: err}
+		}
+
+		wait := exp.jitter(interval)
+
+		// the backend knows best when it will be back
+		var statusErr *HTTPStatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > wait {
+			wait = statusErr.RetryAfter
+		}
+
+		if time.Since(start)+wait > maxElapsed {
+			return &RetryError{Attempts: attempt, Err: err}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+
+		interval = min(time.Duration(float64(interval)*exp.multiplier()), exp.maxInterval())
+	}
+}
+
+// randomize the interval so exporters that failed together don't retry together
+func (exp *RetryExporter) jitter(interval time.Duration) time.Duration {
+	jitter := exp.Jitter
+	if jitter == 0 {
+		jitter = defaultRetryJitter
+	}
+	if jitter < 0 {
+		return interval
+	}
+
+	delta := jitter * float64(interval)
+	return time.Duration(float64(interval) - delta + rand.Float64()*2*delta)
+}
+
+func (exp *RetryExporter) initialInterval() time.Duration {
+	if exp.InitialInterval <= 0 {
+		return defaultRetryInitialInterval
+	}
+	return exp.InitialInterval
+}
+
+func (exp *RetryExporter) multiplier() float64 {
+	if exp.Multiplier < 1 {
+		return defaultRetryMultiplier
+	}
+	return exp.Multiplier
+}
+
+func (exp *RetryExporter) maxInterval() time.Duration {
+	if exp.MaxInterval <= 0 {
+		return defaultRetryMaxInterval
+	}
+	return exp.MaxInterval
+}
+
+func (exp *RetryExporter) maxElapsedTime() time.Duration {
+	if exp.MaxElapsedTime <= 0 {
+		return defaultRetryMaxElapsedTime
+	}
+	return exp.MaxElapsedTime
+}
@@ -0,0 +1,139 @@
+package logExporter_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"otellogger/logExporter"
+	"otellogger/otel"
+	"otellogger/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// exporter failing with the given errors one after another, then succeeding
+type testFlakyExporter struct {
+	errs     []error
+	attempts []time.Time
+}
+
+func (exp *testFlakyExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	exp.attempts = append(exp.attempts, time.Now())
+
+	if len(exp.errs) == 0 {
+		return nil
+	}
+
+	err := exp.errs[0]
+	exp.errs = exp.errs[1:]
+	return err
+}
+
+func retryable(message string) error {
+	return &utils.RetryableError{Err: errors.New(message)}
+}
+
+func TestExportLogsRetry(t *testing.T) {
+	t.Run("Export logs after retries successful", TestExportLogsRetry_Success)
+	t.Run("Export logs waiting for Retry-After successful", TestExportLogsRetry_RetryAfter)
+	t.Run("Error exporting logs with retries - not retryable", TestExportLogsRetry_NotRetryable)
+	t.Run("Error exporting logs with retries - gave up", TestExportLogsRetry_GiveUp)
+	t.Run("Error exporting logs with retries - cancelled", TestExportLogsRetry_Cancelled)
+}
+
+func TestExportLogsRetry_Success(t *testing.T) {
+	flaky := &testFlakyExporter{errs: []error{retryable("down"), retryable("still down")}}
+
+	exp := &logExporter.RetryExporter{
+		Exporter:        flaky,
+		InitialInterval: 20 * time.Millisecond,
+		Jitter:          -1,
+	}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+	if !assert.Len(t, flaky.attempts, 3) {
+		return
+	}
+
+	// the wait doubles after each retry
+	assert.GreaterOrEqual(t, flaky.attempts[1].Sub(flaky.attempts[0]), 20*time.Millisecond)
+	assert.GreaterOrEqual(t, flaky.attempts[2].Sub(flaky.attempts[1]), 40*time.Millisecond)
+
+	// transactions are passed on as transactions
+	transactionExp := &testTransactionExporter{}
+	err = logExporter.NewRetryExporter(transactionExp).ExportTransaction(createTestTransaction(), nil)
+	assert.Equal(t, nil, err)
+	assert.Len(t, transactionExp.transactions, 1)
+}
+
+func TestExportLogsRetry_RetryAfter(t *testing.T) {
+	statusErr := &logExporter.HTTPStatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 200 * time.Millisecond}
+	flaky := &testFlakyExporter{errs: []error{&utils.RetryableError{Err: statusErr}}}
+
+	exp := &logExporter.RetryExporter{Exporter: flaky, InitialInterval: time.Millisecond}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.Equal(t, nil, err)
+	if assert.Len(t, flaky.attempts, 2) {
+		assert.GreaterOrEqual(t, flaky.attempts[1].Sub(flaky.attempts[0]), 200*time.Millisecond)
+	}
+}
+
+func TestExportLogsRetry_NotRetryable(t *testing.T) {
+	errDisk := errors.New("disk full")
+	flaky := &testFlakyExporter{errs: []error{errDisk}}
+
+	err := logExporter.NewRetryExporter(flaky).ExportLogs("1234567890", createTestLog(), nil)
+	assert.Equal(t, errDisk, err)
+	assert.Len(t, flaky.attempts, 1)
+
+	// config errors are never retried
+	err = logExporter.NewRetryExporter(&logExporter.JSONExporter{}).ExportLogs("1234567890", createTestLog(), map[string]string{})
+	assert.ErrorIs(t, err, utils.ErrInvalidConfig)
+}
+
+func TestExportLogsRetry_GiveUp(t *testing.T) {
+	down := retryable("down")
+	flaky := &testFlakyExporter{errs: []error{down, down, down, down}}
+
+	exp := &logExporter.RetryExporter{Exporter: flaky, InitialInterval: time.Millisecond, MaxAttempts: 3}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.EqualError(t, err, "giving up after 3 attempts: down")
+	assert.ErrorIs(t, err, down)
+
+	var retryErr *logExporter.RetryError
+	if assert.ErrorAs(t, err, &retryErr) {
+		assert.Equal(t, 3, retryErr.Attempts)
+	}
+	assert.Len(t, flaky.attempts, 3)
+
+	// the next wait would go past the max elapsed time
+	flaky = &testFlakyExporter{errs: []error{down, down, down, down}}
+	exp = &logExporter.RetryExporter{Exporter: flaky, InitialInterval: 50 * time.Millisecond, MaxElapsedTime: 100 * time.Millisecond, Jitter: -1}
+
+	start := time.Now()
+	err = exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.EqualError(t, err, "giving up after 2 attempts: down")
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestExportLogsRetry_Cancelled(t *testing.T) {
+	down := retryable("down")
+	flaky := &testFlakyExporter{errs: []error{down, down}}
+
+	exp := &logExporter.RetryExporter{Exporter: flaky, InitialInterval: time.Second}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := exp.ExportLogsContext(ctx, "1234567890", createTestLog(), nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, err, down)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Len(t, flaky.attempts, 1)
+}
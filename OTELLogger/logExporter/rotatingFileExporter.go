@@ -0,0 +1,469 @@
+package logExporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"otellogger/otel"
+	"otellogger/utils"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// write the logs of all transactions to one file, rotated by size and/or time
+//
+// config keys:
+//   - rotatingFile: path of the current file (default filepath + filename + ".log")
+//   - rotatingFormat: "text" (same lines as TXTExporter, default) or "json" (one log per line)
+//   - rotatingMaxSize: rotate before the file grows over this many bytes (default 100MiB)
+//   - rotatingInterval: also rotate at every multiple of this duration, e.g. "1h" or
+//     "24h" for midnight UTC (default no time rotation)
+//   - rotatingCompress: "true" to gzip the rotated files
+//   - rotatingMaxFiles: keep at most this many rotated files (default all)
+//   - rotatingMaxDays: delete rotated files older than this many days (default never)
+//
+// rotated files get the time of the rotation in their name, e.g.
+// app-2025-03-10T16-59-59.000.log(.gz), only files named like that count for
+// the retention. compression and cleanup run in the background, Close and
+// Shutdown wait for them and their errors go to OnError
+type RotatingFileExporter struct {
+	// called with the errors of the background compression and cleanup
+	OnError func(err error)
+
+	mu       sync.Mutex
+	file     *os.File
+	path     string
+	size     int64
+	rotateAt time.Time // next time boundary, zero without time rotation
+
+	cleanupMu sync.Mutex
+	cleanup   sync.WaitGroup
+}
+
+const (
+	defaultRotatingMaxSize = 100 << 20
+	rotatingTimeFormat     = "2006-01-02T15-04-05.000"
+)
+
+// settings of a RotatingFileExporter read from the config
+type rotatingConfig struct {
+	path     string
+	format   string
+	maxSize  int64
+	interval time.Duration
+	compress bool
+	maxFiles int
+	maxDays  int
+}
+
+// write the logs of a transaction to the current file, rotating it first if needed
+func (exp *RotatingFileExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	// check if there are no logs to export
+	if len(logs) == 0 {
+		return nil
+	}
+
+	cfg, err := rotatingSettings(config)
+	if err != nil {
+		return err
+	}
+
+	// the whole transaction is written at once so concurrent exports don't interleave
+	var buf bytes.Buffer
+	for _, log := range logs {
+		parsedLog, err := parse(log)
+		if err != nil {
+			return err
+		}
+
+		if cfg.format == "json" {
+			buf.Write(parsedLog)
+			buf.WriteByte('\n')
+		} else {
+			fmt.Fprintf(&buf, "[%s] [%s] %s\n", log.Severity, log.Timestamp, parsedLog)
+		}
+	}
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	err = exp.open(cfg)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	timeUp := !exp.rotateAt.IsZero() && !now.Before(exp.rotateAt)
+	full := exp.size+int64(buf.Len()) > cfg.maxSize
+	if exp.size > 0 && (timeUp || full) {
+		err = exp.rotate(cfg, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	n, err := exp.file.Write(buf.Bytes())
+	exp.size += int64(n)
+
+	return err
+}
+
+// close the current file and wait for the background compression and cleanup
+func (exp *RotatingFileExporter) Close() error {
+	return exp.Shutdown(context.Background())
+}
+
+// same as Close, but stops waiting for the compression and cleanup once the
+// context is done, they finish in the background then
+func (exp *RotatingFileExporter) Shutdown(ctx context.Context) error {
+	exp.mu.Lock()
+	err := exp.closeFile()
+	exp.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		exp.cleanup.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		return errors.Join(err, ctx.Err())
+	}
+}
+
+func (exp *RotatingFileExporter) closeFile() error {
+	if exp.file == nil {
+		return nil
+	}
+
+	err := exp.file.Close()
+	exp.file = nil
+
+	return err
+}
+
+// open the current file, switching files when the config points somewhere else
+func (exp *RotatingFileExporter) open(cfg rotatingConfig) error {
+	if exp.file != nil && exp.path == cfg.path {
+		return nil
+	}
+
+	err := exp.closeFile()
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(cfg.path)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(cfg.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	exp.file = file
+	exp.path = cfg.path
+	exp.size = info.Size()
+
+	// a file left from before a restart rotates at the boundary after its last write
+	exp.rotateAt = time.Time{}
+	if cfg.interval > 0 {
+		since := time.Now()
+		if exp.size > 0 {
+			since = info.ModTime()
+		}
+		exp.rotateAt = since.Truncate(cfg.interval).Add(cfg.interval)
+	}
+
+	return nil
+}
+
+// move the current file aside and start a new one
+func (exp *RotatingFileExporter) rotate(cfg rotatingConfig, now time.Time) error {
+	err := exp.closeFile()
+	if err != nil {
+		return err
+	}
+
+	rotated := rotatedPath(cfg.path, now)
+	err = os.Rename(cfg.path, rotated)
+	if err != nil {
+		return err
+	}
+
+	// the rotated file is compressed and cleaned up even if the new one can't be opened
+	exp.cleanupRotated(cfg, rotated)
+
+	err = exp.open(cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.interval > 0 {
+		exp.rotateAt = now.Truncate(cfg.interval).Add(cfg.interval)
+	}
+
+	return nil
+}
+
+// compress a rotated file and remove old ones in the background
+func (exp *RotatingFileExporter) cleanupRotated(cfg rotatingConfig, rotated string) {
+	exp.cleanup.Add(1)
+	go func() {
+		defer exp.cleanup.Done()
+
+		exp.cleanupMu.Lock()
+		defer exp.cleanupMu.Unlock()
+
+		// a file that failed to compress is kept uncompressed and cleaned
+		// up like the others
+		if cfg.compress {
+			err := compressFile(rotated)
+			if err != nil {
+				exp.reportError(fmt.Errorf("compressing %s: %w", filepath.Base(rotated), err))
+			}
+		}
+
+		err := removeOldRotated(cfg)
+		if err != nil {
+			exp.reportError(fmt.Errorf("removing old rotated files: %w", err))
+		}
+	}()
+}
+
+func (exp *RotatingFileExporter) reportError(err error) {
+	if exp.OnError != nil {
+		exp.OnError(err)
+	}
+}
+
+// get a name for a rotated file that isn't taken yet
+func rotatedPath(path string, now time.Time) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext) + "-" + now.Format(rotatingTimeFormat)
+
+	rotated := base + ext
+	for i := 1; fileExists(rotated) || fileExists(rotated+".gz"); i++ {
+		rotated = fmt.Sprintf("%s.%d%s", base, i, ext)
+	}
+
+	return rotated
+}
+
+// gzip a rotated file, replacing it
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz.tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	err = errors.Join(err, gz.Close(), dst.Close())
+	if err != nil {
+		os.Remove(path + ".gz.tmp")
+		return err
+	}
+
+	err = os.Rename(path+".gz.tmp", path+".gz")
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// a rotated file and when it was rotated
+type rotatedFile struct {
+	path string
+	time time.Time
+	seq  int // .N suffix of files rotated in the same millisecond
+}
+
+// delete the rotated files over the max count or age, newest are kept first
+func removeOldRotated(cfg rotatingConfig) error {
+	if cfg.maxFiles <= 0 && cfg.maxDays <= 0 {
+		return nil
+	}
+
+	rotated, err := listRotated(cfg.path)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(rotated, func(i, j int) bool {
+		if !rotated[i].time.Equal(rotated[j].time) {
+			return rotated[i].time.After(rotated[j].time)
+		}
+		return rotated[i].seq > rotated[j].seq
+	})
+
+	var errs []error
+	cutoff := time.Now().AddDate(0, 0, -cfg.maxDays)
+	for i, file := range rotated {
+		tooMany := cfg.maxFiles > 0 && i >= cfg.maxFiles
+		tooOld := cfg.maxDays > 0 && file.time.Before(cutoff)
+
+		if tooMany || tooOld {
+			err := os.Remove(file.path)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// find the files rotated from path, named base-<rotation time>[.N]ext[.gz].
+// other files starting with the base name (e.g. app-backup.log) are left alone
+func listRotated(path string) ([]rotatedFile, error) {
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(path, ext) + "-"
+
+	matches, err := filepath.Glob(globEscape(prefix) + "*")
+	if err != nil {
+		return nil, err
+	}
+
+	var rotated []rotatedFile
+	for _, match := range matches {
+		name := strings.TrimSuffix(strings.TrimPrefix(match, prefix), ".gz")
+		name, ok := strings.CutSuffix(name, ext)
+		if !ok {
+			continue
+		}
+
+		// the time has a fixed length, anything after it must be the .N suffix
+		if len(name) < len(rotatingTimeFormat) {
+			continue
+		}
+		stamp, suffix := name[:len(rotatingTimeFormat)], name[len(rotatingTimeFormat):]
+
+		file := rotatedFile{path: match}
+		file.time, err = time.ParseInLocation(rotatingTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+
+		if suffix != "" {
+			seq, ok := strings.CutPrefix(suffix, ".")
+			file.seq, err = strconv.Atoi(seq)
+			if !ok || err != nil || file.seq <= 0 {
+				continue
+			}
+		}
+
+		rotated = append(rotated, file)
+	}
+
+	return rotated, nil
+}
+
+// escape the glob metacharacters of a path, so only the * added to it matches anything
+func globEscape(path string) string {
+	var escaped strings.Builder
+	for _, r := range path {
+		if strings.ContainsRune(`*?[\`, r) {
+			escaped.WriteRune('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
+
+func rotatingSettings(config map[string]string) (rotatingConfig, error) {
+	cfg := rotatingConfig{}
+
+	if config == nil {
+		return cfg, utils.ErrNoConfig
+	}
+
+	cfg.path = config["rotatingFile"]
+	if cfg.path == "" {
+		filename, ok := config["filename"]
+		if !ok {
+			return cfg, configError("rotatingFile", "no rotatingFile in config")
+		}
+		cfg.path = filepath.Join(config["filepath"], filename+".log")
+	}
+
+	cfg.format = config["rotatingFormat"]
+	switch cfg.format {
+	case "":
+		cfg.format = "text"
+	case "text", "json":
+	default:
+		return cfg, configError("rotatingFormat", "unsupported rotatingFormat %q", cfg.format)
+	}
+
+	maxSize, err := configInt(config, "rotatingMaxSize", defaultRotatingMaxSize)
+	if err != nil {
+		return cfg, err
+	}
+	if maxSize <= 0 {
+		return cfg, configError("rotatingMaxSize", "rotatingMaxSize must be positive")
+	}
+	cfg.maxSize = int64(maxSize)
+
+	cfg.interval, err = configDuration(config, "rotatingInterval", 0)
+	if err != nil {
+		return cfg, err
+	}
+	if cfg.interval < 0 {
+		return cfg, configError("rotatingInterval", "rotatingInterval must not be negative")
+	}
+
+	cfg.compress, err = configBool(config, "rotatingCompress")
+	if err != nil {
+		return cfg, err
+	}
+
+	cfg.maxFiles, err = configInt(config, "rotatingMaxFiles", 0)
+	if err != nil {
+		return cfg, err
+	}
+	if cfg.maxFiles < 0 {
+		return cfg, configError("rotatingMaxFiles", "rotatingMaxFiles must not be negative")
+	}
+
+	cfg.maxDays, err = configInt(config, "rotatingMaxDays", 0)
+	if err != nil {
+		return cfg, err
+	}
+	if cfg.maxDays < 0 {
+		return cfg, configError("rotatingMaxDays", "rotatingMaxDays must not be negative")
+	}
+
+	return cfg, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,326 @@
+package logExporter_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"otellogger/logExporter"
+	"otellogger/utils"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// get the rotated files next to the current one, oldest first
+func rotatedFiles(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "app-*"))
+	if err != nil {
+		t.Fatalf("Error listing rotated files: %v", err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func countLines(t *testing.T, path string) int {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Error opening logfile: %v", err)
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("Error reading gzip: %v", err)
+		}
+		reader = gz
+	}
+
+	lines := 0
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		lines++
+	}
+	return lines
+}
+
+func TestExportLogsRotatingFile(t *testing.T) {
+	t.Run("Export logs to rotating file successful", TestExportLogsRotatingFile_Success)
+	t.Run("Export logs rotating on size successful", TestExportLogsRotatingFile_Size)
+	t.Run("Export logs rotating on time successful", TestExportLogsRotatingFile_Time)
+	t.Run("Export logs rotating with retention successful", TestExportLogsRotatingFile_Retention)
+	t.Run("Shut down rotating file successful", TestExportLogsRotatingFile_Shutdown)
+	t.Run("Export logs to rotating file concurrently successful", TestExportLogsRotatingFile_Concurrent)
+	t.Run("Error exporting logs to rotating file - invalid config", TestExportLogsRotatingFile_ErrorConfig)
+	t.Run("Error removing old rotated file - reported to OnError", TestExportLogsRotatingFile_ErrorCleanup)
+}
+
+func TestExportLogsRotatingFile_Success(t *testing.T) {
+	dir := t.TempDir()
+	exp := &logExporter.RotatingFileExporter{}
+	defer exp.Close()
+
+	// the path defaults to filepath + filename
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{"filepath": dir, "filename": "app"})
+	assert.Equal(t, nil, err)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"filepath": dir, "filename": "app"})
+	assert.Equal(t, nil, err)
+
+	content, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2*len(createTestLog()), strings.Count(string(content), "\n"))
+	assert.True(t, strings.HasPrefix(string(content), "["+createTestLog()[0].Severity+"]"))
+
+	// one JSON log per line
+	path := filepath.Join(dir, "json", "app.log")
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"rotatingFile": path, "rotatingFormat": "json"})
+	assert.Equal(t, nil, err)
+
+	content, err = os.ReadFile(path)
+	assert.Equal(t, nil, err)
+	assert.True(t, strings.HasPrefix(string(content), `{"Timestamp":`))
+}
+
+func TestExportLogsRotatingFile_Size(t *testing.T) {
+	dir := t.TempDir()
+	exp := &logExporter.RotatingFileExporter{}
+
+	config := map[string]string{
+		"rotatingFile":     filepath.Join(dir, "app.log"),
+		"rotatingMaxSize":  "1000",
+		"rotatingCompress": "true",
+	}
+
+	for i := 0; i < 10; i++ {
+		err := exp.ExportLogs(strconv.Itoa(i), createTestLog(), config)
+		assert.Equal(t, nil, err)
+	}
+
+	err := exp.Close()
+	assert.Equal(t, nil, err)
+
+	info, err := os.Stat(filepath.Join(dir, "app.log"))
+	assert.Equal(t, nil, err)
+	assert.LessOrEqual(t, info.Size(), int64(1000))
+
+	// nothing lost, the rotated files are compressed
+	rotated := rotatedFiles(t, dir)
+	assert.Greater(t, len(rotated), 1)
+
+	lines := countLines(t, filepath.Join(dir, "app.log"))
+	for _, path := range rotated {
+		assert.True(t, strings.HasSuffix(path, ".log.gz"), path)
+		lines += countLines(t, path)
+	}
+	assert.Equal(t, 10*len(createTestLog()), lines)
+}
+
+func TestExportLogsRotatingFile_Time(t *testing.T) {
+	dir := t.TempDir()
+	exp := &logExporter.RotatingFileExporter{}
+
+	config := map[string]string{
+		"rotatingFile":     filepath.Join(dir, "app.log"),
+		"rotatingInterval": "100ms",
+	}
+
+	err := exp.ExportLogs("1", createTestLog(), config)
+	assert.Equal(t, nil, err)
+
+	time.Sleep(150 * time.Millisecond)
+
+	err = exp.ExportLogs("2", createTestLog(), config)
+	assert.Equal(t, nil, err)
+
+	exp.Close()
+
+	rotated := rotatedFiles(t, dir)
+	if assert.Len(t, rotated, 1) {
+		assert.Equal(t, len(createTestLog()), countLines(t, rotated[0]))
+	}
+	assert.Equal(t, len(createTestLog()), countLines(t, filepath.Join(dir, "app.log")))
+}
+
+func TestExportLogsRotatingFile_Retention(t *testing.T) {
+	dir := t.TempDir()
+
+	// a rotated file from long ago
+	old := filepath.Join(dir, "app-2000-01-01T00-00-00.000.log")
+	os.WriteFile(old, []byte("old\n"), 0644)
+	os.Chtimes(old, time.Now().AddDate(0, 0, -30), time.Now().AddDate(0, 0, -30))
+
+	// files that only start like the rotated ones are left alone
+	unrelated := []string{
+		filepath.Join(dir, "app-backup.log"),
+		filepath.Join(dir, "app-notes.txt"),
+		filepath.Join(dir, "app-2000-01-01T00-00-00.000.x.log"),
+	}
+	for _, path := range unrelated {
+		os.WriteFile(path, []byte("keep\n"), 0644)
+		os.Chtimes(path, time.Now().AddDate(0, 0, -30), time.Now().AddDate(0, 0, -30))
+	}
+
+	exp := &logExporter.RotatingFileExporter{}
+	config := map[string]string{
+		"rotatingFile":     filepath.Join(dir, "app.log"),
+		"rotatingMaxSize":  "1",
+		"rotatingMaxFiles": "3",
+		"rotatingMaxDays":  "7",
+	}
+
+	// every export rotates the one before
+	for i := 0; i < 6; i++ {
+		err := exp.ExportLogs(strconv.Itoa(i), createTestLog(), config)
+		assert.Equal(t, nil, err)
+	}
+
+	exp.Close()
+
+	rotated := rotatedFiles(t, dir)
+	assert.Len(t, rotated, 3+len(unrelated))
+	assert.NotContains(t, rotated, old)
+	assert.Subset(t, rotated, unrelated)
+}
+
+func TestExportLogsRotatingFile_Shutdown(t *testing.T) {
+	dir := t.TempDir()
+	exp := &logExporter.RotatingFileExporter{}
+
+	config := map[string]string{
+		"rotatingFile":     filepath.Join(dir, "app.log"),
+		"rotatingMaxSize":  "1",
+		"rotatingCompress": "true",
+	}
+
+	for i := 0; i < 3; i++ {
+		err := exp.ExportLogs(strconv.Itoa(i), createTestLog(), config)
+		assert.Equal(t, nil, err)
+	}
+
+	// the compression is done once Shutdown returns
+	err := exp.Shutdown(context.Background())
+	assert.Equal(t, nil, err)
+
+	rotated := rotatedFiles(t, dir)
+	assert.Len(t, rotated, 2)
+	for _, path := range rotated {
+		assert.True(t, strings.HasSuffix(path, ".log.gz"), path)
+	}
+
+	// flushes and closes through the lifecycle helpers too
+	err = logExporter.ShutdownExporter(context.Background(), exp)
+	assert.Equal(t, nil, err)
+}
+
+func TestExportLogsRotatingFile_Concurrent(t *testing.T) {
+	dir := t.TempDir()
+	exp := &logExporter.RotatingFileExporter{}
+
+	config := map[string]string{
+		"rotatingFile":    filepath.Join(dir, "app.log"),
+		"rotatingMaxSize": "4096",
+		"rotatingFormat":  "json",
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			err := exp.ExportLogs(strconv.Itoa(i), createTestLog(), config)
+			assert.Equal(t, nil, err)
+		}(i)
+	}
+	wg.Wait()
+	exp.Close()
+
+	// every line is a whole log
+	lines := 0
+	for _, path := range append(rotatedFiles(t, dir), filepath.Join(dir, "app.log")) {
+		content, err := os.ReadFile(path)
+		assert.Equal(t, nil, err)
+
+		for _, line := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+			assert.True(t, strings.HasPrefix(line, `{"Timestamp":`) && strings.HasSuffix(line, "}"), line)
+			lines++
+		}
+	}
+	assert.Equal(t, 50*len(createTestLog()), lines)
+}
+
+func TestExportLogsRotatingFile_ErrorConfig(t *testing.T) {
+	exp := &logExporter.RotatingFileExporter{}
+	defer exp.Close()
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.ErrorIs(t, err, utils.ErrNoConfig)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{})
+	assert.EqualError(t, err, "no rotatingFile in config")
+	assert.ErrorIs(t, err, utils.ErrInvalidConfig)
+
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"rotatingFile": path, "rotatingFormat": "xml"})
+	assert.EqualError(t, err, `unsupported rotatingFormat "xml"`)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"rotatingFile": path, "rotatingMaxSize": "0"})
+	assert.EqualError(t, err, "rotatingMaxSize must be positive")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"rotatingFile": path, "rotatingInterval": "daily"})
+	assert.ErrorIs(t, err, utils.ErrInvalidConfig)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"rotatingFile": path, "rotatingInterval": "-1h"})
+	assert.EqualError(t, err, "rotatingInterval must not be negative")
+	assert.ErrorIs(t, err, utils.ErrInvalidConfig)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"rotatingFile": path, "rotatingMaxFiles": "-1"})
+	assert.EqualError(t, err, "rotatingMaxFiles must not be negative")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"rotatingFile": path, "rotatingMaxDays": "-7"})
+	assert.EqualError(t, err, "rotatingMaxDays must not be negative")
+}
+
+func TestExportLogsRotatingFile_ErrorCleanup(t *testing.T) {
+	dir := t.TempDir()
+
+	// a directory named like an old rotated file can't be removed
+	old := filepath.Join(dir, "app-2000-01-01T00-00-00.000.log")
+	os.MkdirAll(filepath.Join(old, "keep"), 0755)
+
+	var mu sync.Mutex
+	var errs []error
+	exp := &logExporter.RotatingFileExporter{OnError: func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}}
+
+	config := map[string]string{
+		"rotatingFile":    filepath.Join(dir, "app.log"),
+		"rotatingMaxSize": "1",
+		"rotatingMaxDays": "7",
+	}
+
+	for i := 0; i < 2; i++ {
+		err := exp.ExportLogs(strconv.Itoa(i), createTestLog(), config)
+		assert.Equal(t, nil, err)
+	}
+
+	exp.Close()
+
+	if assert.Len(t, errs, 1) {
+		assert.ErrorContains(t, errs[0], "removing old rotated files")
+	}
+}
@@ -0,0 +1,599 @@
+package logExporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"otellogger/otel"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// archive transactions as objects in S3 (or any S3-compatible storage like MinIO)
+//
+// each transaction is uploaded as a json object, or with s3BatchSize several
+// transactions are rolled into one ndjson object. objects bigger than the
+// part size are sent as a multipart upload
+//
+// with batches the export returns before the transaction is uploaded, the
+// transactions of a batch that isn't full yet only live in memory and are lost
+// if the process exits before Flush or Shutdown (Logger.Flush/Shutdown call them)
+//
+// config keys (plus the shared HTTPConfig keys):
+//   - s3Endpoint: url of the storage, e.g. "https://s3.eu-central-1.amazonaws.com" (required)
+//   - s3Bucket: bucket name (required)
+//   - s3Region: region used for signing (default "us-east-1")
+//   - s3AccessKey, s3SecretKey: credentials (required), s3SessionToken for temporary ones
+//   - s3Key: object key pattern with the {service}, {logger}, {date} and {traceID}
+//     placeholders (default "{service}/{date}/{traceID}" with the extension of the
+//     format, plus ".gz" with s3Gzip)
+//   - s3DateFormat: Go layout used for {date} (default "2006/01/02")
+//   - s3PathStyle: "false" to use virtual-hosted style urls (bucket.host) instead of host/bucket
+//   - s3Gzip: compress the objects with gzip
+//   - s3BatchSize: number of transactions per object (default 1), call Flush to upload the rest
+//   - s3Format: "json" for one transaction per object or "ndjson" for a transaction
+//     per line (default "json", "ndjson" with a s3BatchSize over 1)
+//   - s3PartSize: size of the multipart upload parts in bytes (default 8MiB, S3 needs at least 5MiB)
+type S3Exporter struct {
+	HTTPConfig
+
+	mu      sync.Mutex
+	batches []*s3Batch // transactions waiting for their batch to fill up
+}
+
+// pending transactions exported with the same config
+type s3Batch struct {
+	config       map[string]string
+	transactions []*otel.TransactionLog
+}
+
+const (
+	defaultS3Region     = "us-east-1"
+	defaultS3Key        = "{service}/{date}/{traceID}"
+	defaultS3DateFormat = "2006/01/02"
+	defaultS3PartSize   = 8 << 20
+
+	s3Algorithm = "AWS4-HMAC-SHA256"
+	s3Service   = "s3"
+)
+
+// settings of the target bucket read from config
+type s3Target struct {
+	endpoint     *url.URL
+	bucket       string
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	pathStyle    bool
+}
+
+// export logs from a transaction, rebuilding the transaction from its logs
+func (exp *S3Exporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	// check if there are no logs to export
+	if len(logs) == 0 {
+		return nil
+	}
+
+	return exp.ExportTransaction(transactionFromLogs(traceID, logs), config)
+}
+
+// add the transaction to the batch and upload it once it is full
+//
+// a failed batch stays pending and is uploaded with the next export or Flush,
+// exporting the same transaction again (same trace ID) replaces the pending one.
+// a batch is uploaded with the config of its transactions, the batches of an
+// earlier config are uploaded once the config changes. if that fails they stay
+// pending without failing the exports with the new config, Flush reports it
+func (exp *S3Exporter) ExportTransaction(transactionLog *otel.TransactionLog, config map[string]string) error {
+	batchSize, err := configInt(config, "s3BatchSize", 1)
+	if err != nil {
+		return err
+	}
+
+	// check the config before anything is added to the batch
+	_, err = s3TargetFromConfig(config)
+	if err != nil {
+		return err
+	}
+
+	_, err = s3Format(config)
+	if err != nil {
+		return err
+	}
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	var batch *s3Batch
+	for _, pending := range slices.Clone(exp.batches) {
+		if maps.Equal(pending.config, config) {
+			batch = pending
+		} else {
+			// tried again with the next export or Flush
+			exp.flush(context.Background(), pending)
+		}
+	}
+	if batch == nil {
+		batch = &s3Batch{config: config}
+		exp.batches = append(exp.batches, batch)
+	}
+
+	replaced := false
+	for i, pending := range batch.transactions {
+		if pending.TraceID == transactionLog.TraceID {
+			batch.transactions[i] = transactionLog
+			replaced = true
+		}
+	}
+	if !replaced {
+		batch.transactions = append(batch.transactions, transactionLog)
+	}
+
+	if len(batch.transactions) < batchSize {
+		return nil
+	}
+
+	return exp.flush(context.Background(), batch)
+}
+
+// upload the pending transactions, even if their batch isn't full yet
+func (exp *S3Exporter) Flush(ctx context.Context) error {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	var errs []error
+	for _, batch := range slices.Clone(exp.batches) {
+		errs = append(errs, exp.flush(ctx, batch))
+	}
+
+	return errors.Join(errs...)
+}
+
+// upload the pending transactions, there is nothing else to close
+func (exp *S3Exporter) Shutdown(ctx context.Context) error {
+	return exp.Flush(ctx)
+}
+
+// upload a batch with the config its transactions were exported with and
+// drop it once it is uploaded
+func (exp *S3Exporter) flush(ctx context.Context, batch *s3Batch) error {
+	config := batch.config
+
+	target, err := s3TargetFromConfig(config)
+	if err != nil {
+		return err
+	}
+
+	partSize, err := configInt(config, "s3PartSize", defaultS3PartSize)
+	if err != nil {
+		return err
+	}
+	if partSize <= 0 {
+		return configError("s3PartSize", "s3PartSize must be positive")
+	}
+
+	compress, err := configBool(config, "s3Gzip")
+	if err != nil {
+		return err
+	}
+
+	format, err := s3Format(config)
+	if err != nil {
+		return err
+	}
+
+	// a json object has a single transaction, ndjson a transaction per line
+	var body bytes.Buffer
+	contentType := "application/json"
+	if format == "ndjson" {
+		contentType = "application/x-ndjson"
+	}
+
+	encoder := json.NewEncoder(&body)
+	for _, transactionLog := range batch.transactions {
+		err := encoder.Encode(transactionLog)
+		if err != nil {
+			return err
+		}
+	}
+
+	payload := body.Bytes()
+	if compress {
+		payload, err = gzipBytes(payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	headers := http.Header{}
+	headers.Set("Content-Type", contentType)
+	if compress {
+		headers.Set("Content-Encoding", "gzip")
+	}
+
+	// batches are named after their first transaction
+	key := s3Key(batch.transactions[0], config, format, compress)
+
+	if len(payload) > partSize {
+		err = exp.multipartUpload(ctx, target, key, payload, partSize, headers, config)
+	} else {
+		_, err = exp.s3Request(ctx, target, http.MethodPut, key, nil, payload, headers, config)
+	}
+	if err != nil {
+		return err
+	}
+
+	exp.batches = slices.DeleteFunc(exp.batches, func(pending *s3Batch) bool {
+		return pending == batch
+	})
+
+	return nil
+}
+
+// upload the payload in parts, aborting the upload if any part fails
+func (exp *S3Exporter) multipartUpload(ctx context.Context, target *s3Target, key string, payload []byte, partSize int, headers http.Header, config map[string]string) error {
+	resp, err := exp.s3Request(ctx, target, http.MethodPost, key, url.Values{"uploads": {""}}, nil, headers, config)
+	if err != nil {
+		return err
+	}
+
+	var created struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = xml.Unmarshal(resp, &created)
+	if err != nil {
+		return fmt.Errorf("invalid CreateMultipartUpload response: %w", err)
+	}
+	if created.UploadID == "" {
+		return fmt.Errorf("no UploadId in CreateMultipartUpload response")
+	}
+
+	err = exp.uploadParts(ctx, target, key, created.UploadID, payload, partSize, config)
+	if err != nil {
+		// don't leave the parts lying around (and billed) in the bucket, even
+		// when the upload failed because the context was canceled
+		exp.s3Request(context.WithoutCancel(ctx), target, http.MethodDelete, key, url.Values{"uploadId": {created.UploadID}}, nil, nil, config)
+		return err
+	}
+
+	return nil
+}
+
+func (exp *S3Exporter) uploadParts(ctx context.Context, target *s3Target, key, uploadID string, payload []byte, partSize int, config map[string]string) error {
+	type completedPart struct {
+		PartNumber int
+		ETag       string
+	}
+	complete := struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{}
+
+	for offset := 0; offset < len(payload); offset += partSize {
+		partNumber := len(complete.Parts) + 1
+		part := payload[offset:min(offset+partSize, len(payload))]
+
+		query := url.Values{
+			"partNumber": {strconv.Itoa(partNumber)},
+			"uploadId":   {uploadID},
+		}
+
+		etag, err := exp.s3PutPart(ctx, target, key, query, part, config)
+		if err != nil {
+			return fmt.Errorf("error uploading part %d: %w", partNumber, err)
+		}
+
+		complete.Parts = append(complete.Parts, completedPart{PartNumber: partNumber, ETag: etag})
+	}
+
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		return err
+	}
+
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/xml")
+
+	resp, err := exp.s3Request(ctx, target, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body, headers, config)
+	if err != nil {
+		return err
+	}
+
+	// the completion can fail after the 200 status has already been sent
+	if bytes.Contains(resp, []byte("<Error>")) {
+		return fmt.Errorf("error completing multipart upload: %s", resp)
+	}
+
+	return nil
+}
+
+// upload a part and get its ETag
+func (exp *S3Exporter) s3PutPart(ctx context.Context, target *s3Target, key string, query url.Values, part []byte, config map[string]string) (string, error) {
+	req, err := s3NewRequest(ctx, target, http.MethodPut, key, query, part, nil, config)
+	if err != nil {
+		return "", err
+	}
+
+	resp, _, err := exp.do(req, config)
+	if err != nil {
+		return "", err
+	}
+
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		return "", fmt.Errorf("no ETag in UploadPart response")
+	}
+
+	return etag, nil
+}
+
+// send a signed request to the object and return the response body
+func (exp *S3Exporter) s3Request(ctx context.Context, target *s3Target, method, key string, query url.Values, body []byte, headers http.Header, config map[string]string) ([]byte, error) {
+	req, err := s3NewRequest(ctx, target, method, key, query, body, headers, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return exp.send(req, config)
+}
+
+// build a signed request with the httpHeaders from config, they are added
+// before signing since S3 rejects x-amz-* headers (like x-amz-meta-*) that
+// aren't signed
+func s3NewRequest(ctx context.Context, target *s3Target, method, key string, query url.Values, body []byte, headers http.Header, config map[string]string) (*http.Request, error) {
+	extra, err := parsePairs(config["httpHeaders"])
+	if err != nil {
+		return nil, configError("httpHeaders", "invalid httpHeaders in config: %w", err)
+	}
+
+	headers = headers.Clone()
+	if headers == nil {
+		headers = http.Header{}
+	}
+	for name, value := range extra {
+		headers.Set(name, value)
+	}
+
+	return target.request(ctx, method, key, query, body, headers, time.Now())
+}
+
+func s3TargetFromConfig(config map[string]string) (*s3Target, error) {
+	endpoint, err := configValue(config, "s3Endpoint")
+	if err != nil {
+		return nil, err
+	}
+
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Host == "" {
+		return nil, configError("s3Endpoint", "invalid s3Endpoint %q", endpoint)
+	}
+
+	target := &s3Target{endpoint: endpointURL, region: config["s3Region"], sessionToken: config["s3SessionToken"]}
+	if target.region == "" {
+		target.region = defaultS3Region
+	}
+
+	target.bucket, err = configValue(config, "s3Bucket")
+	if err != nil {
+		return nil, err
+	}
+
+	target.accessKey, err = configValue(config, "s3AccessKey")
+	if err != nil {
+		return nil, err
+	}
+
+	target.secretKey, err = configValue(config, "s3SecretKey")
+	if err != nil {
+		return nil, err
+	}
+
+	// path style by default as most S3-compatible storages only support that
+	target.pathStyle = true
+	if value := config["s3PathStyle"]; value != "" {
+		target.pathStyle, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, configError("s3PathStyle", "invalid s3PathStyle in config: %w", err)
+		}
+	}
+
+	return target, nil
+}
+
+// build the request for an object and sign it with AWS Signature Version 4
+func (target *s3Target) request(ctx context.Context, method, key string, query url.Values, body []byte, headers http.Header, now time.Time) (*http.Request, error) {
+	objectURL := *target.endpoint
+
+	path := "/" + key
+	if target.pathStyle {
+		path = "/" + target.bucket + path
+	} else {
+		objectURL.Host = target.bucket + "." + objectURL.Host
+	}
+
+	objectURL.Path = strings.TrimSuffix(target.endpoint.Path, "/") + path
+	objectURL.RawPath = s3URIEncode(objectURL.Path, false)
+	objectURL.RawQuery = s3CanonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, objectURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+
+	s3Sign(req, body, target, now)
+
+	return req, nil
+}
+
+// add the SigV4 headers, see https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func s3Sign(req *http.Request, body []byte, target *s3Target, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/" + target.region + "/" + s3Service + "/aws4_request"
+
+	payloadHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if target.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", target.sessionToken)
+	}
+
+	// sign the host and all the content and amz headers
+	signed := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || strings.HasPrefix(lower, "content-") {
+			signed[lower] = strings.TrimSpace(req.Header.Get(name))
+		}
+	}
+
+	names := make([]string, 0, len(signed))
+	for name := range signed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + signed[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := s3Algorithm + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+target.secretKey), now.Format("20060102"))
+	signingKey = hmacSHA256(signingKey, target.region)
+	signingKey = hmacSHA256(signingKey, s3Service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", s3Algorithm+" Credential="+target.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// sorted and encoded query, keys without value keep the '='
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			pairs = append(pairs, s3URIEncode(key, true)+"="+s3URIEncode(value, true))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// percent-encode everything but the unreserved characters (and '/' in paths)
+func s3URIEncode(value string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for _, b := range []byte(value) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~':
+			encoded.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			encoded.WriteByte(b)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+
+	return encoded.String()
+}
+
+// get the object format, batches need ndjson
+func s3Format(config map[string]string) (string, error) {
+	batchSize, err := configInt(config, "s3BatchSize", 1)
+	if err != nil {
+		return "", err
+	}
+
+	format := config["s3Format"]
+	switch format {
+	case "":
+		format = "json"
+		if batchSize > 1 {
+			format = "ndjson"
+		}
+	case "ndjson":
+	case "json":
+		if batchSize > 1 {
+			return "", configError("s3Format", "s3Format json only holds one transaction, use ndjson with s3BatchSize")
+		}
+	default:
+		return "", configError("s3Format", "unsupported s3Format %q", format)
+	}
+
+	return format, nil
+}
+
+// fill in the object key pattern for a transaction, the default pattern gets
+// the extension of the format
+func s3Key(transactionLog *otel.TransactionLog, config map[string]string, format string, compress bool) string {
+	pattern := config["s3Key"]
+	if pattern == "" {
+		pattern = defaultS3Key + "." + format
+		if compress {
+			pattern += ".gz"
+		}
+	}
+
+	dateFormat := config["s3DateFormat"]
+	if dateFormat == "" {
+		dateFormat = defaultS3DateFormat
+	}
+
+	start, _ := transactionTimespan(transactionLog)
+	if start.IsZero() {
+		start = time.Now()
+	}
+
+	key := strings.NewReplacer(
+		"{service}", transactionLog.ServiceName,
+		"{logger}", transactionLog.LoggerName,
+		"{date}", start.Format(dateFormat),
+		"{traceID}", transactionLog.TraceID,
+	).Replace(pattern)
+
+	return strings.TrimPrefix(key, "/")
+}
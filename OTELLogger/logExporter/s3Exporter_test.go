@@ -0,0 +1,424 @@
+package logExporter_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"otellogger/logExporter"
+	"otellogger/otel"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// S3 stand-in checking the SigV4 signatures and keeping the objects in memory
+type testS3 struct {
+	mu        sync.Mutex
+	secretKey string
+	objects   map[string][]byte
+	headers   map[string]http.Header
+	parts     map[string]map[string][]byte
+	aborted   []string
+	requests  int
+	failPart  string // part number answered with 503
+}
+
+func startTestS3(t *testing.T, s3 *testS3) *httptest.Server {
+	s3.objects = make(map[string][]byte)
+	s3.headers = make(map[string]http.Header)
+	s3.parts = make(map[string]map[string][]byte)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s3.mu.Lock()
+		defer s3.mu.Unlock()
+		s3.requests++
+
+		body, _ := io.ReadAll(r.Body)
+		if err := verifyTestSignature(r, body, s3.secretKey); err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprintf(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>%v</Message></Error>", err)
+			return
+		}
+
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		query := r.URL.Query()
+		uploadID := query.Get("uploadId")
+
+		switch {
+		case r.Method == http.MethodPut && uploadID == "":
+			s3.objects[key] = body
+			s3.headers[key] = r.Header
+		case r.Method == http.MethodPost && query.Has("uploads"):
+			uploadID = fmt.Sprintf("upload-%d", len(s3.parts)+1)
+			s3.parts[uploadID] = make(map[string][]byte)
+			s3.headers[key] = r.Header
+			fmt.Fprintf(w, "<InitiateMultipartUploadResult><Bucket>logs</Bucket><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>", key, uploadID)
+		case r.Method == http.MethodPut:
+			if query.Get("partNumber") == s3.failPart {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			s3.parts[uploadID][query.Get("partNumber")] = body
+			w.Header().Set("ETag", `"etag-`+query.Get("partNumber")+`"`)
+		case r.Method == http.MethodPost:
+			var complete struct {
+				Parts []struct {
+					PartNumber string
+					ETag       string
+				} `xml:"Part"`
+			}
+			assert.Equal(t, nil, xml.Unmarshal(body, &complete))
+
+			var object []byte
+			for _, part := range complete.Parts {
+				assert.Equal(t, `"etag-`+part.PartNumber+`"`, part.ETag)
+				object = append(object, s3.parts[uploadID][part.PartNumber]...)
+			}
+			s3.objects[key] = object
+			delete(s3.parts, uploadID)
+			fmt.Fprint(w, "<CompleteMultipartUploadResult></CompleteMultipartUploadResult>")
+		case r.Method == http.MethodDelete:
+			s3.aborted = append(s3.aborted, uploadID)
+			delete(s3.parts, uploadID)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// recompute the signature of a request independently of the exporter
+func verifyTestSignature(r *http.Request, body []byte, secretKey string) error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 ") {
+		return fmt.Errorf("missing signature")
+	}
+
+	fields := make(map[string]string)
+	for _, field := range strings.Split(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 "), ", ") {
+		name, value, _ := strings.Cut(field, "=")
+		fields[name] = value
+	}
+
+	credential := strings.SplitN(fields["Credential"], "/", 2)
+	scope := credential[1]
+	scopeParts := strings.Split(scope, "/")
+
+	payloadHash := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(payloadHash[:]) {
+		return fmt.Errorf("payload hash mismatch")
+	}
+
+	// like S3, reject x-amz-* headers that aren't signed
+	signedHeaders := strings.Split(fields["SignedHeaders"], ";")
+	for name := range r.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") && !slices.Contains(signedHeaders, lower) {
+			return fmt.Errorf("header %s isn't signed", name)
+		}
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + value + "\n")
+	}
+
+	var query []string
+	for key, values := range r.URL.Query() {
+		for _, value := range values {
+			query = append(query, key+"="+value)
+		}
+	}
+	sort.Strings(query)
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		strings.Join(query, "&"),
+		canonicalHeaders.String(),
+		fields["SignedHeaders"],
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + r.Header.Get("X-Amz-Date") + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	sign := func(key []byte, data string) []byte {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(data))
+		return mac.Sum(nil)
+	}
+	key := []byte("AWS4" + secretKey)
+	for _, part := range scopeParts {
+		key = sign(key, part)
+	}
+
+	if hex.EncodeToString(sign(key, stringToSign)) != fields["Signature"] {
+		return fmt.Errorf("signature mismatch")
+	}
+
+	return nil
+}
+
+func testS3Config(server *httptest.Server) map[string]string {
+	return map[string]string{
+		"s3Endpoint":  server.URL,
+		"s3Bucket":    "logs",
+		"s3Region":    "eu-central-1",
+		"s3AccessKey": "AKIDEXAMPLE",
+		"s3SecretKey": "secret",
+	}
+}
+
+func gunzipTest(t *testing.T, data []byte) []byte {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Error reading gzip: %v", err)
+	}
+
+	data, err = io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Error reading gzip: %v", err)
+	}
+
+	return data
+}
+
+func TestExportLogsS3(t *testing.T) {
+	t.Run("Upload transaction to S3 successful", TestExportLogsS3_Put)
+	t.Run("Upload batch of transactions to S3 successful", TestExportLogsS3_Batch)
+	t.Run("Upload transaction to S3 in parts successful", TestExportLogsS3_Multipart)
+	t.Run("Error uploading to S3 - failed part", TestExportLogsS3_FailedPart)
+	t.Run("Error uploading to S3 - wrong credentials", TestExportLogsS3_WrongCredentials)
+	t.Run("Error uploading to S3 - invalid config", TestExportLogsS3_InvalidConfig)
+}
+
+func TestExportLogsS3_Put(t *testing.T) {
+	s3 := &testS3{secretKey: "secret"}
+	server := startTestS3(t, s3)
+
+	exp := &logExporter.S3Exporter{}
+
+	err := exp.ExportLogs("1234567890", nil, nil)
+	assert.Equal(t, nil, err)
+
+	config := testS3Config(server)
+	config["s3SessionToken"] = "token"
+	config["httpHeaders"] = "X-Amz-Meta-Team=ops,X-Team=ops"
+
+	err = exp.ExportTransaction(createTestTransaction(), config)
+	assert.Equal(t, nil, err)
+
+	object, ok := s3.objects["logs/Default/2025/03/10/1234567890.json"]
+	if !assert.Equal(t, true, ok) {
+		return
+	}
+	assert.Equal(t, "application/json", s3.headers["logs/Default/2025/03/10/1234567890.json"].Get("Content-Type"))
+	assert.Equal(t, "token", s3.headers["logs/Default/2025/03/10/1234567890.json"].Get("X-Amz-Security-Token"))
+
+	// the headers from config are signed along with the others
+	assert.Equal(t, "ops", s3.headers["logs/Default/2025/03/10/1234567890.json"].Get("X-Amz-Meta-Team"))
+	assert.Equal(t, "ops", s3.headers["logs/Default/2025/03/10/1234567890.json"].Get("X-Team"))
+
+	var transaction otel.TransactionLog
+	assert.Equal(t, nil, json.Unmarshal(object, &transaction))
+	assert.Equal(t, "1234567890", transaction.TraceID)
+	assert.Len(t, transaction.Spans, 2)
+
+	// keys with characters that need encoding are signed correctly
+	config["s3Key"] = "archive/{logger}/{date} {traceID}.json"
+	config["s3DateFormat"] = "2006-01-02"
+	err = exp.ExportLogs("1234567890", createTestLog(), config)
+	assert.Equal(t, nil, err)
+	assert.Contains(t, s3.objects, "logs/archive/OTelLogger/2025-03-10 1234567890.json")
+}
+
+func TestExportLogsS3_Batch(t *testing.T) {
+	s3 := &testS3{secretKey: "secret"}
+	server := startTestS3(t, s3)
+
+	config := testS3Config(server)
+	config["s3BatchSize"] = "2"
+	config["s3Gzip"] = "true"
+	config["s3Key"] = "{service}/batch-{traceID}.ndjson.gz"
+
+	exp := &logExporter.S3Exporter{}
+
+	first := createTestTransaction()
+	second := createTestTransaction()
+	second.TraceID = "1234567891"
+
+	// nothing is uploaded until the batch is full
+	err := exp.ExportTransaction(first, config)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, s3.requests)
+
+	err = exp.ExportTransaction(second, config)
+	assert.Equal(t, nil, err)
+
+	object, ok := s3.objects["logs/Default/batch-1234567890.ndjson.gz"]
+	if !assert.Equal(t, true, ok) {
+		return
+	}
+	assert.Equal(t, "gzip", s3.headers["logs/Default/batch-1234567890.ndjson.gz"].Get("Content-Encoding"))
+	assert.Equal(t, "application/x-ndjson", s3.headers["logs/Default/batch-1234567890.ndjson.gz"].Get("Content-Type"))
+
+	var traceIDs []string
+	scanner := bufio.NewScanner(bytes.NewReader(gunzipTest(t, object)))
+	for scanner.Scan() {
+		var transaction otel.TransactionLog
+		assert.Equal(t, nil, json.Unmarshal(scanner.Bytes(), &transaction))
+		traceIDs = append(traceIDs, transaction.TraceID)
+	}
+	assert.Equal(t, []string{"1234567890", "1234567891"}, traceIDs)
+
+	// flush uploads a batch that isn't full
+	third := createTestTransaction()
+	third.TraceID = "1234567892"
+
+	err = exp.ExportTransaction(third, config)
+	assert.Equal(t, nil, err)
+	assert.NotContains(t, s3.objects, "logs/Default/batch-1234567892.ndjson.gz")
+
+	// with the config the transaction was exported with
+	err = exp.Flush(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Contains(t, s3.objects, "logs/Default/batch-1234567892.ndjson.gz")
+	assert.Equal(t, "application/x-ndjson", s3.headers["logs/Default/batch-1234567892.ndjson.gz"].Get("Content-Type"))
+
+	// the default key gets the extension of the format
+	delete(config, "s3Key")
+	err = exp.ExportTransaction(first, config)
+	assert.Equal(t, nil, err)
+
+	// a different config uploads the pending batch with its own config first
+	other := testS3Config(server)
+	other["s3BatchSize"] = "2"
+	other["s3Key"] = "other/{traceID}.ndjson"
+	err = exp.ExportTransaction(second, other)
+	assert.Equal(t, nil, err)
+	assert.Contains(t, s3.objects, "logs/Default/2025/03/10/1234567890.ndjson.gz")
+
+	err = exp.Flush(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Contains(t, s3.objects, "logs/other/1234567891.ndjson")
+
+	// a batch that fails to upload after the config changed stays pending
+	// without failing the exports with the new config
+	wrong := testS3Config(server)
+	wrong["s3BatchSize"] = "2"
+	wrong["s3SecretKey"] = "wrong"
+	err = exp.ExportTransaction(first, wrong)
+	assert.Equal(t, nil, err)
+
+	delete(s3.objects, "logs/other/1234567891.ndjson")
+	err = exp.ExportTransaction(second, other)
+	assert.Equal(t, nil, err)
+	err = exp.ExportTransaction(third, other)
+	assert.Equal(t, nil, err)
+	assert.Contains(t, s3.objects, "logs/other/1234567891.ndjson")
+
+	err = exp.Shutdown(context.Background())
+	assert.ErrorContains(t, err, "SignatureDoesNotMatch")
+}
+
+func TestExportLogsS3_Multipart(t *testing.T) {
+	s3 := &testS3{secretKey: "secret"}
+	server := startTestS3(t, s3)
+
+	config := testS3Config(server)
+	config["s3PartSize"] = "200"
+
+	exp := &logExporter.S3Exporter{}
+
+	err := exp.ExportTransaction(createTestTransaction(), config)
+	assert.Equal(t, nil, err)
+
+	// create, a put per part and complete
+	assert.Greater(t, s3.requests, 4)
+
+	object := s3.objects["logs/Default/2025/03/10/1234567890.json"]
+	var transaction otel.TransactionLog
+	assert.Equal(t, nil, json.Unmarshal(object, &transaction))
+	assert.Equal(t, "1234567890", transaction.TraceID)
+	assert.Len(t, s3.parts, 0)
+}
+
+func TestExportLogsS3_FailedPart(t *testing.T) {
+	s3 := &testS3{secretKey: "secret", failPart: "2"}
+	server := startTestS3(t, s3)
+
+	config := testS3Config(server)
+	config["s3PartSize"] = "200"
+
+	exp := &logExporter.S3Exporter{}
+
+	err := exp.ExportTransaction(createTestTransaction(), config)
+	assert.ErrorContains(t, err, "error uploading part 2")
+	assert.Equal(t, []string{"upload-1"}, s3.aborted)
+	assert.Len(t, s3.objects, 0)
+
+	// the transaction stays pending and is uploaded once the storage is back
+	s3.failPart = ""
+	err = exp.Flush(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Contains(t, s3.objects, "logs/Default/2025/03/10/1234567890.json")
+}
+
+func TestExportLogsS3_WrongCredentials(t *testing.T) {
+	s3 := &testS3{secretKey: "other secret"}
+	server := startTestS3(t, s3)
+
+	exp := &logExporter.S3Exporter{}
+
+	err := exp.ExportTransaction(createTestTransaction(), testS3Config(server))
+	var statusErr *logExporter.HTTPStatusError
+	if assert.ErrorAs(t, err, &statusErr) {
+		assert.Equal(t, http.StatusForbidden, statusErr.StatusCode)
+		assert.Contains(t, statusErr.Body, "SignatureDoesNotMatch")
+	}
+}
+
+func TestExportLogsS3_InvalidConfig(t *testing.T) {
+	exp := &logExporter.S3Exporter{}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{})
+	assert.EqualError(t, err, "no s3Endpoint in config")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"s3Endpoint": "http://localhost", "s3Bucket": "logs", "s3AccessKey": "id"})
+	assert.EqualError(t, err, "no s3SecretKey in config")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"s3Endpoint": "localhost"})
+	assert.EqualError(t, err, `invalid s3Endpoint "localhost"`)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"s3BatchSize": "many"})
+	assert.ErrorContains(t, err, "invalid s3BatchSize in config")
+
+	config := map[string]string{"s3Endpoint": "http://localhost", "s3Bucket": "logs", "s3AccessKey": "id", "s3SecretKey": "secret", "s3BatchSize": "2", "s3Format": "json"}
+	err = exp.ExportLogs("1234567890", createTestLog(), config)
+	assert.EqualError(t, err, "s3Format json only holds one transaction, use ndjson with s3BatchSize")
+
+	config["s3Format"] = "csv"
+	err = exp.ExportLogs("1234567890", createTestLog(), config)
+	assert.EqualError(t, err, `unsupported s3Format "csv"`)
+}
@@ -0,0 +1,255 @@
+package logExporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"otellogger/otel"
+	"otellogger/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// export logs to the Splunk HTTP Event Collector
+//
+// config keys (plus the shared HTTPConfig keys):
+//   - splunkEndpoint: HEC base url, e.g. "https://splunk:8088" (required)
+//   - splunkToken: HEC token (required)
+//   - splunkIndex, splunkSource, splunkSourcetype, splunkHost: event metadata
+//   - splunkBatchSize: events per request (default 100)
+//   - splunkChannel: channel GUID, required when acknowledgements are enabled
+//   - splunkAck: "true" to wait until the events are acknowledged as indexed
+//   - splunkAckTimeout: how long to wait for acknowledgements (default 30s)
+//   - splunkAckInterval: how often to poll for acknowledgements (default 1s)
+type SplunkExporter struct {
+	HTTPConfig
+}
+
+const (
+	defaultSplunkBatchSize   = 100
+	defaultSplunkAckTimeout  = 30 * time.Second
+	defaultSplunkAckInterval = time.Second
+)
+
+type splunkEvent struct {
+	Time       float64           `json:"time,omitempty"`
+	Host       string            `json:"host,omitempty"`
+	Source     string            `json:"source,omitempty"`
+	Sourcetype string            `json:"sourcetype,omitempty"`
+	Index      string            `json:"index,omitempty"`
+	Event      splunkEventBody   `json:"event"`
+	Fields     map[string]string `json:"fields,omitempty"`
+}
+
+type splunkEventBody struct {
+	Message  string `json:"message"`
+	Severity string `json:"severity"`
+}
+
+type splunkResponse struct {
+	Text  string `json:"text"`
+	Code  int    `json:"code"`
+	AckID *int64 `json:"ackId"`
+}
+
+// export logs from a transaction in batches of events
+func (exp *SplunkExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	// check if there are no logs to export
+	if len(logs) == 0 {
+		return nil
+	}
+
+	endpoint, err := configValue(config, "splunkEndpoint")
+	if err != nil {
+		return err
+	}
+	endpoint = strings.TrimRight(endpoint, "/")
+
+	token, err := configValue(config, "splunkToken")
+	if err != nil {
+		return err
+	}
+
+	batchSize, err := configInt(config, "splunkBatchSize", defaultSplunkBatchSize)
+	if err != nil {
+		return err
+	}
+	if batchSize < 1 {
+		return configError("splunkBatchSize", "splunkBatchSize must be at least 1")
+	}
+
+	ack, err := configBool(config, "splunkAck")
+	if err != nil {
+		return err
+	}
+	if ack && config["splunkChannel"] == "" {
+		return configError("splunkChannel", "no splunkChannel in config")
+	}
+
+	var ackIDs []int64
+
+	for start := 0; start < len(logs); start += batchSize {
+		batch := logs[start:min(start+batchSize, len(logs))]
+
+		resp, err := exp.sendEvents(endpoint, token, batch, config)
+		if err != nil {
+			return err
+		}
+
+		if ack {
+			if resp.AckID == nil {
+				return errors.New("no ackId in HEC response, is indexer acknowledgement enabled for the token?")
+			}
+			ackIDs = append(ackIDs, *resp.AckID)
+		}
+	}
+
+	if ack {
+		return exp.waitForAcks(endpoint, token, ackIDs, config)
+	}
+
+	return nil
+}
+
+// post a batch of events, HEC accepts them as concatenated json objects
+func (exp *SplunkExporter) sendEvents(endpoint, token string, logs []*otel.OTelLog, config map[string]string) (*splunkResponse, error) {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+
+	for _, log := range logs {
+		err := encoder.Encode(newSplunkEvent(log, config))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint+"/services/collector/event", &body)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := exp.sendHEC(req, token, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp splunkResponse
+	err = json.Unmarshal(respBody, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HEC response: %w", err)
+	}
+
+	if resp.Code != 0 {
+		return nil, fmt.Errorf("HEC error %d: %s", resp.Code, resp.Text)
+	}
+
+	return &resp, nil
+}
+
+// poll the ack endpoint until all the batches are indexed
+func (exp *SplunkExporter) waitForAcks(endpoint, token string, ackIDs []int64, config map[string]string) error {
+	timeout, err := configDuration(config, "splunkAckTimeout", defaultSplunkAckTimeout)
+	if err != nil {
+		return err
+	}
+
+	interval, err := configDuration(config, "splunkAckInterval", defaultSplunkAckInterval)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	pending := ackIDs
+
+	for {
+		body, err := json.Marshal(map[string][]int64{"acks": pending})
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequest(http.MethodPost, endpoint+"/services/collector/ack", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+
+		respBody, err := exp.sendHEC(req, token, config)
+		if err != nil {
+			return err
+		}
+
+		var resp struct {
+			Acks map[string]bool `json:"acks"`
+		}
+		err = json.Unmarshal(respBody, &resp)
+		if err != nil {
+			return fmt.Errorf("invalid HEC ack response: %w", err)
+		}
+
+		var stillPending []int64
+		for _, id := range pending {
+			if !resp.Acks[strconv.FormatInt(id, 10)] {
+				stillPending = append(stillPending, id)
+			}
+		}
+		pending = stillPending
+
+		if len(pending) == 0 {
+			return nil
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			// without an ack the events have to be treated as lost, even if they
+			// may still get indexed later
+			return &utils.RetryableError{Err: fmt.Errorf("timed out waiting for HEC acknowledgements %v", pending)}
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// send a request with the token and channel headers
+func (exp *SplunkExporter) sendHEC(req *http.Request, token string, config map[string]string) ([]byte, error) {
+	req.Header.Set("Authorization", "Splunk "+token)
+	req.Header.Set("Content-Type", "application/json")
+	if channel := config["splunkChannel"]; channel != "" {
+		req.Header.Set("X-Splunk-Request-Channel", channel)
+		req.URL.RawQuery = url.Values{"channel": {channel}}.Encode()
+	}
+
+	return exp.send(req, config)
+}
+
+func newSplunkEvent(log *otel.OTelLog, config map[string]string) splunkEvent {
+	fields := maps.Clone(log.Attributes)
+	if fields == nil {
+		fields = make(map[string]string)
+	}
+	fields["trace_id"] = log.TraceID
+	fields["span_id"] = log.SpanID
+	fields["service"] = log.ServiceName
+	fields["logger"] = log.LoggerName
+
+	event := splunkEvent{
+		Host:       config["splunkHost"],
+		Source:     config["splunkSource"],
+		Sourcetype: config["splunkSourcetype"],
+		Index:      config["splunkIndex"],
+		Event: splunkEventBody{
+			Message:  log.Message,
+			Severity: log.Severity,
+		},
+		Fields: fields,
+	}
+
+	// HEC takes epoch seconds, without a time the receive time is used
+	if timestamp, err := parseTimestamp(log.Timestamp); err == nil {
+		event.Time = float64(timestamp.UnixMilli()) / 1000
+	}
+
+	return event
+}
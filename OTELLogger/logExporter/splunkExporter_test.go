@@ -0,0 +1,197 @@
+package logExporter_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"otellogger/logExporter"
+	"otellogger/utils"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stand-in for HEC, acknowledging each batch after a number of polls
+type testHEC struct {
+	mu          sync.Mutex
+	batches     [][]map[string]any
+	ackPolls    int
+	pollsNeeded int
+	channel     string
+}
+
+func (h *testHEC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Splunk secret-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"text":"Invalid authorization","code":3}`))
+		return
+	}
+	h.channel = r.Header.Get("X-Splunk-Request-Channel")
+
+	switch r.URL.Path {
+	case "/services/collector/event":
+		var batch []map[string]any
+		decoder := json.NewDecoder(r.Body)
+		for decoder.More() {
+			var event map[string]any
+			if err := decoder.Decode(&event); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			batch = append(batch, event)
+		}
+		h.batches = append(h.batches, batch)
+
+		if h.channel != "" {
+			w.Write([]byte(`{"text":"Success","code":0,"ackId":` + strconv.Itoa(len(h.batches)-1) + `}`))
+			return
+		}
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	case "/services/collector/ack":
+		body, _ := io.ReadAll(r.Body)
+		var request struct {
+			Acks []int64 `json:"acks"`
+		}
+		json.Unmarshal(body, &request)
+
+		h.ackPolls++
+		acks := map[string]bool{}
+		for _, id := range request.Acks {
+			acks[strconv.FormatInt(id, 10)] = h.ackPolls >= h.pollsNeeded
+		}
+
+		response, _ := json.Marshal(map[string]any{"acks": acks})
+		w.Write(response)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func startTestHEC(t *testing.T, hec *testHEC) *httptest.Server {
+	server := httptest.NewServer(hec)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestExportLogsSplunk(t *testing.T) {
+	t.Run("Export logs to splunk successful", TestExportLogsSplunk_Success)
+	t.Run("Export logs to splunk with acknowledgements successful", TestExportLogsSplunk_Ack)
+	t.Run("Error exporting logs to splunk - acknowledgement timeout", TestExportLogsSplunk_AckTimeout)
+	t.Run("Error exporting logs to splunk - invalid token", TestExportLogsSplunk_InvalidToken)
+	t.Run("Error exporting logs to splunk - invalid config", TestExportLogsSplunk_InvalidConfig)
+}
+
+func TestExportLogsSplunk_Success(t *testing.T) {
+	hec := &testHEC{}
+	server := startTestHEC(t, hec)
+
+	exp := logExporter.SplunkExporter{}
+
+	err := exp.ExportLogs("1234567890", nil, nil)
+	assert.Equal(t, nil, err)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{
+		"splunkEndpoint":   server.URL,
+		"splunkToken":      "secret-token",
+		"splunkIndex":      "main",
+		"splunkSource":     "otellogger",
+		"splunkSourcetype": "_json",
+		"splunkHost":       "host",
+	})
+	assert.Equal(t, nil, err)
+
+	if !assert.Len(t, hec.batches, 1) || !assert.Len(t, hec.batches[0], 2) {
+		return
+	}
+
+	timestamp, _ := time.ParseInLocation(utils.TimestampFormat, "10.03.2025 17:00:00", time.Local)
+	assert.Equal(t, map[string]any{
+		"time":       float64(timestamp.Unix()),
+		"host":       "host",
+		"source":     "otellogger",
+		"sourcetype": "_json",
+		"index":      "main",
+		"event":      map[string]any{"message": "test message 1", "severity": "INFO"},
+		"fields": map[string]any{
+			"key1":     "val1",
+			"trace_id": "1234567890",
+			"span_id":  "00000000000",
+			"service":  utils.ServiceName,
+			"logger":   utils.LoggerName,
+		},
+	}, hec.batches[0][0])
+}
+
+func TestExportLogsSplunk_Ack(t *testing.T) {
+	hec := &testHEC{pollsNeeded: 2}
+	server := startTestHEC(t, hec)
+
+	exp := logExporter.SplunkExporter{}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{
+		"splunkEndpoint":    server.URL,
+		"splunkToken":       "secret-token",
+		"splunkBatchSize":   "1",
+		"splunkAck":         "true",
+		"splunkChannel":     "0aeeac95-ac74-4aa9-b30d-6c4c0ac581ba",
+		"splunkAckInterval": "10ms",
+	})
+	assert.Equal(t, nil, err)
+
+	// one request per batch and polling until everything is acknowledged
+	assert.Len(t, hec.batches, 2)
+	assert.Equal(t, 2, hec.ackPolls)
+	assert.Equal(t, "0aeeac95-ac74-4aa9-b30d-6c4c0ac581ba", hec.channel)
+}
+
+func TestExportLogsSplunk_AckTimeout(t *testing.T) {
+	hec := &testHEC{pollsNeeded: 1000}
+	server := startTestHEC(t, hec)
+
+	exp := logExporter.SplunkExporter{}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{
+		"splunkEndpoint":    server.URL,
+		"splunkToken":       "secret-token",
+		"splunkAck":         "true",
+		"splunkChannel":     "0aeeac95-ac74-4aa9-b30d-6c4c0ac581ba",
+		"splunkAckTimeout":  "50ms",
+		"splunkAckInterval": "10ms",
+	})
+	assert.EqualError(t, err, "timed out waiting for HEC acknowledgements [0]")
+	assert.Equal(t, true, utils.IsRetryable(err))
+}
+
+func TestExportLogsSplunk_InvalidToken(t *testing.T) {
+	server := startTestHEC(t, &testHEC{})
+
+	exp := logExporter.SplunkExporter{}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{
+		"splunkEndpoint": server.URL,
+		"splunkToken":    "wrong-token",
+	})
+	assert.EqualError(t, err, `unexpected status code 401: {"text":"Invalid authorization","code":3}`)
+	assert.Equal(t, false, utils.IsRetryable(err))
+}
+
+func TestExportLogsSplunk_InvalidConfig(t *testing.T) {
+	exp := logExporter.SplunkExporter{}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{"splunkToken": "secret-token"})
+	assert.EqualError(t, err, "no splunkEndpoint in config")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"splunkEndpoint": "http://localhost"})
+	assert.EqualError(t, err, "no splunkToken in config")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"splunkEndpoint": "http://localhost", "splunkToken": "secret-token", "splunkAck": "true"})
+	assert.EqualError(t, err, "no splunkChannel in config")
+}
@@ -0,0 +1,431 @@
+package logExporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"otellogger/otel"
+	"otellogger/utils"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// export transactions into sql tables so they can be queried
+//
+// every export writes the transaction, its logs and all attributes in one
+// database transaction. exporting a transaction again replaces its rows
+//
+// tables (with the sqlTablePrefix):
+//   - transactions: trace_id, name, service_name, logger_name, start_time, end_time
+//   - logs: trace_id, span_id, log_time, severity, message, logger_name, service_name
+//   - attributes: trace_id, span_id (empty for transaction attributes), attr_key, attr_value
+//
+// config keys:
+//   - sqlDriver, sqlDSN: driver name and data source to open the database with
+//     when no DB is set on the exporter (the driver has to be imported by the app)
+//   - sqlDialect: "postgres", "mysql" or "sqlite" (default guessed from sqlDriver)
+//   - sqlTablePrefix: prefix of the table names (default "otel_")
+//   - sqlBatchSize: rows per insert statement (default 100)
+//   - sqlTimeout: timeout for an export, e.g. "5s" (default 30s)
+//   - sqlAutoMigrate: create or update the tables before the first export
+type SQLExporter struct {
+	// database to write to, opened from config when nil
+	DB *sql.DB
+
+	mu       sync.Mutex
+	opened   bool // DB was opened by the exporter and has to be closed by it
+	migrated bool
+}
+
+const (
+	defaultSQLTablePrefix = "otel_"
+	defaultSQLBatchSize   = 100
+	defaultSQLTimeout     = 30 * time.Second
+)
+
+// placeholder and column type differences between the databases
+type sqlDialect struct {
+	numbered bool   // $1, $2... instead of ?
+	idType   string // type of the indexed text columns
+	timeType string
+	textType string
+	intType  string
+}
+
+var sqlDialects = map[string]sqlDialect{
+	"postgres": {numbered: true, idType: "VARCHAR(255)", timeType: "TIMESTAMPTZ", textType: "TEXT", intType: "INTEGER"},
+	"mysql":    {idType: "VARCHAR(255)", timeType: "DATETIME(6)", textType: "TEXT", intType: "INT"},
+	"sqlite":   {idType: "TEXT", timeType: "TIMESTAMP", textType: "TEXT", intType: "INTEGER"},
+}
+
+// schema changes in order, each one is applied once and recorded in the
+// schema_migrations table. new versions are only ever appended
+var sqlMigrations = []func(d sqlDialect, prefix string) []string{
+	// 1: initial tables
+	func(d sqlDialect, prefix string) []string {
+		return []string{
+			"CREATE TABLE " + prefix + "transactions (" +
+				"trace_id " + d.idType + " NOT NULL PRIMARY KEY, " +
+				"name " + d.textType + ", " +
+				"service_name " + d.idType + ", " +
+				"logger_name " + d.idType + ", " +
+				"start_time " + d.timeType + ", " +
+				"end_time " + d.timeType + ")",
+			"CREATE TABLE " + prefix + "logs (" +
+				"trace_id " + d.idType + " NOT NULL, " +
+				"span_id " + d.idType + " NOT NULL, " +
+				"log_time " + d.timeType + ", " +
+				"severity " + d.idType + ", " +
+				"message " + d.textType + ", " +
+				"logger_name " + d.idType + ", " +
+				"service_name " + d.idType + ", " +
+				"PRIMARY KEY (trace_id, span_id))",
+			"CREATE TABLE " + prefix + "attributes (" +
+				"trace_id " + d.idType + " NOT NULL, " +
+				"span_id " + d.idType + " NOT NULL, " +
+				"attr_key " + d.idType + " NOT NULL, " +
+				"attr_value " + d.textType + ", " +
+				"PRIMARY KEY (trace_id, span_id, attr_key))",
+			"CREATE INDEX " + prefix + "transactions_service ON " + prefix + "transactions (service_name, start_time)",
+			"CREATE INDEX " + prefix + "logs_severity ON " + prefix + "logs (severity, log_time)",
+		}
+	},
+}
+
+// export logs from a transaction, rebuilding the transaction from its logs
+func (exp *SQLExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	// check if there are no logs to export
+	if len(logs) == 0 {
+		return nil
+	}
+
+	return exp.ExportTransaction(transactionFromLogs(traceID, logs), config)
+}
+
+// write the transaction, its logs and attributes in one database transaction
+func (exp *SQLExporter) ExportTransaction(transactionLog *otel.TransactionLog, config map[string]string) error {
+	db, dialect, err := exp.database(config)
+	if err != nil {
+		return err
+	}
+
+	batchSize, err := configInt(config, "sqlBatchSize", defaultSQLBatchSize)
+	if err != nil {
+		return err
+	}
+	if batchSize <= 0 {
+		return configError("sqlBatchSize", "sqlBatchSize must be positive")
+	}
+
+	timeout, err := configDuration(config, "sqlTimeout", defaultSQLTimeout)
+	if err != nil {
+		return err
+	}
+
+	autoMigrate, err := configBool(config, "sqlAutoMigrate")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if autoMigrate {
+		err = exp.migrateOnce(ctx, db, dialect, config)
+		if err != nil {
+			return err
+		}
+	}
+
+	prefix := sqlTablePrefix(config)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// no-op once committed
+	defer tx.Rollback()
+
+	// replace the rows of a transaction that was exported before
+	for _, table := range []string{"attributes", "logs", "transactions"} {
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+prefix+table+" WHERE trace_id = "+dialect.placeholder(1), transactionLog.TraceID)
+		if err != nil {
+			return err
+		}
+	}
+
+	start, end := transactionTimespan(transactionLog)
+	err = dialect.insert(ctx, tx, prefix+"transactions",
+		[]string{"trace_id", "name", "service_name", "logger_name", "start_time", "end_time"},
+		[][]any{{transactionLog.TraceID, transactionName(transactionLog), transactionLog.ServiceName, transactionLog.LoggerName, sqlTime(start), sqlTime(end)}},
+		batchSize)
+	if err != nil {
+		return err
+	}
+
+	var logRows, attributeRows [][]any
+	attributeRows = appendAttributeRows(attributeRows, transactionLog.TraceID, "", transactionLog.Attributes)
+
+	for _, log := range transactionLog.Spans {
+		var loggedAt any
+		if timestamp, err := parseTimestamp(log.Timestamp); err == nil {
+			loggedAt = timestamp
+		}
+
+		logRows = append(logRows, []any{transactionLog.TraceID, log.SpanID, loggedAt, log.Severity, log.Message, log.LoggerName, log.ServiceName})
+		attributeRows = appendAttributeRows(attributeRows, transactionLog.TraceID, log.SpanID, log.Attributes)
+	}
+
+	err = dialect.insert(ctx, tx, prefix+"logs",
+		[]string{"trace_id", "span_id", "log_time", "severity", "message", "logger_name", "service_name"},
+		logRows, batchSize)
+	if err != nil {
+		return err
+	}
+
+	err = dialect.insert(ctx, tx, prefix+"attributes",
+		[]string{"trace_id", "span_id", "attr_key", "attr_value"},
+		attributeRows, batchSize)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// create the tables or apply the migrations that are missing
+func (exp *SQLExporter) Migrate(config map[string]string) error {
+	db, dialect, err := exp.database(config)
+	if err != nil {
+		return err
+	}
+
+	timeout, err := configDuration(config, "sqlTimeout", defaultSQLTimeout)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return migrateSQL(ctx, db, dialect, sqlTablePrefix(config))
+}
+
+// close the database if it was opened by the exporter
+func (exp *SQLExporter) Close() error {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	if !exp.opened {
+		return nil
+	}
+
+	err := exp.DB.Close()
+	exp.DB = nil
+	exp.opened = false
+	exp.migrated = false
+
+	return err
+}
+
+// get the database, opening it from config on first use, and its dialect
+func (exp *SQLExporter) database(config map[string]string) (*sql.DB, sqlDialect, error) {
+	if config == nil {
+		return nil, sqlDialect{}, utils.ErrNoConfig
+	}
+
+	name := config["sqlDialect"]
+	if name == "" {
+		name = guessSQLDialect(config["sqlDriver"])
+		if name == "" {
+			return nil, sqlDialect{}, configError("sqlDialect", "no sqlDialect in config")
+		}
+	}
+
+	dialect, ok := sqlDialects[name]
+	if !ok {
+		return nil, sqlDialect{}, configError("sqlDialect", "unsupported sqlDialect %q", name)
+	}
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	if exp.DB != nil {
+		return exp.DB, dialect, nil
+	}
+
+	driver, err := configValue(config, "sqlDriver")
+	if err != nil {
+		return nil, sqlDialect{}, err
+	}
+
+	dsn, err := configValue(config, "sqlDSN")
+	if err != nil {
+		return nil, sqlDialect{}, err
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, sqlDialect{}, err
+	}
+
+	exp.DB = db
+	exp.opened = true
+
+	return db, dialect, nil
+}
+
+func (exp *SQLExporter) migrateOnce(ctx context.Context, db *sql.DB, dialect sqlDialect, config map[string]string) error {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	if exp.migrated {
+		return nil
+	}
+
+	err := migrateSQL(ctx, db, dialect, sqlTablePrefix(config))
+	if err != nil {
+		return err
+	}
+
+	exp.migrated = true
+
+	return nil
+}
+
+func migrateSQL(ctx context.Context, db *sql.DB, dialect sqlDialect, prefix string) error {
+	versions := prefix + "schema_migrations"
+
+	_, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+versions+" (version "+dialect.intType+" NOT NULL PRIMARY KEY)")
+	if err != nil {
+		return err
+	}
+
+	var current int
+	err = db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM "+versions).Scan(&current)
+	if err != nil {
+		return err
+	}
+
+	for version := current + 1; version <= len(sqlMigrations); version++ {
+		err := applySQLMigration(ctx, db, dialect, prefix, version)
+		if err != nil {
+			return fmt.Errorf("error applying migration %d: %w", version, err)
+		}
+	}
+
+	return nil
+}
+
+// apply a migration and record its version (mysql commits the ddl statements
+// right away, the other databases roll the whole migration back on errors)
+func applySQLMigration(ctx context.Context, db *sql.DB, dialect sqlDialect, prefix string, version int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range sqlMigrations[version-1](dialect, prefix) {
+		_, err = tx.ExecContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO "+prefix+"schema_migrations (version) VALUES ("+dialect.placeholder(1)+")", version)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// get the placeholder of the nth argument (starting at 1)
+func (d sqlDialect) placeholder(n int) string {
+	if d.numbered {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// insert the rows with multi-row statements of up to batchSize rows
+func (d sqlDialect) insert(ctx context.Context, tx *sql.Tx, table string, columns []string, rows [][]any, batchSize int) error {
+	for start := 0; start < len(rows); start += batchSize {
+		batch := rows[start:min(start+batchSize, len(rows))]
+
+		var query strings.Builder
+		query.WriteString("INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES ")
+
+		args := make([]any, 0, len(batch)*len(columns))
+		for i, row := range batch {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+
+			query.WriteString("(")
+			for j, value := range row {
+				if j > 0 {
+					query.WriteString(", ")
+				}
+				args = append(args, value)
+				query.WriteString(d.placeholder(len(args)))
+			}
+			query.WriteString(")")
+		}
+
+		_, err := tx.ExecContext(ctx, query.String(), args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// attribute rows in key order so the statements are the same for each export
+func appendAttributeRows(rows [][]any, traceID, spanID string, attributes map[string]string) [][]any {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		rows = append(rows, []any{traceID, spanID, key, attributes[key]})
+	}
+
+	return rows
+}
+
+func guessSQLDialect(driver string) string {
+	switch driver {
+	case "postgres", "pgx":
+		return "postgres"
+	case "mysql":
+		return "mysql"
+	case "sqlite", "sqlite3":
+		return "sqlite"
+	default:
+		return ""
+	}
+}
+
+func sqlTablePrefix(config map[string]string) string {
+	prefix, ok := config["sqlTablePrefix"]
+	if !ok {
+		return defaultSQLTablePrefix
+	}
+	return prefix
+}
+
+// zero times are stored as NULL
+func sqlTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
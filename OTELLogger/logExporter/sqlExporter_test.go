@@ -0,0 +1,316 @@
+package logExporter_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"otellogger/logExporter"
+	"otellogger/utils"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fake database/sql driver recording the statements of each database (by DSN)
+type testSQLDriver struct{}
+
+type testSQLDB struct {
+	mu         sync.Mutex
+	statements []testSQLStatement
+	version    int64  // highest version in the schema_migrations table
+	failOn     string // statements containing this fail
+}
+
+type testSQLStatement struct {
+	query string
+	args  []driver.Value
+}
+
+type testSQLConn struct {
+	db *testSQLDB
+}
+
+type testSQLTx struct {
+	db *testSQLDB
+}
+
+type testSQLRows struct {
+	values []driver.Value
+	done   bool
+}
+
+var (
+	testSQLMu  sync.Mutex
+	testSQLDBs = make(map[string]*testSQLDB)
+)
+
+func init() {
+	sql.Register("otelfake", testSQLDriver{})
+}
+
+func (testSQLDriver) Open(dsn string) (driver.Conn, error) {
+	testSQLMu.Lock()
+	defer testSQLMu.Unlock()
+
+	if testSQLDBs[dsn] == nil {
+		testSQLDBs[dsn] = &testSQLDB{}
+	}
+
+	return &testSQLConn{db: testSQLDBs[dsn]}, nil
+}
+
+func (db *testSQLDB) record(query string, args []driver.Value) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.statements = append(db.statements, testSQLStatement{query: query, args: args})
+	if db.failOn != "" && strings.Contains(query, db.failOn) {
+		return errors.New("fake failure")
+	}
+	if strings.HasPrefix(query, "INSERT INTO otel_schema_migrations") {
+		db.version = args[0].(int64)
+	}
+
+	return nil
+}
+
+func (db *testSQLDB) queries() []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var queries []string
+	for _, statement := range db.statements {
+		queries = append(queries, statement.query)
+	}
+	return queries
+}
+
+func (c *testSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+
+func (c *testSQLConn) Close() error { return nil }
+
+func (c *testSQLConn) Begin() (driver.Tx, error) {
+	return &testSQLTx{db: c.db}, c.db.record("BEGIN", nil)
+}
+
+func (c *testSQLConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+
+	return driver.RowsAffected(0), c.db.record(query, values)
+}
+
+func (c *testSQLConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	err := c.db.record(query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	return &testSQLRows{values: []driver.Value{c.db.version}}, nil
+}
+
+func (tx *testSQLTx) Commit() error   { return tx.db.record("COMMIT", nil) }
+func (tx *testSQLTx) Rollback() error { return tx.db.record("ROLLBACK", nil) }
+
+func (r *testSQLRows) Columns() []string { return []string{"version"} }
+func (r *testSQLRows) Close() error      { return nil }
+
+func (r *testSQLRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.values)
+	return nil
+}
+
+func openTestSQL(t *testing.T) (*sql.DB, *testSQLDB) {
+	db, err := sql.Open("otelfake", t.Name())
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// make sure the fake database exists
+	err = db.Ping()
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+
+	testSQLMu.Lock()
+	defer testSQLMu.Unlock()
+
+	return db, testSQLDBs[t.Name()]
+}
+
+func TestExportLogsSQL(t *testing.T) {
+	t.Run("Export transaction to postgres successful", TestExportLogsSQL_Postgres)
+	t.Run("Export transaction to mysql in batches successful", TestExportLogsSQL_Batches)
+	t.Run("Migrate sql schema successful", TestExportLogsSQL_Migrate)
+	t.Run("Export to database opened from config successful", TestExportLogsSQL_Open)
+	t.Run("Error exporting to sql - rolled back", TestExportLogsSQL_Rollback)
+	t.Run("Error exporting to sql - invalid config", TestExportLogsSQL_InvalidConfig)
+}
+
+func TestExportLogsSQL_Postgres(t *testing.T) {
+	db, fake := openTestSQL(t)
+
+	exp := &logExporter.SQLExporter{DB: db}
+
+	err := exp.ExportLogs("1234567890", nil, nil)
+	assert.Equal(t, nil, err)
+
+	transaction := createTestTransaction()
+	err = exp.ExportTransaction(transaction, map[string]string{"sqlDialect": "postgres"})
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, []string{
+		"BEGIN",
+		"DELETE FROM otel_attributes WHERE trace_id = $1",
+		"DELETE FROM otel_logs WHERE trace_id = $1",
+		"DELETE FROM otel_transactions WHERE trace_id = $1",
+		"INSERT INTO otel_transactions (trace_id, name, service_name, logger_name, start_time, end_time) VALUES ($1, $2, $3, $4, $5, $6)",
+		"INSERT INTO otel_logs (trace_id, span_id, log_time, severity, message, logger_name, service_name) VALUES " +
+			"($1, $2, $3, $4, $5, $6, $7), ($8, $9, $10, $11, $12, $13, $14)",
+		"INSERT INTO otel_attributes (trace_id, span_id, attr_key, attr_value) VALUES " +
+			"($1, $2, $3, $4), ($5, $6, $7, $8), ($9, $10, $11, $12), ($13, $14, $15, $16)",
+		"COMMIT",
+	}, fake.queries())
+
+	assert.Equal(t, []driver.Value{"1234567890", "checkout", "Default", "OTelLogger", transaction.StartTime, transaction.EndTime}, fake.statements[4].args)
+
+	timestamp, _ := time.ParseInLocation(utils.TimestampFormat, "10.03.2025 17:00:00", time.Local)
+	assert.Equal(t, []driver.Value{"1234567890", "00000000000", timestamp, "INFO", "test message 1", "OTelLogger", "Default"}, fake.statements[5].args[:7])
+
+	// transaction attributes have no span ID
+	assert.Equal(t, []driver.Value{
+		"1234567890", "", "name", "checkout",
+		"1234567890", "", "user", "42",
+		"1234567890", "00000000000", "key1", "val1",
+		"1234567890", "00000000001", "key2", "val2",
+	}, fake.statements[6].args)
+}
+
+func TestExportLogsSQL_Batches(t *testing.T) {
+	db, fake := openTestSQL(t)
+
+	exp := &logExporter.SQLExporter{DB: db}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{
+		"sqlDialect":     "mysql",
+		"sqlBatchSize":   "1",
+		"sqlTablePrefix": "app_",
+	})
+	assert.Equal(t, nil, err)
+
+	queries := fake.queries()
+	assert.Equal(t, "DELETE FROM app_attributes WHERE trace_id = ?", queries[1])
+	assert.Equal(t, "INSERT INTO app_logs (trace_id, span_id, log_time, severity, message, logger_name, service_name) VALUES (?, ?, ?, ?, ?, ?, ?)", queries[5])
+	assert.Equal(t, queries[5], queries[6])
+	assert.Equal(t, "INSERT INTO app_attributes (trace_id, span_id, attr_key, attr_value) VALUES (?, ?, ?, ?)", queries[7])
+	assert.Equal(t, queries[7], queries[8])
+	assert.Equal(t, "COMMIT", queries[9])
+
+	// a rebuilt transaction is named after its logger and timed by its logs
+	assert.Equal(t, "OTelLogger", fake.statements[4].args[1])
+}
+
+func TestExportLogsSQL_Migrate(t *testing.T) {
+	db, fake := openTestSQL(t)
+
+	exp := &logExporter.SQLExporter{DB: db}
+	config := map[string]string{"sqlDialect": "sqlite", "sqlAutoMigrate": "true"}
+
+	err := exp.Migrate(config)
+	assert.Equal(t, nil, err)
+
+	queries := fake.queries()
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS otel_schema_migrations (version INTEGER NOT NULL PRIMARY KEY)", queries[0])
+	assert.Equal(t, "SELECT COALESCE(MAX(version), 0) FROM otel_schema_migrations", queries[1])
+	assert.Equal(t, "BEGIN", queries[2])
+	assert.Equal(t, "CREATE TABLE otel_transactions (trace_id TEXT NOT NULL PRIMARY KEY, name TEXT, service_name TEXT, "+
+		"logger_name TEXT, start_time TIMESTAMP, end_time TIMESTAMP)", queries[3])
+	assert.Equal(t, "INSERT INTO otel_schema_migrations (version) VALUES (?)", queries[len(queries)-2])
+	assert.Equal(t, "COMMIT", queries[len(queries)-1])
+
+	// nothing left to apply, the auto migration only checks the version once
+	fake.statements = nil
+	err = exp.ExportLogs("1234567890", createTestLog(), config)
+	assert.Equal(t, nil, err)
+	err = exp.ExportLogs("1234567890", createTestLog(), config)
+	assert.Equal(t, nil, err)
+
+	var creates, selects int
+	for _, query := range fake.queries() {
+		if strings.HasPrefix(query, "CREATE TABLE ") {
+			creates++
+		}
+		if strings.HasPrefix(query, "SELECT ") {
+			selects++
+		}
+	}
+	assert.Equal(t, 1, creates)
+	assert.Equal(t, 1, selects)
+}
+
+func TestExportLogsSQL_Open(t *testing.T) {
+	exp := &logExporter.SQLExporter{}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{
+		"sqlDriver":  "otelfake",
+		"sqlDSN":     t.Name(),
+		"sqlDialect": "postgres",
+	})
+	assert.Equal(t, nil, err)
+	assert.NotNil(t, exp.DB)
+
+	testSQLMu.Lock()
+	fake := testSQLDBs[t.Name()]
+	testSQLMu.Unlock()
+	assert.Contains(t, fake.queries(), "COMMIT")
+
+	assert.Equal(t, nil, exp.Close())
+	assert.Nil(t, exp.DB)
+}
+
+func TestExportLogsSQL_Rollback(t *testing.T) {
+	db, fake := openTestSQL(t)
+	fake.failOn = "INSERT INTO otel_logs"
+
+	exp := &logExporter.SQLExporter{DB: db}
+
+	err := exp.ExportTransaction(createTestTransaction(), map[string]string{"sqlDialect": "postgres"})
+	assert.EqualError(t, err, "fake failure")
+
+	queries := fake.queries()
+	assert.Equal(t, "ROLLBACK", queries[len(queries)-1])
+	assert.NotContains(t, queries, "COMMIT")
+}
+
+func TestExportLogsSQL_InvalidConfig(t *testing.T) {
+	exp := &logExporter.SQLExporter{}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.EqualError(t, err, "no config provided")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{})
+	assert.EqualError(t, err, "no sqlDialect in config")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"sqlDialect": "oracle"})
+	assert.EqualError(t, err, `unsupported sqlDialect "oracle"`)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"sqlDriver": "postgres"})
+	assert.EqualError(t, err, "no sqlDSN in config")
+}
@@ -0,0 +1,94 @@
+package logExporter
+
+import (
+	"errors"
+	"io"
+	"otellogger/utils"
+)
+
+// a connection kept open between exports, shared by the exporters writing to a
+// socket. it is dialed on the first write, dialed again when the network or
+// address change and the owning exporter holds its own lock around it
+type streamConn[C io.Closer] struct {
+	conn    C
+	open    bool
+	network string
+	address string
+}
+
+// write over the connection to network/address, dialing it first if needed.
+// write returns how many bytes it sent before failing. a write that failed
+// before sending anything is tried once more on a new connection since the
+// server may have closed an idle one. once something was sent it isn't sent
+// again here, the server may have got it already, the error is returned and
+// exporting again (e.g. with a RetryExporter) delivers it at least once. the
+// errors are returned as they are, see streamError
+func (c *streamConn[C]) write(network, address string, dial func() (C, error), write func(conn C) (int, error)) error {
+	var err error
+
+	for attempt := 0; attempt < 2; attempt++ {
+		err = c.connect(network, address, dial)
+		if err != nil {
+			return err
+		}
+
+		var written int
+		written, err = write(c.conn)
+		if err == nil {
+			return nil
+		}
+
+		// drop the broken connection, trying again with a new one is only
+		// safe if nothing made it out
+		c.Close()
+		if written > 0 {
+			return err
+		}
+	}
+
+	return err
+}
+
+func (c *streamConn[C]) connect(network, address string, dial func() (C, error)) error {
+	if c.open && c.network == network && c.address == address {
+		return nil
+	}
+	c.Close()
+
+	conn, err := dial()
+	if err != nil {
+		return err
+	}
+
+	c.conn = conn
+	c.open = true
+	c.network = network
+	c.address = address
+
+	return nil
+}
+
+// close the connection if there is one
+func (c *streamConn[C]) Close() error {
+	if !c.open {
+		return nil
+	}
+
+	err := c.conn.Close()
+
+	var zero C
+	c.conn = zero
+	c.open = false
+
+	return err
+}
+
+// mark connection errors as retryable, config errors (e.g. from loading
+// certificates while dialing) aren't going to go away by trying again
+func streamError(err error) error {
+	if err == nil || errors.Is(err, utils.ErrInvalidConfig) {
+		return err
+	}
+
+	return &utils.RetryableError{Err: err}
+}
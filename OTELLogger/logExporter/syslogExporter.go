@@ -0,0 +1,248 @@
+package logExporter
+
+import (
+	"crypto/tls"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"otellogger/otel"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// export logs as RFC 5424 syslog messages
+//
+// config keys:
+//   - syslogNetwork: "udp", "tcp", "tcp+tls", "unixgram" or "unix" (default "unixgram")
+//   - syslogAddress: host:port or socket path (default "/dev/log")
+//   - syslogFacility: facility name, e.g. "local0" (default "user")
+//   - syslogHostname: HOSTNAME field (default the machine hostname)
+//   - syslogTimeout: timeout for connecting and writing, e.g. "5s" (default 10s)
+//   - syslogCACert, syslogClientCert, syslogClientKey: PEM files for tcp+tls
+//
+// stream connections (tcp, tcp+tls, unix) use octet-counting framing
+type SyslogExporter struct {
+	// custom TLS settings for tcp+tls, used instead of the syslog*Cert config keys when set
+	TLSConfig *tls.Config
+
+	mu   sync.Mutex
+	conn streamConn[net.Conn]
+}
+
+const (
+	defaultSyslogTimeout = 10 * time.Second
+	// SD-ID suffix, 32473 is the enterprise number reserved for documentation
+	syslogEnterpriseID = "32473"
+)
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6,
+	"news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// export each log from a transaction as a syslog message
+func (exp *SyslogExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	// check if there are no logs to export
+	if len(logs) == 0 {
+		return nil
+	}
+
+	if config == nil {
+		config = map[string]string{}
+	}
+
+	facility := syslogFacilities["user"]
+	if name, ok := config["syslogFacility"]; ok {
+		facility, ok = syslogFacilities[name]
+		if !ok {
+			return configError("syslogFacility", "unknown syslogFacility %q", name)
+		}
+	}
+
+	hostname := config["syslogHostname"]
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+
+	timeout, err := configDuration(config, "syslogTimeout", defaultSyslogTimeout)
+	if err != nil {
+		return err
+	}
+
+	network := config["syslogNetwork"]
+	if network == "" {
+		network = "unixgram"
+	}
+
+	address := config["syslogAddress"]
+	if address == "" {
+		if network != "unixgram" && network != "unix" {
+			return configError("syslogAddress", "no syslogAddress in config")
+		}
+		address = "/dev/log"
+	}
+
+	switch network {
+	case "udp", "tcp", "tcp+tls", "unixgram", "unix":
+	default:
+		return configError("syslogNetwork", "unsupported syslogNetwork %q", network)
+	}
+
+	dial := func() (net.Conn, error) {
+		return exp.dial(network, address, config, timeout)
+	}
+
+	// stream connections use octet-counting framing
+	stream := network == "tcp" || network == "tcp+tls" || network == "unix"
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	for _, log := range logs {
+		message := syslogMessage(log, facility, hostname)
+		if stream {
+			message = strconv.Itoa(len(message)) + " " + message
+		}
+
+		err := exp.conn.write(network, address, dial, func(conn net.Conn) (int, error) {
+			conn.SetWriteDeadline(time.Now().Add(timeout))
+			return conn.Write([]byte(message))
+		})
+		if err != nil {
+			return streamError(err)
+		}
+	}
+
+	return nil
+}
+
+// close the connection to the syslog server
+func (exp *SyslogExporter) Close() error {
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	return exp.conn.Close()
+}
+
+// connect to the syslog server, over TLS for tcp+tls
+func (exp *SyslogExporter) dial(network, address string, config map[string]string, timeout time.Duration) (net.Conn, error) {
+	if network != "tcp+tls" {
+		return net.DialTimeout(network, address, timeout)
+	}
+
+	tlsConfig := exp.TLSConfig
+	if tlsConfig == nil {
+		var err error
+		tlsConfig, err = tlsFromConfig(config, "syslogCACert", "syslogClientCert", "syslogClientKey")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", address, tlsConfig)
+}
+
+// format a log as <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG
+func syslogMessage(log *otel.OTelLog, facility int, hostname string) string {
+	timestamp := "-"
+	if parsed, err := parseTimestamp(log.Timestamp); err == nil {
+		timestamp = parsed.Format("2006-01-02T15:04:05.000000Z07:00")
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s %s %s",
+		facility*8+syslogSeverity(log.Severity),
+		timestamp,
+		syslogHeaderField(hostname, 255),
+		syslogHeaderField(log.ServiceName, 48),
+		os.Getpid(),
+		syslogHeaderField(log.LoggerName, 32),
+		syslogStructuredData(log),
+		log.Message,
+	)
+}
+
+// map the logger levels to syslog severities
+func syslogSeverity(severity string) int {
+	switch severity {
+	case "DEBUG":
+		return 7
+	case "INFO":
+		return 6
+	case "WARNING":
+		return 4
+	case "ERROR":
+		return 3
+	default:
+		return 5
+	}
+}
+
+// header fields are printable ascii without spaces, "-" when empty
+func syslogHeaderField(value string, maxLength int) string {
+	field := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, value)
+
+	if len(field) > maxLength {
+		field = field[:maxLength]
+	}
+	if field == "" {
+		return "-"
+	}
+
+	return field
+}
+
+// trace context and attributes as [trace@32473 ...][attrs@32473 ...]
+func syslogStructuredData(log *otel.OTelLog) string {
+	var sd strings.Builder
+
+	sd.WriteString("[trace@" + syslogEnterpriseID)
+	sd.WriteString(` traceID="` + syslogParamValue(log.TraceID) + `"`)
+	sd.WriteString(` spanID="` + syslogParamValue(log.SpanID) + `"`)
+	sd.WriteString("]")
+
+	if len(log.Attributes) > 0 {
+		sd.WriteString("[attrs@" + syslogEnterpriseID)
+		for _, key := range slices.Sorted(maps.Keys(log.Attributes)) {
+			name := syslogParamName(key)
+			if name == "" {
+				continue
+			}
+			sd.WriteString(" " + name + `="` + syslogParamValue(log.Attributes[key]) + `"`)
+		}
+		sd.WriteString("]")
+	}
+
+	return sd.String()
+}
+
+// param names are up to 32 printable ascii characters except '=', ' ', ']' and '"'
+func syslogParamName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, name)
+
+	if len(name) > 32 {
+		name = name[:32]
+	}
+
+	return name
+}
+
+// escape the characters that are special inside param values
+func syslogParamValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
@@ -0,0 +1,221 @@
+package logExporter_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"otellogger/logExporter"
+	"otellogger/utils"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// expected messages for createTestLog with facility local0 and hostname "host"
+func expectedSyslogMessages() []string {
+	timestamp := func(ts string) string {
+		parsed, _ := time.ParseInLocation(utils.TimestampFormat, ts, time.Local)
+		return parsed.Format("2006-01-02T15:04:05.000000Z07:00")
+	}
+	pid := os.Getpid()
+
+	return []string{
+		fmt.Sprintf(`<134>1 %s host Default %d OTelLogger [trace@32473 traceID="1234567890" spanID="00000000000"][attrs@32473 key1="val1"] test message 1`,
+			timestamp("10.03.2025 17:00:00"), pid),
+		fmt.Sprintf(`<134>1 %s host Default %d OTelLogger [trace@32473 traceID="1234567890" spanID="00000000001"][attrs@32473 key2="val2"] test message 2`,
+			timestamp("10.03.2025 17:01:00"), pid),
+	}
+}
+
+func syslogTestConfig(network, address string) map[string]string {
+	return map[string]string{
+		"syslogNetwork":  network,
+		"syslogAddress":  address,
+		"syslogFacility": "local0",
+		"syslogHostname": "host",
+		"syslogTimeout":  "1s",
+	}
+}
+
+func readDatagrams(t *testing.T, conn net.PacketConn, count int) []string {
+	var messages []string
+	buf := make([]byte, 64*1024)
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for i := 0; i < count; i++ {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("Error reading datagram: %v", err)
+		}
+		messages = append(messages, string(buf[:n]))
+	}
+
+	return messages
+}
+
+// read octet-counted frames from a stream
+func readFrames(t *testing.T, reader *bufio.Reader, count int) []string {
+	var messages []string
+
+	for i := 0; i < count; i++ {
+		length, err := reader.ReadString(' ')
+		if err != nil {
+			t.Fatalf("Error reading frame length: %v", err)
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(length))
+		assert.Equal(t, nil, err)
+
+		message := make([]byte, n)
+		_, err = io.ReadFull(reader, message)
+		assert.Equal(t, nil, err)
+
+		messages = append(messages, string(message))
+	}
+
+	return messages
+}
+
+func TestExportLogsSyslog(t *testing.T) {
+	t.Run("Export logs to syslog over UDP successful", TestExportLogsSyslog_UDP)
+	t.Run("Export logs to syslog over TCP successful", TestExportLogsSyslog_TCP)
+	t.Run("Export logs to syslog over unix socket with reconnect", TestExportLogsSyslog_Unix)
+	t.Run("Error exporting logs to syslog - invalid config", TestExportLogsSyslog_InvalidConfig)
+	t.Run("Format syslog structured data", TestExportLogsSyslog_StructuredData)
+}
+
+func TestExportLogsSyslog_UDP(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	defer listener.Close()
+
+	exp := &logExporter.SyslogExporter{}
+	defer exp.Close()
+
+	err = exp.ExportLogs("1234567890", nil, nil)
+	assert.Equal(t, nil, err)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), syslogTestConfig("udp", listener.LocalAddr().String()))
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, expectedSyslogMessages(), readDatagrams(t, listener, 2))
+}
+
+func TestExportLogsSyslog_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	defer listener.Close()
+
+	received := make(chan []string)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		received <- readFrames(t, bufio.NewReader(conn), 2)
+	}()
+
+	exp := &logExporter.SyslogExporter{}
+	defer exp.Close()
+
+	err = exp.ExportLogs("1234567890", createTestLog(), syslogTestConfig("tcp", listener.Addr().String()))
+	assert.Equal(t, nil, err)
+
+	select {
+	case messages := <-received:
+		assert.Equal(t, expectedSyslogMessages(), messages)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no messages received")
+	}
+}
+
+func TestExportLogsSyslog_Unix(t *testing.T) {
+	path := t.TempDir() + "/log.sock"
+
+	listener, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+
+	exp := &logExporter.SyslogExporter{}
+	defer exp.Close()
+
+	config := syslogTestConfig("unixgram", path)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), config)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, expectedSyslogMessages(), readDatagrams(t, listener, 2))
+
+	// restart the server, the exporter has to reconnect
+	listener.Close()
+	os.Remove(path)
+
+	listener, err = net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	defer listener.Close()
+
+	err = exp.ExportLogs("1234567890", createTestLog(), config)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, expectedSyslogMessages(), readDatagrams(t, listener, 2))
+}
+
+func TestExportLogsSyslog_InvalidConfig(t *testing.T) {
+	exp := &logExporter.SyslogExporter{}
+	defer exp.Close()
+
+	config := syslogTestConfig("udp", "")
+	err := exp.ExportLogs("1234567890", createTestLog(), config)
+	assert.EqualError(t, err, "no syslogAddress in config")
+
+	config = syslogTestConfig("sctp", "127.0.0.1:514")
+	err = exp.ExportLogs("1234567890", createTestLog(), config)
+	assert.EqualError(t, err, `unsupported syslogNetwork "sctp"`)
+
+	config = syslogTestConfig("udp", "127.0.0.1:514")
+	config["syslogFacility"] = "local9"
+	err = exp.ExportLogs("1234567890", createTestLog(), config)
+	assert.EqualError(t, err, `unknown syslogFacility "local9"`)
+
+	// nothing listening on the socket
+	config = syslogTestConfig("unixgram", t.TempDir()+"/missing.sock")
+	err = exp.ExportLogs("1234567890", createTestLog(), config)
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, true, utils.IsRetryable(err))
+}
+
+func TestExportLogsSyslog_StructuredData(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	defer listener.Close()
+
+	exp := &logExporter.SyslogExporter{}
+	defer exp.Close()
+
+	logs := createTestLog()[:1]
+	logs[0].Severity = "ERROR"
+	logs[0].ServiceName = "my service"
+	logs[0].Attributes = map[string]string{"b key": `quote"d]`, "a=key": `back\slash`}
+
+	err = exp.ExportLogs("1234567890", logs, syslogTestConfig("udp", listener.LocalAddr().String()))
+	assert.Equal(t, nil, err)
+
+	message := readDatagrams(t, listener, 1)[0]
+	assert.True(t, strings.HasPrefix(message, "<131>1 "), message)
+	assert.Contains(t, message, " host myservice ")
+	assert.Contains(t, message, `[attrs@32473 a_key="back\\slash" b_key="quote\"d\]"] test message 1`)
+}
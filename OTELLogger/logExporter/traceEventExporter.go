@@ -0,0 +1,191 @@
+package logExporter
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"otellogger/otel"
+	"otellogger/utils"
+)
+
+// export transactions in the Trace Event format to open them in
+// chrome://tracing or Perfetto
+//
+// the transaction is a complete event on its own track, each span a complete
+// event on a track of its own, lasting from its first log until the next span
+// starts (or the transaction ends), and each log line an instant event on the
+// track of its span, with the attributes as args
+type TraceEventExporter struct{}
+
+type traceEventFile struct {
+	TraceEvents     []traceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+type traceEvent struct {
+	Name      string            `json:"name"`
+	Category  string            `json:"cat,omitempty"`
+	Phase     string            `json:"ph"`
+	Timestamp int64             `json:"ts"`
+	Duration  int64             `json:"dur,omitempty"`
+	Scope     string            `json:"s,omitempty"`
+	PID       int               `json:"pid"`
+	TID       int               `json:"tid"`
+	Args      map[string]string `json:"args,omitempty"`
+}
+
+const (
+	traceEventPID = 1
+	// track of the transaction, the spans follow on 2, 3, ...
+	traceEventTID = 1
+)
+
+// export logs from a transaction, rebuilding the transaction from its logs
+func (exp *TraceEventExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	// check if there are no logs to export
+	if len(logs) == 0 {
+		return nil
+	}
+
+	return exp.ExportTransaction(transactionFromLogs(traceID, logs), config)
+}
+
+// export a transaction as a trace event file
+func (exp *TraceEventExporter) ExportTransaction(transactionLog *otel.TransactionLog, config map[string]string) error {
+	if config == nil {
+		return utils.ErrNoConfig
+	}
+
+	// get the filepath from config
+	filepath, ok := config["filepath"]
+	if !ok {
+		return configError("filepath", "no filepath in config")
+	}
+
+	// get the way the filename will look like
+	filename, ok := config["filename"]
+	if !ok {
+		return configError("filename", "no filename in config")
+	}
+
+	// trace files will have the format filename_1234567890.trace.json
+	file, err := os.Create(filepath + filename + "_" + transactionLog.TraceID + ".trace.json")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return json.NewEncoder(file).Encode(traceEventFile{
+		TraceEvents:     traceEvents(transactionLog),
+		DisplayTimeUnit: "ms",
+	})
+}
+
+// build the events for a transaction, timestamps are in microseconds
+func traceEvents(transactionLog *otel.TransactionLog) []traceEvent {
+	start, end := transactionTimespan(transactionLog)
+
+	// name the process after the service and the track after the transaction
+	events := []traceEvent{
+		{
+			Name:  "process_name",
+			Phase: "M",
+			PID:   traceEventPID,
+			TID:   traceEventTID,
+			Args:  map[string]string{"name": transactionLog.ServiceName},
+		},
+		{
+			Name:  "thread_name",
+			Phase: "M",
+			PID:   traceEventPID,
+			TID:   traceEventTID,
+			Args:  map[string]string{"name": "trace " + transactionLog.TraceID},
+		},
+	}
+
+	args := maps.Clone(transactionLog.Attributes)
+	if args == nil {
+		args = make(map[string]string)
+	}
+	args["traceID"] = transactionLog.TraceID
+
+	events = append(events, traceEvent{
+		Name:      transactionName(transactionLog),
+		Category:  "transaction",
+		Phase:     "X",
+		Timestamp: start.UnixMicro(),
+		Duration:  max(end.Sub(start).Microseconds(), 1),
+		PID:       traceEventPID,
+		TID:       traceEventTID,
+		Args:      args,
+	})
+
+	// the logs of a span, in the order the spans started
+	var spanIDs []string
+	spanLogs := make(map[string][]*otel.OTelLog)
+	for _, log := range transactionLog.Spans {
+		if _, ok := spanLogs[log.SpanID]; !ok {
+			spanIDs = append(spanIDs, log.SpanID)
+		}
+		spanLogs[log.SpanID] = append(spanLogs[log.SpanID], log)
+	}
+
+	for i, spanID := range spanIDs {
+		tid := traceEventTID + 1 + i
+		logs := spanLogs[spanID]
+
+		// a span lasts until the next one starts, at least until its last log
+		spanStart := logTime(logs[0], start, end)
+		spanEnd := end
+		if i+1 < len(spanIDs) {
+			spanEnd = logTime(spanLogs[spanIDs[i+1]][0], start, end)
+		}
+		for _, log := range logs {
+			if logEnd := logTime(log, start, end); logEnd.After(spanEnd) {
+				spanEnd = logEnd
+			}
+		}
+
+		events = append(events,
+			traceEvent{
+				Name:  "thread_name",
+				Phase: "M",
+				PID:   traceEventPID,
+				TID:   tid,
+				Args:  map[string]string{"name": "span " + spanID},
+			},
+			traceEvent{
+				Name:      logs[0].LoggerName,
+				Category:  "span",
+				Phase:     "X",
+				Timestamp: spanStart.UnixMicro(),
+				Duration:  max(spanEnd.Sub(spanStart).Microseconds(), 1),
+				PID:       traceEventPID,
+				TID:       tid,
+				Args:      map[string]string{"spanID": spanID},
+			},
+		)
+
+		for _, log := range logs {
+			args := maps.Clone(log.Attributes)
+			if args == nil {
+				args = make(map[string]string)
+			}
+			args["spanID"] = log.SpanID
+			args["logger"] = log.LoggerName
+
+			events = append(events, traceEvent{
+				Name:      log.Message,
+				Category:  log.Severity,
+				Phase:     "i",
+				Timestamp: logTime(log, start, end).UnixMicro(),
+				Scope:     "t",
+				PID:       traceEventPID,
+				TID:       tid,
+				Args:      args,
+			})
+		}
+	}
+
+	return events
+}
@@ -0,0 +1,171 @@
+package logExporter_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"otellogger/logExporter"
+	"otellogger/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testTraceEvent struct {
+	Name      string            `json:"name"`
+	Category  string            `json:"cat"`
+	Phase     string            `json:"ph"`
+	Timestamp int64             `json:"ts"`
+	Duration  int64             `json:"dur"`
+	Scope     string            `json:"s"`
+	PID       int               `json:"pid"`
+	TID       int               `json:"tid"`
+	Args      map[string]string `json:"args"`
+}
+
+func readTraceEvents(t *testing.T, path string) []testTraceEvent {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Error reading file: %v", err)
+	}
+
+	var traceFile struct {
+		TraceEvents     []testTraceEvent `json:"traceEvents"`
+		DisplayTimeUnit string           `json:"displayTimeUnit"`
+	}
+	err = json.Unmarshal(content, &traceFile)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "ms", traceFile.DisplayTimeUnit)
+
+	return traceFile.TraceEvents
+}
+
+func TestExportLogsTraceEvent(t *testing.T) {
+	t.Run("Export transaction as trace events successful", TestExportLogsTraceEvent_Transaction)
+	t.Run("Export logs as trace events successful", TestExportLogsTraceEvent_Logs)
+	t.Run("Error exporting trace events - missing config", TestExportLogsTraceEvent_NoConfig)
+}
+
+func TestExportLogsTraceEvent_Transaction(t *testing.T) {
+	dir := t.TempDir() + "/"
+	transactionLog := createTestTransaction()
+	exp := logExporter.TraceEventExporter{}
+
+	err := exp.ExportTransaction(transactionLog, map[string]string{"filepath": dir, "filename": "test_trace"})
+	assert.Equal(t, nil, err)
+
+	events := readTraceEvents(t, dir+"test_trace_1234567890.trace.json")
+	if !assert.Len(t, events, 9) {
+		return
+	}
+
+	// metadata naming the process and track
+	assert.Equal(t, "M", events[0].Phase)
+	assert.Equal(t, map[string]string{"name": utils.ServiceName}, events[0].Args)
+	assert.Equal(t, "thread_name", events[1].Name)
+	assert.Equal(t, map[string]string{"name": "trace 1234567890"}, events[1].Args)
+
+	// the transaction as a complete event
+	assert.Equal(t, testTraceEvent{
+		Name:      "checkout",
+		Category:  "transaction",
+		Phase:     "X",
+		Timestamp: transactionLog.StartTime.UnixMicro(),
+		Duration:  (2 * time.Minute).Microseconds(),
+		PID:       1,
+		TID:       1,
+		Args:      map[string]string{"name": "checkout", "user": "42", "traceID": "1234567890"},
+	}, events[2])
+
+	// each span on its own track, lasting until the next one
+	assert.Equal(t, testTraceEvent{
+		Name:  "thread_name",
+		Phase: "M",
+		PID:   1,
+		TID:   2,
+		Args:  map[string]string{"name": "span 00000000000"},
+	}, events[3])
+	assert.Equal(t, testTraceEvent{
+		Name:      utils.LoggerName,
+		Category:  "span",
+		Phase:     "X",
+		Timestamp: transactionLog.StartTime.Add(time.Second).UnixMicro(),
+		Duration:  time.Minute.Microseconds(),
+		PID:       1,
+		TID:       2,
+		Args:      map[string]string{"spanID": "00000000000"},
+	}, events[4])
+
+	// with its log lines as instant events
+	assert.Equal(t, testTraceEvent{
+		Name:      "test message 1",
+		Category:  "INFO",
+		Phase:     "i",
+		Timestamp: transactionLog.StartTime.Add(time.Second).UnixMicro(),
+		Scope:     "t",
+		PID:       1,
+		TID:       2,
+		Args:      map[string]string{"key1": "val1", "spanID": "00000000000", "logger": utils.LoggerName},
+	}, events[5])
+
+	// the last one lasts until the end of the transaction
+	assert.Equal(t, 3, events[7].TID)
+	assert.Equal(t, transactionLog.StartTime.Add(61*time.Second).UnixMicro(), events[7].Timestamp)
+	assert.Equal(t, (59 * time.Second).Microseconds(), events[7].Duration)
+	assert.Equal(t, "test message 2", events[8].Name)
+	assert.Equal(t, 3, events[8].TID)
+
+	// logs of the same span share its track
+	transactionLog.Spans[1].SpanID = transactionLog.Spans[0].SpanID
+
+	err = exp.ExportTransaction(transactionLog, map[string]string{"filepath": dir, "filename": "test_trace"})
+	assert.Equal(t, nil, err)
+
+	events = readTraceEvents(t, dir+"test_trace_1234567890.trace.json")
+	if !assert.Len(t, events, 7) {
+		return
+	}
+	assert.Equal(t, (2*time.Minute - time.Second).Microseconds(), events[4].Duration)
+	assert.Equal(t, []string{"test message 1", "test message 2"}, []string{events[5].Name, events[6].Name})
+	assert.Equal(t, 2, events[6].TID)
+}
+
+func TestExportLogsTraceEvent_Logs(t *testing.T) {
+	dir := t.TempDir() + "/"
+	exp := logExporter.TraceEventExporter{}
+
+	err := exp.ExportLogs("1234567890", nil, nil)
+	assert.Equal(t, nil, err)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"filepath": dir, "filename": "test_trace"})
+	assert.Equal(t, nil, err)
+
+	events := readTraceEvents(t, dir+"test_trace_1234567890.trace.json")
+	if !assert.Len(t, events, 9) {
+		return
+	}
+
+	assert.Equal(t, utils.LoggerName, events[2].Name)
+	assert.Equal(t, time.Minute.Microseconds(), events[2].Duration)
+	assert.Equal(t, events[2].Timestamp, events[4].Timestamp)
+
+	// the last span of a transaction rebuilt from its logs ends with its last log
+	assert.Equal(t, int64(1), events[7].Duration)
+}
+
+func TestExportLogsTraceEvent_NoConfig(t *testing.T) {
+	exp := logExporter.TraceEventExporter{}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.Equal(t, errors.New("no config provided"), err)
+	assert.ErrorIs(t, err, utils.ErrNoConfig)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"filename": "test_trace"})
+	assert.EqualError(t, err, "no filepath in config")
+	assert.ErrorIs(t, err, utils.ErrInvalidConfig)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"filepath": ""})
+	assert.EqualError(t, err, "no filename in config")
+	assert.ErrorIs(t, err, utils.ErrInvalidConfig)
+}
@@ -0,0 +1,82 @@
+package logExporter
+
+import (
+	"otellogger/otel"
+	"time"
+)
+
+// trace exporters work on whole transactions, when only the logs are passed
+// in (ExportLogs) the transaction is rebuilt from them
+func transactionFromLogs(traceID string, logs []*otel.OTelLog) *otel.TransactionLog {
+	transactionLog := &otel.TransactionLog{
+		TraceID: traceID,
+		Spans:   logs,
+	}
+
+	if len(logs) > 0 {
+		transactionLog.LoggerName = logs[0].LoggerName
+		transactionLog.ServiceName = logs[0].ServiceName
+	}
+
+	return transactionLog
+}
+
+// get the name shown for the transaction in trace viewers
+func transactionName(transactionLog *otel.TransactionLog) string {
+	if name := transactionLog.Attributes["name"]; name != "" {
+		return name
+	}
+	if transactionLog.LoggerName != "" {
+		return transactionLog.LoggerName
+	}
+	return "transaction"
+}
+
+// get the start and end of a transaction, falling back to the log timestamps
+// when the transaction wasn't timed (e.g. rebuilt from logs)
+func transactionTimespan(transactionLog *otel.TransactionLog) (time.Time, time.Time) {
+	start, end := transactionLog.StartTime, transactionLog.EndTime
+
+	for _, log := range transactionLog.Spans {
+		timestamp, err := parseTimestamp(log.Timestamp)
+		if err != nil {
+			continue
+		}
+
+		if transactionLog.StartTime.IsZero() && (start.IsZero() || timestamp.Before(start)) {
+			start = timestamp
+		}
+		if transactionLog.EndTime.IsZero() && timestamp.After(end) {
+			end = timestamp
+		}
+	}
+
+	if end.Before(start) {
+		end = start
+	}
+
+	return start, end
+}
+
+// get the time of a log, kept inside the transaction timespan since log
+// timestamps only have second precision
+func logTime(log *otel.OTelLog, start, end time.Time) time.Time {
+	timestamp, err := parseTimestamp(log.Timestamp)
+	if err != nil || timestamp.Before(start) {
+		return start
+	}
+	if timestamp.After(end) {
+		return end
+	}
+	return timestamp
+}
+
+// export a transaction with ExportTransaction if the exporter supports it,
+// otherwise just its logs
+func exportTransaction(exp LogExporter, transactionLog *otel.TransactionLog, config map[string]string) error {
+	if transactionExp, ok := exp.(TransactionExporter); ok {
+		return transactionExp.ExportTransaction(transactionLog, config)
+	}
+
+	return exp.ExportLogs(transactionLog.TraceID, transactionLog.Spans, config)
+}
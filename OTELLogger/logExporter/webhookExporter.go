@@ -0,0 +1,189 @@
+package logExporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"otellogger/otel"
+	"sync"
+	"text/template"
+)
+
+// export logs to any http endpoint with a templated body (chat notifications,
+// incident tools, ingestion apis...)
+//
+// the template gets the log (an *otel.OTelLog) when sending per log, or the
+// transaction (an *otel.TransactionLog with the filtered logs in Spans) when
+// sending per transaction, and has a "json" function to embed values safely
+//
+// config keys (plus the shared HTTPConfig keys):
+//   - webhookURL: url to send to (required)
+//   - webhookMethod: http method (default "POST")
+//   - webhookHeaders: request headers as "key=value,key2=value2"
+//   - webhookContentType: content type of the body (default "application/json")
+//   - webhookTemplate: body template, or webhookTemplateFile to read it from a file
+//     (default the log or transaction as json)
+//   - webhookMode: "log" (default) to send a request per log or "transaction"
+//   - webhookMinLevel: only send logs of this level or higher (e.g. "WARNING")
+type WebhookExporter struct {
+	HTTPConfig
+
+	mu        sync.Mutex
+	templates map[string]*template.Template // parsed templates by their text
+}
+
+const defaultWebhookTemplate = "{{json .}}"
+
+var webhookFuncs = template.FuncMap{
+	"json": func(value any) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+// export logs from a transaction, rebuilding the transaction from its logs
+func (exp *WebhookExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	// check if there are no logs to export
+	if len(logs) == 0 {
+		return nil
+	}
+
+	return exp.ExportTransaction(transactionFromLogs(traceID, logs), config)
+}
+
+// send the logs of a transaction that pass the level filter
+func (exp *WebhookExporter) ExportTransaction(transactionLog *otel.TransactionLog, config map[string]string) error {
+	url, err := configValue(config, "webhookURL")
+	if err != nil {
+		return err
+	}
+
+	minLevel := 0
+	if level := config["webhookMinLevel"]; level != "" {
+		minLevel = levelRank(level)
+		if minLevel == 0 {
+			return configError("webhookMinLevel", "unknown webhookMinLevel %q", level)
+		}
+	}
+
+	var logs []*otel.OTelLog
+	for _, log := range transactionLog.Spans {
+		if levelRank(log.Severity) >= minLevel {
+			logs = append(logs, log)
+		}
+	}
+
+	// nothing important enough to send
+	if len(logs) == 0 {
+		return nil
+	}
+
+	tmpl, err := exp.template(config)
+	if err != nil {
+		return err
+	}
+
+	switch config["webhookMode"] {
+	case "", "log":
+		for _, log := range logs {
+			err := exp.sendTemplate(url, tmpl, log, config)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case "transaction":
+		filtered := *transactionLog
+		filtered.Spans = logs
+		return exp.sendTemplate(url, tmpl, &filtered, config)
+	default:
+		return configError("webhookMode", "unsupported webhookMode %q", config["webhookMode"])
+	}
+}
+
+// render the body and send it
+func (exp *WebhookExporter) sendTemplate(url string, tmpl *template.Template, data any, config map[string]string) error {
+	var body bytes.Buffer
+	err := tmpl.Execute(&body, data)
+	if err != nil {
+		return err
+	}
+
+	method := config["webhookMethod"]
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	req, err := http.NewRequest(method, url, &body)
+	if err != nil {
+		return err
+	}
+
+	contentType := config["webhookContentType"]
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	headers, err := parsePairs(config["webhookHeaders"])
+	if err != nil {
+		return configError("webhookHeaders", "invalid webhookHeaders in config: %w", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	_, err = exp.send(req, config)
+	return err
+}
+
+// get the parsed body template, parsing it on first use
+func (exp *WebhookExporter) template(config map[string]string) (*template.Template, error) {
+	text := config["webhookTemplate"]
+	if file := config["webhookTemplateFile"]; text == "" && file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+	if text == "" {
+		text = defaultWebhookTemplate
+	}
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+
+	if tmpl, ok := exp.templates[text]; ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := template.New("webhook").Funcs(webhookFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, configError("webhookTemplate", "invalid webhook template: %w", err)
+	}
+
+	if exp.templates == nil {
+		exp.templates = make(map[string]*template.Template)
+	}
+	exp.templates[text] = tmpl
+
+	return tmpl, nil
+}
+
+// order of the log levels, 0 for unknown levels
+func levelRank(severity string) int {
+	switch severity {
+	case "DEBUG":
+		return 1
+	case "INFO":
+		return 2
+	case "WARNING":
+		return 3
+	case "ERROR":
+		return 4
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,153 @@
+package logExporter_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"otellogger/logExporter"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testWebhookRequest struct {
+	method string
+	header http.Header
+	body   string
+}
+
+func startTestWebhook(t *testing.T, requests *[]testWebhookRequest) *httptest.Server {
+	var mu sync.Mutex
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		body, _ := io.ReadAll(r.Body)
+		*requests = append(*requests, testWebhookRequest{method: r.Method, header: r.Header, body: string(body)})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestExportLogsWebhook(t *testing.T) {
+	t.Run("Export logs to webhook per log successful", TestExportLogsWebhook_PerLog)
+	t.Run("Export transaction to webhook successful", TestExportLogsWebhook_Transaction)
+	t.Run("Export logs to webhook with shared http client", TestExportLogsWebhook_SharedClient)
+	t.Run("Error exporting logs to webhook - invalid config", TestExportLogsWebhook_InvalidConfig)
+}
+
+func TestExportLogsWebhook_PerLog(t *testing.T) {
+	var requests []testWebhookRequest
+	server := startTestWebhook(t, &requests)
+
+	exp := &logExporter.WebhookExporter{}
+
+	err := exp.ExportLogs("1234567890", nil, nil)
+	assert.Equal(t, nil, err)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"webhookURL": server.URL})
+	assert.Equal(t, nil, err)
+
+	// the default template sends each log as json
+	if !assert.Len(t, requests, 2) {
+		return
+	}
+	assert.Equal(t, http.MethodPost, requests[0].method)
+	assert.Equal(t, "application/json", requests[0].header.Get("Content-Type"))
+	assert.Equal(t, `{"Timestamp":"10.03.2025 17:00:00","Severity":"INFO","Message":"test message 1",`+
+		`"LoggerName":"OTelLogger","ServiceName":"Default","TraceID":"1234567890","SpanID":"00000000000",`+
+		`"Attributes":{"key1":"val1"}}`, requests[0].body)
+
+	// only logs of the minimum level or higher are sent
+	logs := createTestLog()
+	logs[1].Severity = "ERROR"
+
+	requests = nil
+	err = exp.ExportLogs("1234567890", logs, map[string]string{
+		"webhookURL":         server.URL,
+		"webhookMethod":      http.MethodPut,
+		"webhookHeaders":     "X-Token=secret",
+		"webhookContentType": "text/plain",
+		"webhookTemplate":    `{{.Severity}} in {{.ServiceName}}: {{.Message}} ({{index .Attributes "key2"}})`,
+		"webhookMinLevel":    "WARNING",
+	})
+	assert.Equal(t, nil, err)
+
+	if !assert.Len(t, requests, 1) {
+		return
+	}
+	assert.Equal(t, http.MethodPut, requests[0].method)
+	assert.Equal(t, "secret", requests[0].header.Get("X-Token"))
+	assert.Equal(t, "text/plain", requests[0].header.Get("Content-Type"))
+	assert.Equal(t, "ERROR in Default: test message 2 (val2)", requests[0].body)
+}
+
+func TestExportLogsWebhook_Transaction(t *testing.T) {
+	var requests []testWebhookRequest
+	server := startTestWebhook(t, &requests)
+
+	templateFile := t.TempDir() + "/template.json"
+	err := os.WriteFile(templateFile, []byte(`{"text": {{json (printf "%s failed with %d logs" .Attributes.name (len .Spans))}}, "trace": {{json .TraceID}}}`), 0644)
+	if err != nil {
+		t.Fatalf("Error writing template: %v", err)
+	}
+
+	exp := &logExporter.WebhookExporter{}
+
+	err = exp.ExportTransaction(createTestTransaction(), map[string]string{
+		"webhookURL":          server.URL,
+		"webhookMode":         "transaction",
+		"webhookTemplateFile": templateFile,
+	})
+	assert.Equal(t, nil, err)
+
+	if !assert.Len(t, requests, 1) {
+		return
+	}
+	assert.Equal(t, `{"text": "checkout failed with 2 logs", "trace": "1234567890"}`, requests[0].body)
+
+	// nothing is sent if no log passes the filter
+	requests = nil
+	err = exp.ExportTransaction(createTestTransaction(), map[string]string{
+		"webhookURL":      server.URL,
+		"webhookMode":     "transaction",
+		"webhookMinLevel": "ERROR",
+	})
+	assert.Equal(t, nil, err)
+	assert.Len(t, requests, 0)
+}
+
+func TestExportLogsWebhook_SharedClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	exp := &logExporter.WebhookExporter{
+		HTTPConfig: logExporter.HTTPConfig{Client: &http.Client{Timeout: 50 * time.Millisecond}},
+	}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{"webhookURL": server.URL})
+	assert.ErrorContains(t, err, "Client.Timeout exceeded")
+}
+
+func TestExportLogsWebhook_InvalidConfig(t *testing.T) {
+	exp := &logExporter.WebhookExporter{}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{})
+	assert.EqualError(t, err, "no webhookURL in config")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"webhookURL": "http://localhost", "webhookMinLevel": "FATAL"})
+	assert.EqualError(t, err, `unknown webhookMinLevel "FATAL"`)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"webhookURL": "http://localhost", "webhookMode": "batch"})
+	assert.EqualError(t, err, `unsupported webhookMode "batch"`)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"webhookURL": "http://localhost", "webhookTemplate": "{{.Message"})
+	assert.ErrorContains(t, err, "invalid webhook template")
+}
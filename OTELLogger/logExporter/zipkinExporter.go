@@ -0,0 +1,147 @@
+package logExporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"otellogger/otel"
+	"strings"
+	"time"
+)
+
+// export transactions as traces to Zipkin using the v2 JSON api
+//
+// each transaction becomes a root span tagged with its attributes, its logs
+// are added either as annotations on the root span or as child spans
+//
+// config keys (plus the shared HTTPConfig keys):
+//   - zipkinEndpoint: Zipkin base url, e.g. "http://localhost:9411" (required)
+//   - zipkinLogMode: "annotations" (default) or "spans"
+type ZipkinExporter struct {
+	HTTPConfig
+}
+
+type zipkinSpan struct {
+	TraceID       string             `json:"traceId"`
+	ID            string             `json:"id"`
+	ParentID      string             `json:"parentId,omitempty"`
+	Name          string             `json:"name"`
+	Timestamp     int64              `json:"timestamp"`
+	Duration      int64              `json:"duration"`
+	LocalEndpoint zipkinEndpoint     `json:"localEndpoint"`
+	Annotations   []zipkinAnnotation `json:"annotations,omitempty"`
+	Tags          map[string]string  `json:"tags,omitempty"`
+}
+
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+}
+
+type zipkinAnnotation struct {
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+// export logs from a transaction, rebuilding the transaction from its logs
+func (exp *ZipkinExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	// check if there are no logs to export
+	if len(logs) == 0 {
+		return nil
+	}
+
+	return exp.ExportTransaction(transactionFromLogs(traceID, logs), config)
+}
+
+// export a transaction as a trace
+func (exp *ZipkinExporter) ExportTransaction(transactionLog *otel.TransactionLog, config map[string]string) error {
+	endpoint, err := configValue(config, "zipkinEndpoint")
+	if err != nil {
+		return err
+	}
+
+	spans, err := zipkinSpans(transactionLog, config["zipkinLogMode"])
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(spans)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(endpoint, "/")+"/api/v2/spans", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = exp.send(req, config)
+	return err
+}
+
+// build the root span of the transaction and its children
+func zipkinSpans(transactionLog *otel.TransactionLog, logMode string) ([]*zipkinSpan, error) {
+	start, end := transactionTimespan(transactionLog)
+	endpoint := zipkinEndpoint{ServiceName: transactionLog.ServiceName}
+
+	root := &zipkinSpan{
+		TraceID:       traceIDHex(transactionLog.TraceID),
+		ID:            spanIDHex(transactionLog.TraceID),
+		Name:          transactionName(transactionLog),
+		Timestamp:     start.UnixMicro(),
+		Duration:      zipkinDuration(start, end),
+		LocalEndpoint: endpoint,
+		Tags:          maps.Clone(transactionLog.Attributes),
+	}
+	spans := []*zipkinSpan{root}
+
+	switch logMode {
+	case "", "annotations":
+		for _, log := range transactionLog.Spans {
+			root.Annotations = append(root.Annotations, zipkinAnnotation{
+				Timestamp: logTime(log, start, end).UnixMicro(),
+				Value:     fmt.Sprintf("[%s] %s", log.Severity, log.Message),
+			})
+		}
+	case "spans":
+		for i, log := range transactionLog.Spans {
+			logStart := logTime(log, start, end)
+
+			// each log lasts until the next one starts
+			logEnd := end
+			if i+1 < len(transactionLog.Spans) {
+				logEnd = logTime(transactionLog.Spans[i+1], start, end)
+			}
+
+			tags := maps.Clone(log.Attributes)
+			if tags == nil {
+				tags = make(map[string]string)
+			}
+			// prefixed so they don't overwrite attributes with the same name
+			tags["otel.level"] = log.Severity
+			tags["otel.logger"] = log.LoggerName
+
+			spans = append(spans, &zipkinSpan{
+				TraceID:       root.TraceID,
+				ID:            spanIDHex(log.SpanID),
+				ParentID:      root.ID,
+				Name:          log.Message,
+				Timestamp:     logStart.UnixMicro(),
+				Duration:      zipkinDuration(logStart, logEnd),
+				LocalEndpoint: endpoint,
+				Tags:          tags,
+			})
+		}
+	default:
+		return nil, configError("zipkinLogMode", "unsupported zipkinLogMode %q", logMode)
+	}
+
+	return spans, nil
+}
+
+// zipkin durations are in microseconds and must be at least 1
+func zipkinDuration(start, end time.Time) int64 {
+	return max(end.Sub(start).Microseconds(), 1)
+}
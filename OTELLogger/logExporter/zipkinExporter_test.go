@@ -0,0 +1,183 @@
+package logExporter_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"otellogger/logExporter"
+	"otellogger/otel"
+	"otellogger/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testZipkinSpan struct {
+	TraceID       string            `json:"traceId"`
+	ID            string            `json:"id"`
+	ParentID      string            `json:"parentId"`
+	Name          string            `json:"name"`
+	Timestamp     int64             `json:"timestamp"`
+	Duration      int64             `json:"duration"`
+	LocalEndpoint map[string]string `json:"localEndpoint"`
+	Annotations   []struct {
+		Timestamp int64  `json:"timestamp"`
+		Value     string `json:"value"`
+	} `json:"annotations"`
+	Tags map[string]string `json:"tags"`
+}
+
+// start a zipkin stand-in decoding the posted spans
+func startTestZipkin(t *testing.T, statusCode int, spans *[]testZipkinSpan) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v2/spans", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		body, _ := io.ReadAll(r.Body)
+		if spans != nil {
+			assert.Equal(t, nil, json.Unmarshal(body, spans))
+		}
+
+		w.WriteHeader(statusCode)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func createTestTransaction() *otel.TransactionLog {
+	start, _ := time.ParseInLocation(utils.TimestampFormat, "10.03.2025 16:59:59", time.Local)
+
+	return &otel.TransactionLog{
+		TraceID:     "1234567890",
+		LoggerName:  utils.LoggerName,
+		ServiceName: utils.ServiceName,
+		Spans:       createTestLog(),
+		Attributes:  map[string]string{"name": "checkout", "user": "42"},
+		StartTime:   start,
+		EndTime:     start.Add(2 * time.Minute),
+	}
+}
+
+func TestExportLogsZipkin(t *testing.T) {
+	t.Run("Export transaction to zipkin with annotations successful", TestExportLogsZipkin_Annotations)
+	t.Run("Export transaction to zipkin with child spans successful", TestExportLogsZipkin_Spans)
+	t.Run("Export logs to zipkin without transaction successful", TestExportLogsZipkin_Logs)
+	t.Run("Error exporting to zipkin - no endpoint in config", TestExportLogsZipkin_NoEndpoint)
+	t.Run("Error exporting to zipkin - error status", TestExportLogsZipkin_ErrorStatus)
+}
+
+func TestExportLogsZipkin_Annotations(t *testing.T) {
+	var spans []testZipkinSpan
+	server := startTestZipkin(t, http.StatusAccepted, &spans)
+
+	transactionLog := createTestTransaction()
+	exp := logExporter.ZipkinExporter{}
+
+	err := exp.ExportTransaction(transactionLog, map[string]string{"zipkinEndpoint": server.URL + "/"})
+	assert.Equal(t, nil, err)
+
+	if !assert.Len(t, spans, 1) {
+		return
+	}
+
+	root := spans[0]
+	assert.Equal(t, "000000000000000000000000499602d2", root.TraceID)
+	assert.Equal(t, "00000000499602d2", root.ID)
+	assert.Equal(t, "", root.ParentID)
+	assert.Equal(t, "checkout", root.Name)
+	assert.Equal(t, transactionLog.StartTime.UnixMicro(), root.Timestamp)
+	assert.Equal(t, (2 * time.Minute).Microseconds(), root.Duration)
+	assert.Equal(t, utils.ServiceName, root.LocalEndpoint["serviceName"])
+	assert.Equal(t, map[string]string{"name": "checkout", "user": "42"}, root.Tags)
+
+	if !assert.Len(t, root.Annotations, 2) {
+		return
+	}
+	assert.Equal(t, "[INFO] test message 1", root.Annotations[0].Value)
+	assert.Equal(t, transactionLog.StartTime.Add(time.Second).UnixMicro(), root.Annotations[0].Timestamp)
+	assert.Equal(t, "[INFO] test message 2", root.Annotations[1].Value)
+}
+
+func TestExportLogsZipkin_Spans(t *testing.T) {
+	var spans []testZipkinSpan
+	server := startTestZipkin(t, http.StatusAccepted, &spans)
+
+	transactionLog := createTestTransaction()
+	exp := logExporter.ZipkinExporter{}
+
+	err := exp.ExportTransaction(transactionLog, map[string]string{"zipkinEndpoint": server.URL, "zipkinLogMode": "spans"})
+	assert.Equal(t, nil, err)
+
+	if !assert.Len(t, spans, 3) {
+		return
+	}
+
+	assert.Len(t, spans[0].Annotations, 0)
+
+	child := spans[1]
+	assert.Equal(t, spans[0].TraceID, child.TraceID)
+	assert.Equal(t, spans[0].ID, child.ParentID)
+	assert.Equal(t, "0000000000000000", child.ID)
+	assert.Equal(t, "test message 1", child.Name)
+	assert.Equal(t, time.Minute.Microseconds(), child.Duration)
+	assert.Equal(t, map[string]string{"key1": "val1", "otel.level": "INFO", "otel.logger": utils.LoggerName}, child.Tags)
+
+	// the last log lasts until the end of the transaction
+	assert.Equal(t, "0000000000000001", spans[2].ID)
+	assert.Equal(t, (59 * time.Second).Microseconds(), spans[2].Duration)
+
+	err = exp.ExportTransaction(transactionLog, map[string]string{"zipkinEndpoint": server.URL, "zipkinLogMode": "events"})
+	assert.EqualError(t, err, `unsupported zipkinLogMode "events"`)
+}
+
+func TestExportLogsZipkin_Logs(t *testing.T) {
+	var spans []testZipkinSpan
+	server := startTestZipkin(t, http.StatusAccepted, &spans)
+
+	exp := logExporter.ZipkinExporter{}
+
+	err := exp.ExportLogs("1234567890", nil, nil)
+	assert.Equal(t, nil, err)
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"zipkinEndpoint": server.URL})
+	assert.Equal(t, nil, err)
+
+	if !assert.Len(t, spans, 1) {
+		return
+	}
+
+	// the timespan comes from the first and last log
+	first, _ := time.ParseInLocation(utils.TimestampFormat, "10.03.2025 17:00:00", time.Local)
+	assert.Equal(t, utils.LoggerName, spans[0].Name)
+	assert.Equal(t, first.UnixMicro(), spans[0].Timestamp)
+	assert.Equal(t, time.Minute.Microseconds(), spans[0].Duration)
+	assert.Equal(t, utils.ServiceName, spans[0].LocalEndpoint["serviceName"])
+}
+
+func TestExportLogsZipkin_NoEndpoint(t *testing.T) {
+	exp := logExporter.ZipkinExporter{}
+
+	err := exp.ExportLogs("1234567890", createTestLog(), nil)
+	assert.EqualError(t, err, "no config provided")
+
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{})
+	assert.EqualError(t, err, "no zipkinEndpoint in config")
+}
+
+func TestExportLogsZipkin_ErrorStatus(t *testing.T) {
+	exp := logExporter.ZipkinExporter{}
+
+	server := startTestZipkin(t, http.StatusServiceUnavailable, nil)
+	err := exp.ExportLogs("1234567890", createTestLog(), map[string]string{"zipkinEndpoint": server.URL})
+	assert.EqualError(t, err, "unexpected status code 503")
+	assert.Equal(t, true, utils.IsRetryable(err))
+
+	server = startTestZipkin(t, http.StatusBadRequest, nil)
+	err = exp.ExportLogs("1234567890", createTestLog(), map[string]string{"zipkinEndpoint": server.URL})
+	assert.EqualError(t, err, "unexpected status code 400")
+	assert.Equal(t, false, utils.IsRetryable(err))
+}
@@ -1,20 +1,24 @@
 package logger
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
+	"maps"
 	"os"
 	"otellogger/logExporter"
 	"otellogger/otel"
 	"otellogger/utils"
+	"slices"
 	"sync"
 	"time"
 )
 
-// driver interface
-type LogExporter interface {
-	ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error
-}
+// driver interface (defined next to the exporters so the wrapping
+// exporters can use it too)
+type LogExporter = logExporter.LogExporter
+
+// optional driver interface for exporters that need the whole transaction
+type TransactionExporter = logExporter.TransactionExporter
 
 type Logger struct {
 	LoggerName      string
@@ -149,18 +153,18 @@ func (l *Logger) createLog(level Level, traceID, message string, attrs map[strin
 		l.mu.Lock()
 		defer l.mu.Unlock()
 
-		timestamp := time.Now().Format("02.01.2006 15:04:05")
+		timestamp := time.Now().Format(utils.TimestampFormat)
 
 		// check if the transaction log exists
 		_, ok := l.TransactionLogs[traceID]
 		if !ok {
-			return errors.New("invalid trace ID")
+			return utils.ErrInvalidTraceID
 		}
 
 		// create the new log and add it to the transaction log
 		lvl := l.getLevel(level)
 		if lvl == "UNKNOWN LEVEL" {
-			return errors.New("unknown log level")
+			return utils.ErrUnknownLevel
 		}
 
 		otelLog := otel.NewOTelLog(l.LoggerName, traceID, l.ServiceName, timestamp, l.getLevel(level), message, attrs)
@@ -189,19 +193,48 @@ func (l *Logger) Error(message, traceID string, attrs map[string]string) error {
 // export logs for a transaction
 func (l *Logger) ExportLogs(traceID string) error {
 	l.mu.Lock()
-	defer l.mu.Unlock()
 
 	transactionLog, ok := l.TransactionLogs[traceID]
 	if !ok {
-		return errors.New("invalid trace ID")
+		l.mu.Unlock()
+		return utils.ErrInvalidTraceID
+	}
+
+	// export a copy so logging can go on while the exporter is busy, and time
+	// it so a failed export doesn't leave the transaction ended and a retry
+	// reports how long it really took
+	exported := *transactionLog
+	exported.Spans = slices.Clone(transactionLog.Spans)
+	if exported.EndTime.IsZero() {
+		exported.EndTime = time.Now()
 	}
+	config := l.config
 
-	err := l.LogExporter.ExportLogs(transactionLog.TraceID, transactionLog.Spans, l.config)
+	l.mu.Unlock()
+
+	var err error
+	if exp, ok := l.LogExporter.(TransactionExporter); ok {
+		err = exp.ExportTransaction(&exported, config)
+	} else {
+		err = l.LogExporter.ExportLogs(exported.TraceID, exported.Spans, config)
+	}
 	if err != nil {
 		return err
 	}
 
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.TransactionLogs[traceID] != transactionLog {
+		return nil
+	}
+
+	// logs added during the export are kept for the next one, otherwise
 	// remove transaction log from map
+	if len(transactionLog.Spans) > len(exported.Spans) {
+		transactionLog.Spans = transactionLog.Spans[len(exported.Spans):]
+		return nil
+	}
 	delete(l.TransactionLogs, traceID)
 
 	return nil
@@ -209,22 +242,27 @@ func (l *Logger) ExportLogs(traceID string) error {
 
 // export all logs from all transactions
 func (l *Logger) ExportAllLogs() error {
+	// the exports remove the transactions from the map, so range over the trace IDs
+	l.mu.Lock()
+	traceIDs := slices.Collect(maps.Keys(l.TransactionLogs))
+	l.mu.Unlock()
+
 	var wg sync.WaitGroup
-	errChan := make(chan error, len(l.TransactionLogs))
+	errChan := make(chan error, len(traceIDs))
 
 	// export each transaction log on a separate goroutine
-	for _, tLog := range l.TransactionLogs {
+	for _, traceID := range traceIDs {
 		wg.Add(1)
 
-		go func(tLog *otel.TransactionLog) {
+		go func(traceID string) {
 			defer wg.Done()
 
-			err := l.ExportLogs(tLog.TraceID)
+			err := l.ExportLogs(traceID)
 			if err != nil {
 				errChan <- err
 				return
 			}
-		}(tLog)
+		}(traceID)
 	}
 
 	wg.Wait()
@@ -238,3 +276,14 @@ func (l *Logger) ExportAllLogs() error {
 
 	return nil
 }
+
+// wait until the logs exported so far have left a background exporter (e.g. a logExporter.BatchProcessor)
+func (l *Logger) Flush(ctx context.Context) error {
+	return logExporter.FlushExporter(ctx, l.LogExporter)
+}
+
+// export everything left in the exporter and stop it, closing its connections
+// and files
+func (l *Logger) Shutdown(ctx context.Context) error {
+	return logExporter.ShutdownExporter(ctx, l.LogExporter)
+}
@@ -2,6 +2,7 @@ package logger_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -265,6 +266,7 @@ func TestDebug_Error(t *testing.T) {
 	err := l.Debug("debug log", "invalid trace ID", map[string]string{"key1": "val1"})
 	assert.NotEqual(t, nil, err)
 	assert.Equal(t, "invalid trace ID", err.Error())
+	assert.ErrorIs(t, err, utils.ErrInvalidTraceID)
 }
 
 func TestInfo(t *testing.T) {
@@ -313,6 +315,7 @@ func TestInfo_Error(t *testing.T) {
 	err := l.Info("info log", "invalid trace ID", map[string]string{"key1": "val1"})
 	assert.NotEqual(t, nil, err)
 	assert.Equal(t, "invalid trace ID", err.Error())
+	assert.ErrorIs(t, err, utils.ErrInvalidTraceID)
 }
 
 func TestWarning(t *testing.T) {
@@ -357,6 +360,7 @@ func TestWarning_Error(t *testing.T) {
 	err := l.Warning("warning log", "invalid trace ID", map[string]string{"key1": "val1"})
 	assert.NotEqual(t, nil, err)
 	assert.Equal(t, "invalid trace ID", err.Error())
+	assert.ErrorIs(t, err, utils.ErrInvalidTraceID)
 }
 
 func TestError(t *testing.T) {
@@ -397,6 +401,7 @@ func TestError_Error(t *testing.T) {
 	err := l.Error("error log", "invalid trace ID", map[string]string{"key1": "val1"})
 	assert.NotEqual(t, nil, err)
 	assert.Equal(t, "invalid trace ID", err.Error())
+	assert.ErrorIs(t, err, utils.ErrInvalidTraceID)
 }
 
 func TestCustomExporter(t *testing.T) {
@@ -465,6 +470,8 @@ func TestExportLogs(t *testing.T) {
 	t.Run("Export logs successful", TestExportLogs_Success)
 	t.Run("Error exporting logs - invalid trace ID", TestExportLogs_ErrorInvalidTraceID)
 	t.Run("Error exporting logs - log exporter returns error", TestExportLogs_ErrorOnLogExporter)
+	t.Run("Export logs with transaction exporter successful", TestExportLogs_TransactionExporter)
+	t.Run("Export logs while logging to the transaction successful", TestExportLogs_LogDuringExport)
 }
 
 func TestExportLogs_Success(t *testing.T) {
@@ -512,6 +519,7 @@ func TestExportLogs_ErrorInvalidTraceID(t *testing.T) {
 	err := l.ExportLogs("1234567890")
 	assert.NotEqual(t, nil, err)
 	assert.Equal(t, "invalid trace ID", err.Error())
+	assert.ErrorIs(t, err, utils.ErrInvalidTraceID)
 }
 
 func TestExportLogs_ErrorOnLogExporter(t *testing.T) {
@@ -534,6 +542,114 @@ func TestExportLogs_ErrorOnLogExporter(t *testing.T) {
 	// check for error
 	err = l.ExportLogs(traceID)
 	assert.Equal(t, "mock error", err.Error())
+
+	// the transaction is kept for another try, not ended
+	assert.True(t, l.TransactionLogs[traceID].EndTime.IsZero())
+}
+
+// exporter keeping the whole transaction it receives
+type TestTransactionExporter struct {
+	transactionLog *otel.TransactionLog
+}
+
+func (c *TestTransactionExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	return errors.New("ExportLogs should not be called")
+}
+
+func (c *TestTransactionExporter) ExportTransaction(transactionLog *otel.TransactionLog, config map[string]string) error {
+	c.transactionLog = transactionLog
+	return nil
+}
+
+func TestExportLogs_TransactionExporter(t *testing.T) {
+	exp := &TestTransactionExporter{}
+	l := logger.NewLogger(logger.DEBUG).WithExporter(exp)
+	l.SetServiceName("TestService")
+
+	traceID := l.StartTransaction(map[string]string{"test": "test"})
+
+	err := l.Info("info message", traceID, map[string]string{"key": "val"})
+	assert.Equal(t, nil, err)
+
+	err = l.ExportLogs(traceID)
+	assert.Equal(t, nil, err)
+
+	// the exporter gets the whole transaction, timed from start to export
+	assert.Equal(t, traceID, exp.transactionLog.TraceID)
+	assert.Equal(t, utils.LoggerName, exp.transactionLog.LoggerName)
+	assert.Equal(t, "TestService", exp.transactionLog.ServiceName)
+	assert.Equal(t, map[string]string{"test": "test"}, exp.transactionLog.Attributes)
+	assert.Len(t, exp.transactionLog.Spans, 1)
+	assert.False(t, exp.transactionLog.StartTime.IsZero())
+	assert.False(t, exp.transactionLog.EndTime.Before(exp.transactionLog.StartTime))
+	assert.NotContains(t, l.TransactionLogs, traceID)
+}
+
+// exporter logging to the transaction it exports
+type LoggingExporter struct {
+	logger *logger.Logger
+	logs   int
+}
+
+func (c *LoggingExporter) ExportLogs(traceID string, logs []*otel.OTelLog, config map[string]string) error {
+	c.logs = len(logs)
+	return c.logger.Info("logged during export", traceID, nil)
+}
+
+func TestExportLogs_LogDuringExport(t *testing.T) {
+	exp := &LoggingExporter{}
+	l := logger.NewLogger(logger.DEBUG).WithExporter(exp)
+	exp.logger = l
+
+	traceID := l.StartTransaction(nil)
+
+	err := l.Info("info message", traceID, nil)
+	assert.Equal(t, nil, err)
+
+	// the logger isn't locked during the export, the new log is kept for the next one
+	err = l.ExportLogs(traceID)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, exp.logs)
+	assert.Len(t, l.TransactionLogs[traceID].Spans, 1)
+	assert.Equal(t, "logged during export", l.TransactionLogs[traceID].Spans[0].Message)
+}
+
+func TestShutdown(t *testing.T) {
+	exp := &TestTransactionExporter{}
+	l := logger.NewLogger(logger.DEBUG).WithExporter(logExporter.NewBatchProcessor(exp))
+
+	traceID := l.StartTransaction(map[string]string{"test": "test"})
+
+	err := l.Info("info message", traceID, map[string]string{"key": "val"})
+	assert.Equal(t, nil, err)
+
+	// exported in the background, the transaction is done for the logger
+	err = l.ExportLogs(traceID)
+	assert.Equal(t, nil, err)
+	assert.NotContains(t, l.TransactionLogs, traceID)
+
+	err = l.Flush(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, traceID, exp.transactionLog.TraceID)
+
+	err = l.Shutdown(context.Background())
+	assert.Equal(t, nil, err)
+
+	// exporters that export right away have nothing to flush
+	err = logger.NewLogger(logger.DEBUG).Shutdown(context.Background())
+	assert.Equal(t, nil, err)
+
+	// a batch processor inside a wrapper is flushed too
+	exp = &TestTransactionExporter{}
+	l = logger.NewLogger(logger.DEBUG).WithExporter(&logExporter.RetryExporter{Exporter: logExporter.NewBatchProcessor(exp)})
+
+	traceID = l.StartTransaction(nil)
+	l.Info("info message", traceID, nil)
+	l.ExportLogs(traceID)
+
+	err = l.Flush(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, traceID, exp.transactionLog.TraceID)
 }
 
 func TestExportAllLogs(t *testing.T) {
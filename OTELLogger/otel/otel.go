@@ -3,6 +3,7 @@ package otel
 import (
 	"math/rand"
 	"strconv"
+	"time"
 )
 
 // log structure
@@ -19,16 +20,23 @@ type OTelLog struct {
 
 // transaction-styled log (contains multiple OTelLogs)
 type TransactionLog struct {
-	TraceID    string
-	Spans      []*OTelLog
-	Attributes map[string]string
+	TraceID     string
+	LoggerName  string
+	ServiceName string
+	Spans       []*OTelLog
+	Attributes  map[string]string
+	StartTime   time.Time
+	EndTime     time.Time // set when the transaction is exported
 }
 
 // create new transaction log and generate its trace ID
 func NewTransactionLog(loggerName, serviceName string, attributes map[string]string) *TransactionLog {
 	return &TransactionLog{
-		TraceID:    strconv.FormatInt(rand.Int63(), 10),
-		Attributes: attributes,
+		TraceID:     strconv.FormatInt(rand.Int63(), 10),
+		LoggerName:  loggerName,
+		ServiceName: serviceName,
+		Attributes:  attributes,
+		StartTime:   time.Now(),
 	}
 }
 
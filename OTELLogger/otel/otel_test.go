@@ -16,6 +16,10 @@ func TestNewTransactionLog(t *testing.T) {
 	_, err := strconv.ParseInt(tlog.TraceID, 10, 64)
 	assert.Equal(t, nil, err)
 	assert.Equal(t, attrs, tlog.Attributes)
+	assert.Equal(t, utils.LoggerName, tlog.LoggerName)
+	assert.Equal(t, utils.ServiceName, tlog.ServiceName)
+	assert.False(t, tlog.StartTime.IsZero())
+	assert.True(t, tlog.EndTime.IsZero())
 }
 
 func TestNewOTelLog(t *testing.T) {
@@ -3,4 +3,7 @@ package utils
 const (
 	ServiceName = "Default"
 	LoggerName  = "OTelLogger"
+
+	// layout of the timestamps stored on each log
+	TimestampFormat = "02.01.2006 15:04:05"
 )
@@ -0,0 +1,54 @@
+package utils
+
+import "errors"
+
+// errors that can be checked with errors.Is
+var (
+	// the exporter needs a config but got none
+	ErrNoConfig = errors.New("no config provided")
+	// a config value is missing or malformed (see ConfigError)
+	ErrInvalidConfig = errors.New("invalid config")
+	// there is no transaction with the trace ID
+	ErrInvalidTraceID = errors.New("invalid trace ID")
+	// the log level isn't one of DEBUG, INFO, WARNING or ERROR
+	ErrUnknownLevel = errors.New("unknown log level")
+)
+
+// error about a config value, matches ErrInvalidConfig
+type ConfigError struct {
+	Key string // config key the error is about
+	Msg string // e.g. "no filepath in config"
+	Err error  // why the value is malformed, if there is a cause
+}
+
+func (e *ConfigError) Error() string {
+	return e.Msg
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ConfigError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
+
+// wraps an export error that may succeed if the export is tried again
+// (e.g. the downstream is temporarily unavailable)
+type RetryableError struct {
+	Err error
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// check if an error or any error it wraps is marked as retryable
+func IsRetryable(err error) bool {
+	var retryable *RetryableError
+	return errors.As(err, &retryable)
+}